@@ -0,0 +1,221 @@
+// Package check evaluates the values of a numeric query against warning and
+// critical thresholds, with the states and exit codes of Nagios and Icinga
+// plugins.
+package check
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+// State is the outcome of a check. Its value is the plugin exit code.
+type State int
+
+const (
+	OK State = iota
+	Warning
+	Critical
+	Unknown
+)
+
+// States are the names of the states, as --absent takes them.
+var States = []string{"ok", "warning", "critical", "unknown"}
+
+func (s State) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ParseState parses a state name such as "critical".
+func ParseState(name string) (State, error) {
+	for i, s := range States {
+		if strings.EqualFold(name, s) {
+			return State(i), nil
+		}
+	}
+	return Unknown, errors.NewValidationError(
+		fmt.Sprintf("invalid state: %s", name),
+		fmt.Errorf("valid states: %s", strings.Join(States, ", ")),
+	)
+}
+
+// Threshold is a limit a value breaches by going above it, or below it when
+// Below is set.
+type Threshold struct {
+	Limit float64
+	Below bool
+}
+
+// ParseThreshold parses a --warn or --crit value: a number, or a number
+// prefixed with ">" (the same) or "<" to be breached by lower values.
+func ParseThreshold(s string) (*Threshold, error) {
+	text := strings.TrimSpace(s)
+	t := &Threshold{}
+	switch {
+	case strings.HasPrefix(text, "<"):
+		t.Below = true
+		text = text[1:]
+	case strings.HasPrefix(text, ">"):
+		text = text[1:]
+	}
+	limit, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || math.IsNaN(limit) {
+		return nil, errors.NewValidationError(
+			fmt.Sprintf("invalid threshold: %s", s),
+			fmt.Errorf("use a number such as 50, >50 (breached above) or <5 (breached below)"),
+		)
+	}
+	t.Limit = limit
+	return t, nil
+}
+
+// Breached reports whether v is beyond the threshold.
+func (t *Threshold) Breached(v float64) bool {
+	if t.Below {
+		return v < t.Limit
+	}
+	return v > t.Limit
+}
+
+func (t *Threshold) String() string {
+	if t.Below {
+		return "< " + formatNumber(t.Limit)
+	}
+	return "> " + formatNumber(t.Limit)
+}
+
+// perfdata renders the threshold as a Nagios range: "50" alerts above 50,
+// "5:" below 5.
+func (t *Threshold) perfdata() string {
+	if t.Below {
+		return formatNumber(t.Limit) + ":"
+	}
+	return formatNumber(t.Limit)
+}
+
+// Rule decides the state of a check from the values of its query.
+type Rule struct {
+	// Warn and Crit are the thresholds, either of which may be nil.
+	Warn *Threshold
+	Crit *Threshold
+	// Change evaluates the percent change from the second-to-last value to
+	// the last, instead of the last value.
+	Change bool
+	// Absent is the state when the query returns no value. Its zero value
+	// is OK, so set it to Unknown for the usual plugin behaviour.
+	Absent State
+}
+
+// Result is the outcome of evaluating a Rule.
+type Result struct {
+	State State
+	// Value is what the thresholds were compared with; it is NaN when the
+	// data was absent.
+	Value   float64
+	Message string
+}
+
+// Evaluate returns the state of values, one per bucket of the query. The
+// last value is checked, or with Change its percent change from the one
+// before. No values, or NaN, count as absent data.
+func (r Rule) Evaluate(values []float64) Result {
+	needed := 1
+	if r.Change {
+		needed = 2
+	}
+	if len(values) < needed || math.IsNaN(values[len(values)-1]) {
+		return Result{State: r.Absent, Value: math.NaN(), Message: "no data"}
+	}
+
+	value := values[len(values)-1]
+	subject := "value " + formatNumber(value)
+	if r.Change {
+		previous := values[len(values)-2]
+		if math.IsNaN(previous) {
+			return Result{State: r.Absent, Value: math.NaN(), Message: "no data to compare with"}
+		}
+		value = percentChange(previous, value)
+		subject = fmt.Sprintf("change %s%% (%s to %s)", formatNumber(value), formatNumber(previous), formatNumber(values[len(values)-1]))
+	}
+
+	switch {
+	case r.Crit != nil && r.Crit.Breached(value):
+		return Result{State: Critical, Value: value, Message: subject + " " + r.Crit.String()}
+	case r.Warn != nil && r.Warn.Breached(value):
+		return Result{State: Warning, Value: value, Message: subject + " " + r.Warn.String()}
+	default:
+		return Result{State: OK, Value: value, Message: subject}
+	}
+}
+
+// percentChange is the change from previous to current as a percentage of
+// previous, rounded to two decimals. A rise from zero is infinite.
+func percentChange(previous, current float64) float64 {
+	if previous == 0 {
+		switch {
+		case current > 0:
+			return math.Inf(1)
+		case current < 0:
+			return math.Inf(-1)
+		default:
+			return 0
+		}
+	}
+	return math.Round((current-previous)/math.Abs(previous)*10000) / 100
+}
+
+// StatusLine renders the result as a Nagios plugin output line, such as
+// "ERRORS CRITICAL - value 57 > 50 | errors=57;10;50". label names the
+// check and its performance data, which is left out when there is no finite
+// value to report.
+func (r Rule) StatusLine(label string, res Result) string {
+	line := strings.ToUpper(label) + " " + res.State.String() + " - " + res.Message
+	if math.IsNaN(res.Value) || math.IsInf(res.Value, 0) {
+		return line
+	}
+
+	var uom string
+	if r.Change {
+		uom = "%"
+	}
+
+	var warn, crit string
+	if r.Warn != nil {
+		warn = r.Warn.perfdata()
+	}
+	if r.Crit != nil {
+		crit = r.Crit.perfdata()
+	}
+	return fmt.Sprintf("%s | %s=%s%s;%s;%s", line, perfdataLabel(label), formatNumber(res.Value), uom, warn, crit)
+}
+
+// perfdataLabel quotes a label holding characters Nagios separates
+// performance data on.
+func perfdataLabel(label string) string {
+	if strings.ContainsAny(label, " '=") {
+		return "'" + strings.ReplaceAll(label, "'", "''") + "'"
+	}
+	return label
+}
+
+func formatNumber(v float64) string {
+	if math.IsInf(v, 0) {
+		if v > 0 {
+			return "inf"
+		}
+		return "-inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,113 @@
+package check
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func threshold(t *testing.T, s string) *Threshold {
+	t.Helper()
+
+	th, err := ParseThreshold(s)
+	require.NoError(t, err)
+	return th
+}
+
+func TestParseThreshold(t *testing.T) {
+	assert.Equal(t, &Threshold{Limit: 50}, threshold(t, "50"))
+	assert.Equal(t, &Threshold{Limit: 50}, threshold(t, ">50"))
+	assert.Equal(t, &Threshold{Limit: 0.5, Below: true}, threshold(t, "< 0.5"))
+	assert.Equal(t, &Threshold{Limit: -10}, threshold(t, "-10"))
+
+	for _, bad := range []string{"", ">", "<<5", "fifty", "NaN"} {
+		_, err := ParseThreshold(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseState(t *testing.T) {
+	state, err := ParseState("Critical")
+	require.NoError(t, err)
+	assert.Equal(t, Critical, state)
+	assert.Equal(t, 2, int(state), "states are the Nagios exit codes")
+
+	_, err = ParseState("bad")
+	assert.Error(t, err)
+}
+
+func TestRule_Evaluate(t *testing.T) {
+	above := Rule{Warn: threshold(t, "10"), Crit: threshold(t, "50"), Absent: Unknown}
+	below := Rule{Warn: threshold(t, "<5"), Crit: threshold(t, "<1"), Absent: Critical}
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		values  []float64
+		state   State
+		message string
+	}{
+		{"ok", above, []float64{3}, OK, "value 3"},
+		{"at the limit", above, []float64{10}, OK, "value 10"},
+		{"warning", above, []float64{11}, Warning, "value 11 > 10"},
+		{"critical", above, []float64{57}, Critical, "value 57 > 50"},
+		{"last bucket", above, []float64{100, 2}, OK, "value 2"},
+		{"below warning", below, []float64{3}, Warning, "value 3 < 5"},
+		{"below critical", below, []float64{0}, Critical, "value 0 < 1"},
+		{"absent", above, nil, Unknown, "no data"},
+		{"absent state", below, []float64{math.NaN()}, Critical, "no data"},
+		{"no thresholds", Rule{}, []float64{1e6}, OK, "value 1000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.rule.Evaluate(tt.values)
+			assert.Equal(t, tt.state, res.State)
+			assert.Equal(t, tt.message, res.Message)
+		})
+	}
+}
+
+func TestRule_EvaluateChange(t *testing.T) {
+	rule := Rule{Warn: threshold(t, "50"), Crit: threshold(t, "200"), Change: true, Absent: Unknown}
+
+	res := rule.Evaluate([]float64{10, 12})
+	assert.Equal(t, OK, res.State)
+	assert.Equal(t, 20.0, res.Value)
+	assert.Equal(t, "change 20% (10 to 12)", res.Message)
+
+	res = rule.Evaluate([]float64{10, 40})
+	assert.Equal(t, Critical, res.State)
+	assert.Equal(t, "change 300% (10 to 40) > 200", res.Message)
+
+	res = rule.Evaluate([]float64{0, 5})
+	assert.Equal(t, Critical, res.State, "a rise from zero is infinite")
+	assert.Equal(t, "change inf% (0 to 5) > 200", res.Message)
+
+	res = Rule{Warn: threshold(t, "<-50"), Change: true}.Evaluate([]float64{40, 10})
+	assert.Equal(t, Warning, res.State)
+	assert.Equal(t, -75.0, res.Value)
+
+	res = rule.Evaluate([]float64{12})
+	assert.Equal(t, Unknown, res.State, "a change needs two values")
+}
+
+func TestRule_StatusLine(t *testing.T) {
+	rule := Rule{Warn: threshold(t, "10"), Crit: threshold(t, "<1"), Absent: Unknown}
+
+	assert.Equal(t, "ERRORS CRITICAL - value 0 < 1 | errors=0;10;1:",
+		rule.StatusLine("errors", rule.Evaluate([]float64{0})))
+	assert.Equal(t, "API ERRORS OK - value 2.5 | 'api errors'=2.5;10;1:",
+		rule.StatusLine("api errors", rule.Evaluate([]float64{2.5})))
+	assert.Equal(t, "ERRORS UNKNOWN - no data",
+		rule.StatusLine("errors", rule.Evaluate(nil)), "absent data has no performance data")
+	assert.Equal(t, "ERRORS OK - value 4 | errors=4;;",
+		Rule{}.StatusLine("errors", Rule{}.Evaluate([]float64{4})))
+
+	change := Rule{Crit: threshold(t, "200"), Change: true}
+	assert.Equal(t, "ERRORS OK - change 50% (2 to 3) | errors=50%;;200",
+		change.StatusLine("errors", change.Evaluate([]float64{2, 3})))
+	assert.Equal(t, "ERRORS CRITICAL - change inf% (0 to 3) > 200",
+		change.StatusLine("errors", change.Evaluate([]float64{0, 3})))
+}
@@ -54,6 +54,23 @@ func TestSchemaCommandListMatchesSchemas(t *testing.T) {
 	}
 }
 
+// writeCommands are the only commands that change anything: ingest and
+// files put/push write to Scalyr, files pull writes local files, and the
+// config commands edit the local config file or credential store.
+var writeCommands = map[string]bool{
+	"ingest":     true,
+	"files put":  true,
+	"files pull": true,
+	"files push": true,
+
+	"config init":  true,
+	"config set":   true,
+	"config unset": true,
+
+	"config credentials set":    true,
+	"config credentials delete": true,
+}
+
 func TestSchemaOutputIsValidJSON(t *testing.T) {
 	listOut := captureStdout(t, func() {
 		runSchema(schemaCmd, nil)
@@ -73,7 +90,7 @@ func TestSchemaOutputIsValidJSON(t *testing.T) {
 		var schema commandSchema
 		require.NoError(t, json.Unmarshal([]byte(out), &schema), "schema %q failed to unmarshal", name)
 		assert.Equal(t, name, schema.Command)
-		assert.True(t, schema.ReadOnly, "schema %q must report read_only", name)
+		assert.Equal(t, !writeCommands[name], schema.ReadOnly, "schema %q reports the wrong read_only", name)
 	}
 }
 
@@ -96,6 +113,24 @@ func TestSchemaFlagsMatchCobraDefinitions(t *testing.T) {
 		"facet-query":      facetQueryCmd,
 		"timeseries-query": timeseriesQueryCmd,
 		"tail":             tailCmd,
+		"ingest":           ingestCmd,
+		"files ls":         filesLsCmd,
+		"files get":        filesGetCmd,
+		"files put":        filesPutCmd,
+		"files diff":       filesDiffCmd,
+		"files pull":       filesPullCmd,
+		"files push":       filesPushCmd,
+		"config init":      configInitCmd,
+		"config get":       configGetCmd,
+		"config set":       configSetCmd,
+		"config unset":     configUnsetCmd,
+		"config list":      configListCmd,
+		"config validate":  configValidateCmd,
+		"config path":      configPathCmd,
+		"config profiles":  configProfilesCmd,
+
+		"config credentials set":    configCredentialsSetCmd,
+		"config credentials delete": configCredentialsDeleteCmd,
 	}
 
 	for name, cmd := range runnable {
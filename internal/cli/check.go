@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/check"
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/timerange"
+	"github.com/andreagrandi/logbasset/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check a numeric query against thresholds",
+	Long: `Check runs a numeric query and compares its last value with the --warn and --crit
+thresholds, for cron jobs, CI gates and monitoring systems. It prints a Nagios
+and Icinga compatible status line and exits 0 (OK), 1 (WARNING), 2 (CRITICAL)
+or 3 (UNKNOWN, including invalid flags or configuration and failed queries).
+
+Thresholds are breached above the number (50 or >50), or below it (<5). With
+--change they apply to the percent change between the last two buckets, and
+--absent sets the state when the query returns no data.
+
+With --every the check runs again at that interval until interrupted, printing
+a line only when the state changes.`,
+	Args:         checkArgs,
+	Run:          runCheck,
+	SilenceUsage: true,
+}
+
+var (
+	checkFilter    string
+	checkFunction  string
+	checkStartTime string
+	checkEndTime   string
+	checkBuckets   int
+	checkWarn      string
+	checkCrit      string
+	checkChange    bool
+	checkAbsent    string
+	checkLabel     string
+	checkEvery     string
+	checkOutput    string
+)
+
+func init() {
+	checkCmd.Flags().StringVar(&checkFilter, "filter", "", "Log filter expression of the query")
+	checkCmd.Flags().StringVar(&checkFunction, "function", "", "Function to compute from matching events")
+	checkCmd.Flags().StringVar(&checkStartTime, "start", "", "Start time for the query (required)")
+	checkCmd.Flags().StringVar(&checkEndTime, "end", "", "End time for the query")
+	checkCmd.Flags().IntVar(&checkBuckets, "buckets", 1, "Number of time buckets (1-5000); the last one is checked (default 2 with --change)")
+	checkCmd.Flags().StringVar(&checkWarn, "warn", "", "Warning threshold: N or >N breached above, <N below")
+	checkCmd.Flags().StringVar(&checkCrit, "crit", "", "Critical threshold: N or >N breached above, <N below")
+	checkCmd.Flags().BoolVar(&checkChange, "change", false, "Check the percent change between the last two buckets instead of the last value")
+	checkCmd.Flags().StringVar(&checkAbsent, "absent", "unknown", "State when the query returns no data: ok|warning|critical|unknown")
+	checkCmd.Flags().StringVar(&checkLabel, "label", "logbasset", "Name of the check in the status line and performance data")
+	checkCmd.Flags().StringVar(&checkEvery, "every", "", "Run the check at this interval (e.g., 1m) until interrupted, printing state changes only")
+	checkCmd.Flags().StringVar(&checkOutput, "output", "text", "Output format: text (a Nagios status line) or json")
+	checkCmd.MarkFlagRequired("start")
+
+	// Monitoring systems read the status line and exit code, so failures
+	// before the check runs are UNKNOWN like any other
+	checkCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		exitCheckError(errors.NewUsageError(err.Error(), nil))
+		return nil
+	})
+	checkCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			exitCheckError(errors.NewUsageError(err.Error(), nil))
+		}
+		if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+			exitCheckError(err)
+		}
+		return nil
+	}
+}
+
+// checkArgs rejects arguments with the UNKNOWN status line.
+func checkArgs(cmd *cobra.Command, args []string) error {
+	if err := cobra.NoArgs(cmd, args); err != nil {
+		exitCheckError(errors.NewUsageError(err.Error(), nil))
+	}
+	return nil
+}
+
+// checkReport is the result of one run of a check.
+type checkReport struct {
+	Label   string   `json:"label"`
+	State   string   `json:"state"`
+	Code    int      `json:"code"`
+	Value   *float64 `json:"value"`
+	Message string   `json:"message"`
+	Time    string   `json:"time"`
+
+	state check.State
+	line  string
+}
+
+func runCheck(cmd *cobra.Command, args []string) {
+	rule, params, err := checkSetup(cmd)
+	if err != nil {
+		exitCheckError(err)
+	}
+
+	c := getConfig().GetClient()
+	evaluate := func(ctx context.Context) (checkReport, error) {
+		return runCheckQuery(ctx, c, rule, params)
+	}
+
+	// Set up signal handling for graceful cancellation
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if checkEvery == "" {
+		report, err := evaluate(ctx)
+		if err != nil {
+			exitCheckError(err)
+		}
+		printCheckReport(report)
+		if report.state != check.OK {
+			errors.Exit(int(report.state))
+		}
+		return
+	}
+
+	every, _ := timerange.ParseDuration(checkEvery)
+	watchCheck(ctx, every, evaluate, printCheckReport)
+}
+
+// checkSetup validates the flags of check, returning its rule and the
+// parameters of its query.
+func checkSetup(cmd *cobra.Command) (check.Rule, client.NumericQueryParams, error) {
+	if checkOutput != "text" && checkOutput != "json" {
+		return check.Rule{}, client.NumericQueryParams{}, errors.NewValidationError(
+			fmt.Sprintf("invalid output format: %s", checkOutput),
+			fmt.Errorf("valid formats are: text, json"),
+		)
+	}
+
+	buckets := checkBuckets
+	if checkChange && !cmd.Flags().Changed("buckets") {
+		buckets = 2
+	}
+	params := validation.QueryValidationParams{
+		StartTime:       checkStartTime,
+		EndTime:         checkEndTime,
+		Buckets:         buckets,
+		Priority:        getConfig().Priority,
+		Query:           checkFilter,
+		ValidateBuckets: true,
+	}
+	if err := validation.ValidateQueryParams(params, validation.DefaultConfig()); err != nil {
+		return check.Rule{}, client.NumericQueryParams{}, err
+	}
+	if checkChange && buckets < 2 {
+		return check.Rule{}, client.NumericQueryParams{}, errors.NewValidationError(
+			"--change needs at least 2 buckets",
+			fmt.Errorf("provided buckets: %d", buckets),
+		)
+	}
+	if checkEvery != "" {
+		if err := validation.ValidateCheckEvery(checkEvery); err != nil {
+			return check.Rule{}, client.NumericQueryParams{}, err
+		}
+	}
+
+	rule, err := checkRule()
+	if err != nil {
+		return check.Rule{}, client.NumericQueryParams{}, err
+	}
+	return rule, client.NumericQueryParams{
+		Filter:    checkFilter,
+		Function:  checkFunction,
+		StartTime: checkStartTime,
+		EndTime:   checkEndTime,
+		Buckets:   buckets,
+		Priority:  getConfig().Priority,
+	}, nil
+}
+
+// checkRule builds the rule of --warn, --crit, --change and --absent.
+func checkRule() (check.Rule, error) {
+	absent, err := check.ParseState(checkAbsent)
+	if err != nil {
+		return check.Rule{}, err
+	}
+	rule := check.Rule{Change: checkChange, Absent: absent}
+	if checkWarn != "" {
+		if rule.Warn, err = check.ParseThreshold(checkWarn); err != nil {
+			return check.Rule{}, err
+		}
+	}
+	if checkCrit != "" {
+		if rule.Crit, err = check.ParseThreshold(checkCrit); err != nil {
+			return check.Rule{}, err
+		}
+	}
+	return rule, nil
+}
+
+// runCheckQuery runs the query of a check and evaluates its values. A failed
+// query is reported as UNKNOWN, and its error returned.
+func runCheckQuery(ctx context.Context, c client.ClientInterface, rule check.Rule, params client.NumericQueryParams) (checkReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout())
+	defer cancel()
+
+	result, err := c.NumericQuery(ctx, params)
+	if err != nil {
+		return unknownCheckReport(err), err
+	}
+	return newCheckReport(rule, rule.Evaluate(result.Values)), nil
+}
+
+func newCheckReport(rule check.Rule, res check.Result) checkReport {
+	report := checkReport{
+		Label:   checkLabel,
+		State:   res.State.String(),
+		Code:    int(res.State),
+		Message: res.Message,
+		Time:    time.Now().UTC().Format(time.RFC3339),
+		state:   res.State,
+		line:    rule.StatusLine(checkLabel, res),
+	}
+	// JSON has no NaN or infinity, so those values are left out
+	if !math.IsNaN(res.Value) && !math.IsInf(res.Value, 0) {
+		report.Value = &res.Value
+	}
+	return report
+}
+
+// unknownCheckReport is the UNKNOWN report of a check that could not run.
+func unknownCheckReport(err error) checkReport {
+	message := err.Error()
+	if lbErr, ok := err.(*errors.LogBassetError); ok && lbErr.Message != "" {
+		message = lbErr.Message
+	}
+	return newCheckReport(check.Rule{}, check.Result{State: check.Unknown, Value: math.NaN(), Message: message})
+}
+
+func printCheckReport(report checkReport) {
+	if checkOutput == "json" {
+		outputJSON(report, false)
+		return
+	}
+	fmt.Println(report.line)
+}
+
+// exitCheckError prints the UNKNOWN status line of err and exits with the
+// UNKNOWN exit code, reporting err itself on stderr as usual.
+func exitCheckError(err error) {
+	printCheckReport(unknownCheckReport(err))
+
+	lbErr, ok := err.(*errors.LogBassetError)
+	if !ok {
+		lbErr = &errors.LogBassetError{Type: errors.APIError, Message: err.Error()}
+	}
+	unknown := *lbErr
+	unknown.ExitCode = int(check.Unknown)
+	errors.HandleErrorAndExit(&unknown)
+}
+
+// watchCheck runs evaluate every interval until ctx is cancelled, emitting
+// the first report and then each one whose state differs from the last.
+// Failed runs are UNKNOWN states like any other.
+func watchCheck(ctx context.Context, every time.Duration, evaluate func(context.Context) (checkReport, error), emit func(checkReport)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	var last *check.State
+	for {
+		report, _ := evaluate(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if last == nil || report.state != *last {
+			emit(report)
+			last = &report.state
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
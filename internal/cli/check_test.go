@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchCheck(t *testing.T) {
+	states := []check.State{check.OK, check.OK, check.Warning, check.Warning, check.Unknown, check.OK}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runs := 0
+	evaluate := func(context.Context) (checkReport, error) {
+		state := states[runs]
+		runs++
+		if runs == len(states) {
+			cancel()
+		}
+		return checkReport{State: state.String(), state: state}, nil
+	}
+
+	var emitted []string
+	watchCheck(ctx, time.Millisecond, evaluate, func(r checkReport) { emitted = append(emitted, r.State) })
+
+	assert.Equal(t, []string{"OK", "WARNING", "UNKNOWN"}, emitted,
+		"only the first report and state changes are printed, and a run cut short by an interrupt is not")
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
+)
+
+// colorEnabled reports whether output is coloured. --color (or the color
+// config key) always and never are obeyed as given; auto colours when
+// stdout is a terminal or goes through --pager, unless NO_COLOR is set, and
+// FORCE_COLOR turns it on regardless of the terminal.
+func colorEnabled() bool {
+	mode := flagColor
+	if cfg != nil && cfg.Color != "" {
+		mode = cfg.Color
+	}
+
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
+	return IsTTY() || activePager != nil
+}
+
+// outputTheme returns the default theme with the config file's theme:
+// overrides applied.
+func outputTheme() output.Theme {
+	theme := output.DefaultTheme()
+	if cfg == nil || len(cfg.Theme) == 0 {
+		return theme
+	}
+	theme, err := theme.WithOverrides(cfg.Theme)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	return theme
+}
+
+// timeFormat returns how timestamps are rendered, from --time-format and
+// --tz or their config keys.
+func timeFormat() output.TimeFormat {
+	format, tz := flagTimeFormat, flagTZ
+	if cfg != nil {
+		format, tz = cfg.TimeFormat, cfg.TZ
+	}
+	tf, err := output.NewTimeFormat(format, tz)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	return tf
+}
+
+// terminalOptions fills in the options that depend on where output goes:
+// the terminal width, and whether and how to colour it.
+func terminalOptions(opts output.Options) output.Options {
+	opts.Width = terminalWidth()
+	opts.Color = colorEnabled()
+	if opts.Color {
+		opts.Theme = outputTheme()
+	}
+	return opts
+}
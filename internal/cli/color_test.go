@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/andreagrandi/logbasset/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColorEnabled(t *testing.T) {
+	previousTTY, previousCfg, previousFlag := IsTTY, cfg, flagColor
+	defer func() { IsTTY, cfg, flagColor = previousTTY, previousCfg, previousFlag }()
+
+	tests := []struct {
+		name    string
+		mode    string
+		tty     bool
+		noColor string
+		force   string
+		want    bool
+	}{
+		{name: "auto on a terminal", mode: "auto", tty: true, want: true},
+		{name: "auto in a pipe", mode: "auto", tty: false, want: false},
+		{name: "NO_COLOR disables auto", mode: "auto", tty: true, noColor: "1", want: false},
+		{name: "FORCE_COLOR enables auto in a pipe", mode: "auto", tty: false, force: "1", want: true},
+		{name: "FORCE_COLOR=0 is ignored", mode: "auto", tty: false, force: "0", want: false},
+		{name: "NO_COLOR wins over FORCE_COLOR", mode: "auto", tty: true, noColor: "1", force: "1", want: false},
+		{name: "always ignores the environment", mode: "always", tty: false, noColor: "1", want: true},
+		{name: "never ignores the environment", mode: "never", tty: true, force: "1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("FORCE_COLOR", tt.force)
+			IsTTY = func() bool { return tt.tty }
+			cfg = &config.Config{Color: tt.mode}
+
+			assert.Equal(t, tt.want, colorEnabled())
+		})
+	}
+}
+
+func TestOutputTheme_ConfigOverrides(t *testing.T) {
+	previousCfg := cfg
+	defer func() { cfg = previousCfg }()
+
+	cfg = &config.Config{Theme: map[string]string{"error": "bold red"}}
+	theme := outputTheme()
+	assert.Equal(t, "1;31", theme.Error)
+	assert.Equal(t, "33", theme.Warning)
+}
@@ -0,0 +1,480 @@
+package cli
+
+import (
+	"bufio"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andreagrandi/logbasset/internal/config"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/logging"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage LogBasset configuration",
+	Long: `Create, inspect and edit logbasset.yaml. Keys are the top-level settings
+(server, token, verbose, priority, log_level, timeout, profile) or
+profiles.<name>.<key> for a named profile.`,
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a config file",
+	Long: `Creates logbasset.yaml with 0600 permissions. On a terminal it prompts for the
+server, token and priority; otherwise, or with --non-interactive, it writes the values
+given with --server, --token and --priority or found in the environment.`,
+	Args: cobra.NoArgs,
+	Run:  runConfigInit,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a config key",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Write a config key to the config file",
+	Args:  cobra.ExactArgs(2),
+	Run:   runConfigSet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a config key from the config file",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigUnset,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List effective settings and where each came from",
+	Long:  "Lists every setting with its effective value and its source: flag, env, profile, file or default. Tokens are masked.",
+	Args:  cobra.NoArgs,
+	Run:   runConfigList,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the effective configuration and every profile",
+	Args:  cobra.NoArgs,
+	Run:   runConfigValidate,
+}
+
+var configCredentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manage tokens kept in the credential store",
+	Long: `Stores read tokens in the credential store named by the credential_store key
+(default "file": an AES-256-GCM encrypted ~/.config/logbasset/credentials.enc unlocked
+with the scalyr_credentials_passphrase env var or a prompt). Tokens are stored per
+profile; without a profile the name is "default".`,
+}
+
+var configCredentialsSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Store a token in the credential store",
+	Long:  "Stores a token for the active profile (or --name). The token is read from stdin, or prompted for without echo on a terminal.",
+	Args:  cobra.NoArgs,
+	Run:   runConfigCredentialsSet,
+}
+
+var configCredentialsDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Remove a token from the credential store",
+	Args:  cobra.NoArgs,
+	Run:   runConfigCredentialsDelete,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the loaded config file",
+	Args:  cobra.NoArgs,
+	Run:   runConfigPath,
+}
+
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List the named profiles in the config file",
+	Long: `Lists the profiles defined under "profiles:" in logbasset.yaml and marks the
+active one, selected with --profile, the scalyr_profile env var, or the file's "profile:" key.
+Tokens are never printed; the output only reports whether a profile sets one.`,
+	Args: cobra.NoArgs,
+	Run:  runConfigProfiles,
+}
+
+var (
+	configProfilesOutput string
+	configListOutput     string
+	configInitFile       string
+	configInitForce      bool
+	configInitNoPrompt   bool
+	configSetFile        string
+	configUnsetFile      string
+	configCredentialName string
+)
+
+func init() {
+	configProfilesCmd.Flags().StringVar(&configProfilesOutput, "output", "text", "Output format: text|json")
+	configListCmd.Flags().StringVar(&configListOutput, "output", "text", "Output format: text|json")
+	configInitCmd.Flags().StringVar(&configInitFile, "file", "", "Config file to create (default ~/.config/logbasset/logbasset.yaml)")
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Overwrite an existing config file")
+	configInitCmd.Flags().BoolVar(&configInitNoPrompt, "non-interactive", false, "Do not prompt; use flag and environment values")
+	configSetCmd.Flags().StringVar(&configSetFile, "file", "", "Config file to edit (default: the loaded file, else ~/.config/logbasset/logbasset.yaml)")
+	configUnsetCmd.Flags().StringVar(&configUnsetFile, "file", "", "Config file to edit (default: the loaded file)")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configProfilesCmd)
+
+	for _, c := range []*cobra.Command{configCredentialsSetCmd, configCredentialsDeleteCmd} {
+		c.Flags().StringVar(&configCredentialName, "name", "", "Credential name (default: the active profile, or \"default\")")
+		configCredentialsCmd.AddCommand(c)
+	}
+	configCmd.AddCommand(configCredentialsCmd)
+}
+
+// validateTextOrJSON rejects anything but the text and json output formats
+// the config subcommands support.
+func validateTextOrJSON(output string) {
+	if output != "text" && output != "json" {
+		errors.HandleErrorAndExit(errors.NewValidationError(
+			fmt.Sprintf("invalid output format: %s", output),
+			fmt.Errorf("valid formats are: text, json"),
+		))
+	}
+}
+
+// configFilePath picks the file config set/unset edit: --file, then the file
+// that was loaded, then the default location.
+func configFilePath(file string) string {
+	if file != "" {
+		return file
+	}
+	if getConfig().File != "" {
+		return getConfig().File
+	}
+	path, err := config.DefaultPath()
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	return path
+}
+
+func runConfigInit(cmd *cobra.Command, args []string) {
+	path := configInitFile
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+	}
+
+	c := getConfig()
+	settings := map[string]string{
+		"server":   c.Server,
+		"token":    c.Token,
+		"priority": c.Priority,
+	}
+
+	if !configInitNoPrompt && isStdinTTY() {
+		in := bufio.NewReader(os.Stdin)
+		settings["server"] = prompt(in, "Scalyr server", settings["server"])
+		settings["token"] = promptSecret("API token (Read Logs)", settings["token"])
+		settings["priority"] = prompt(in, "Query priority (high|low)", settings["priority"])
+	}
+
+	if settings["token"] == "" {
+		logging.Warn("no token given; set one later with 'logbasset config set token <token>' or the scalyr_readlog_token env var")
+	}
+
+	if err := config.CreateFile(path, settings, configInitForce); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	fmt.Printf("Wrote %s\n", path)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) {
+	value, err := getConfig().Value(args[0])
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	fmt.Println(value)
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) {
+	path := configFilePath(configSetFile)
+	if err := config.SetInFile(path, args[0], args[1]); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	fmt.Printf("Set %s in %s\n", strings.ToLower(args[0]), path)
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) {
+	path := configFilePath(configUnsetFile)
+	removed, err := config.UnsetInFile(path, args[0])
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	if !removed {
+		logging.Warnf("%s is not set in %s", strings.ToLower(args[0]), path)
+		return
+	}
+	fmt.Printf("Removed %s from %s\n", strings.ToLower(args[0]), path)
+}
+
+type settingSummary struct {
+	Key    string        `json:"key"`
+	Value  string        `json:"value"`
+	Source config.Source `json:"source"`
+}
+
+func runConfigList(cmd *cobra.Command, args []string) {
+	validateTextOrJSON(configListOutput)
+	if !cmd.Flags().Changed("output") && !IsTTY() {
+		configListOutput = "json"
+		errors.OutputJSON = true
+	}
+
+	c := getConfig()
+	settings := make([]settingSummary, 0, len(config.Settings))
+	for _, s := range config.Settings {
+		value, _ := c.Value(s.Key)
+		if s.Secret {
+			value = maskSecret(value)
+		}
+		settings = append(settings, settingSummary{Key: s.Key, Value: value, Source: c.Sources[s.Key]})
+	}
+
+	if configListOutput == "json" {
+		outputJSON(settings, false)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	w.Flush()
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) {
+	c := getConfig()
+	if err := c.ValidateSettings(); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	for _, name := range c.ProfileNames() {
+		p, err := config.LoadProfile(name)
+		if err == nil {
+			err = p.ValidateSettings()
+		}
+		if err != nil {
+			errors.HandleErrorAndExit(errors.NewConfigError(fmt.Sprintf("profile %q is invalid", name), err))
+		}
+	}
+
+	file := c.File
+	if file == "" {
+		file = "no config file"
+	}
+	fmt.Printf("Configuration is valid (%s, %d profiles)\n", file, len(c.Profiles))
+}
+
+func runConfigPath(cmd *cobra.Command, args []string) {
+	if file := getConfig().File; file != "" {
+		fmt.Println(file)
+		return
+	}
+	errors.HandleErrorAndExit(errors.NewConfigError(
+		"no config file found",
+		fmt.Errorf("searched for logbasset.yaml in: %s", strings.Join(config.SearchDirs(), ", ")),
+	))
+}
+
+// credentialTarget opens the configured credential store and picks the name
+// to store under.
+func credentialTarget() (config.CredentialStore, string) {
+	store, err := config.OpenCredentialStore(getConfig().CredentialStore)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	name := configCredentialName
+	if name == "" {
+		name = getConfig().CredentialName()
+	}
+	return store, strings.ToLower(name)
+}
+
+func runConfigCredentialsSet(cmd *cobra.Command, args []string) {
+	store, name := credentialTarget()
+
+	var token string
+	if isStdinTTY() {
+		token = promptSecret("API token for "+name, "")
+	} else {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			errors.HandleErrorAndExit(errors.NewUsageError("failed to read token from stdin", err))
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		errors.HandleErrorAndExit(errors.NewValidationError("token must not be empty", nil))
+	}
+
+	if err := store.Set(name, token); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	fmt.Printf("Stored token for %s\n", name)
+
+	if getConfig().CredentialStore == "" {
+		logging.Warn("credential_store is not set, so the token will not be used yet; run 'logbasset config set credential_store file'")
+	}
+}
+
+func runConfigCredentialsDelete(cmd *cobra.Command, args []string) {
+	store, name := credentialTarget()
+	if err := store.Delete(name); err != nil {
+		if stderrors.Is(err, config.ErrCredentialNotFound) {
+			logging.Warnf("no token stored for %s", name)
+			return
+		}
+		errors.HandleErrorAndExit(err)
+	}
+	fmt.Printf("Deleted token for %s\n", name)
+}
+
+// promptPassphrase wraps the credential store passphrase lookup so that a
+// terminal user is prompted when the env var is not set.
+func promptPassphrase(next func() (string, error)) func() (string, error) {
+	var cached string
+	return func() (string, error) {
+		if cached != "" {
+			return cached, nil
+		}
+		p, err := next()
+		if err != nil && isStdinTTY() {
+			p, err = promptSecret("Credential store passphrase", ""), nil
+			if p == "" {
+				err = errors.NewAuthError("credential store passphrase is required", nil)
+			}
+		}
+		cached = p
+		return p, err
+	}
+}
+
+// prompt asks for a value on stderr, returning def when the answer is empty.
+func prompt(in *bufio.Reader, label, def string) string {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", label)
+	}
+	answer, _ := in.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// promptSecret is prompt without echoing what is typed.
+func promptSecret(label, def string) string {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", label, maskSecret(def))
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", label)
+	}
+	answer, _ := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if value := strings.TrimSpace(string(answer)); value != "" {
+		return value
+	}
+	return def
+}
+
+// maskSecret hides all but the last four characters of a token.
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 4 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
+
+type profileSummary struct {
+	Name     string `json:"name"`
+	Active   bool   `json:"active"`
+	Server   string `json:"server,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+	HasToken bool   `json:"has_token"`
+}
+
+func runConfigProfiles(cmd *cobra.Command, args []string) {
+	validateTextOrJSON(configProfilesOutput)
+
+	if !cmd.Flags().Changed("output") && !IsTTY() {
+		configProfilesOutput = "json"
+		errors.OutputJSON = true
+	}
+
+	c := getConfig()
+	profiles := make([]profileSummary, 0, len(c.Profiles))
+	for _, name := range c.ProfileNames() {
+		p := c.Profiles[name]
+		summary := profileSummary{
+			Name:     name,
+			Active:   name == c.Profile,
+			Server:   p.Server,
+			Priority: p.Priority,
+			HasToken: p.Token != "",
+		}
+		if p.Timeout > 0 {
+			summary.Timeout = p.Timeout.String()
+		}
+		profiles = append(profiles, summary)
+	}
+
+	if configProfilesOutput == "json" {
+		outputJSON(profiles, false)
+		return
+	}
+
+	if len(profiles) == 0 {
+		fmt.Println("No profiles defined; add a \"profiles:\" section to logbasset.yaml")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tNAME\tSERVER\tPRIORITY\tTOKEN")
+	for _, p := range profiles {
+		marker := ""
+		if p.Active {
+			marker = "*"
+		}
+		token := "-"
+		if p.HasToken {
+			token = "set"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", marker, p.Name, p.Server, p.Priority, token)
+	}
+	w.Flush()
+}
@@ -1,15 +1,25 @@
 package cli
 
 import (
+	"bufio"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/config"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/filesync"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -40,29 +50,58 @@ const (
 func resetCLIFlags() {
 	reset := func(fs *pflag.FlagSet) {
 		fs.VisitAll(func(f *pflag.Flag) {
-			_ = f.Value.Set(f.DefValue)
+			// Set appends to slice flags, so those are emptied instead
+			if slice, ok := f.Value.(pflag.SliceValue); ok {
+				_ = slice.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
 			f.Changed = false
 		})
 	}
 	reset(rootCmd.PersistentFlags())
-	for _, sub := range rootCmd.Commands() {
-		reset(sub.Flags())
+	var resetTree func(cmd *cobra.Command)
+	resetTree = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			reset(sub.Flags())
+			resetTree(sub)
+		}
 	}
+	resetTree(rootCmd)
 }
 
 type cliRun struct {
-	stdout  string
-	request map[string]any
+	stdout   string
+	request  map[string]any
+	requests []map[string]any
 }
 
 // runCLI executes rootCmd end-to-end against a mock Scalyr server that replies
 // with mockResponse, and returns captured stdout plus the parsed request body.
 func runCLI(t *testing.T, mockResponse string, args ...string) cliRun {
 	t.Helper()
+	return runCLIResponses(t, []string{mockResponse}, args...)
+}
+
+// runCLIResponses is runCLI for commands that make several API calls: the mock
+// server replies with responses in order, repeating the last one once they run
+// out. request holds the final request body and requests every one of them.
+func runCLIResponses(t *testing.T, responses []string, args ...string) cliRun {
+	t.Helper()
+	return runCLIServer(t, responses, func(serverURL string) []string {
+		return append(append([]string{}, args...), "--token", "test-token", "--server", serverURL)
+	})
+}
+
+// runCLIServer is runCLIResponses for tests that pass the mock server's URL
+// some other way, such as through a config file profile: buildArgs receives
+// the URL and returns the full command line.
+func runCLIServer(t *testing.T, responses []string, buildArgs func(serverURL string) []string) cliRun {
+	t.Helper()
 
 	var (
-		mu      sync.Mutex
-		request map[string]any
+		mu       sync.Mutex
+		requests []map[string]any
 	)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, http.MethodPost, r.Method, "Scalyr API requests must be POST")
@@ -74,11 +113,12 @@ func runCLI(t *testing.T, mockResponse string, args ...string) cliRun {
 		assert.NoError(t, json.Unmarshal(raw, &body))
 
 		mu.Lock()
-		request = body
+		requests = append(requests, body)
+		idx := min(len(requests), len(responses)) - 1
 		mu.Unlock()
 
 		w.Header().Set("Content-Type", "application/json")
-		_, _ = io.WriteString(w, mockResponse)
+		_, _ = io.WriteString(w, responses[idx])
 	}))
 	defer server.Close()
 
@@ -86,7 +126,7 @@ func runCLI(t *testing.T, mockResponse string, args ...string) cliRun {
 	errors.OutputJSON = false
 	defer func() { errors.OutputJSON = false }()
 
-	fullArgs := append(append([]string{}, args...), "--token", "test-token", "--server", server.URL)
+	fullArgs := buildArgs(server.URL)
 
 	out := captureStdout(t, func() {
 		rootCmd.SetArgs(fullArgs)
@@ -96,7 +136,11 @@ func runCLI(t *testing.T, mockResponse string, args ...string) cliRun {
 
 	mu.Lock()
 	defer mu.Unlock()
-	return cliRun{stdout: out, request: request}
+	run := cliRun{stdout: out, requests: requests}
+	if len(requests) > 0 {
+		run.request = requests[len(requests)-1]
+	}
+	return run
 }
 
 func TestE2EQueryOutputFormats(t *testing.T) {
@@ -175,6 +219,39 @@ func TestE2EQueryOutputFormats(t *testing.T) {
 	}
 }
 
+func TestE2EQueryColor(t *testing.T) {
+	run := runCLI(t, mockQueryResponse, "query", "connection", "--output", "compact", "--color", "always")
+	expected := "\033[2m22:13:20\033[0m I user logged in\n" +
+		"\033[2m22:13:21\033[0m \033[31mE\033[0m \033[31mdb \033[0m\033[7mconnection\033[0m\033[31m failed\033[0m\n"
+	assert.Equal(t, expected, run.stdout)
+
+	t.Setenv("FORCE_COLOR", "1")
+	run = runCLI(t, mockQueryResponse, "query", "connection", "--output", "compact", "--color", "never")
+	assert.NotContains(t, run.stdout, "\033[")
+}
+
+func TestE2ETimeFormat(t *testing.T) {
+	run := runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", "compact", "--tz", "America/New_York")
+	assert.Equal(t, "17:13:20 I user logged in\n17:13:21 E db connection failed\n", run.stdout)
+
+	run = runCLI(t, mockQueryResponse,
+		"query", "severity >= 3", "--output", "csv", "--columns", "timestamp,message",
+		"--time-format", "rfc3339", "--tz", "America/New_York")
+	assert.Equal(t, "timestamp,message\n"+
+		"2023-11-14T17:13:20-05:00,user logged in\n"+
+		"2023-11-14T17:13:21-05:00,db connection failed\n", run.stdout)
+
+	run = runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", "json", "--time-format", "rfc3339")
+	assert.Contains(t, run.stdout, `"timestamp":"1700000000000000000"`, "JSON keeps the raw timestamps")
+
+	t.Setenv("scalyr_time_format", "unixms")
+	run = runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", "singleline")
+	assert.True(t, strings.HasPrefix(run.stdout, "1700000000000 [3] user logged in"), "the config default applies: %s", run.stdout)
+
+	run = runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", "singleline", "--time-format", "raw")
+	assert.True(t, strings.HasPrefix(run.stdout, "1700000000000000000 [3]"), "the flag overrides the config default: %s", run.stdout)
+}
+
 func TestE2EQueryColumnsCSV(t *testing.T) {
 	run := runCLI(t, mockQueryResponse,
 		"query", `$source="accessLog"`,
@@ -197,6 +274,22 @@ func TestE2EQueryFieldsJSON(t *testing.T) {
 	assert.JSONEq(t, expected, run.stdout)
 }
 
+func TestE2EQueryTemplate(t *testing.T) {
+	run := runCLI(t, mockQueryResponse,
+		"query", "severity >= 3",
+		"--template", `{{.Timestamp | time "15:04:05"}} {{.Severity | severity | pad 5}} {{.Attributes.host | default "-"}} {{.Message}}`)
+
+	expected := "22:13:20 info  web-01 user logged in\n" +
+		"22:13:21 error - db connection failed\n"
+	assert.Equal(t, expected, run.stdout)
+
+	path := filepath.Join(t.TempDir(), "event.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte("{{sevchar .Severity}} {{.Message}}\n"), 0o600))
+
+	run = runCLI(t, mockQueryResponse, "query", "severity >= 3", "--all", "--template-file", path)
+	assert.Equal(t, "I user logged in\nE db connection failed\n", run.stdout)
+}
+
 // TestE2EQueryNonTTYDefaultsToJSON guards the documented behavior that piped
 // output (stdout is a pipe during tests) falls back to compact JSON.
 func TestE2EQueryNonTTYDefaultsToJSON(t *testing.T) {
@@ -204,6 +297,58 @@ func TestE2EQueryNonTTYDefaultsToJSON(t *testing.T) {
 	assert.JSONEq(t, mockQueryResponse, run.stdout)
 }
 
+// TestE2EQuerySinglePageStreams checks that a single-page query prints each
+// event as soon as it is decoded: the mock server holds back the rest of the
+// body until the first event has reached stdout.
+func TestE2EQuerySinglePageStreams(t *testing.T) {
+	firstWritten := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"status":"success","matches":[`+
+			`{"timestamp":"1700000000000000000","severity":3,"message":"first"}`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-firstWritten:
+		case <-time.After(5 * time.Second):
+			t.Error("the first event was not written before the body ended")
+		}
+		_, _ = io.WriteString(w, `,{"timestamp":"1700000001000000000","severity":3,"message":"second"}]}`)
+	}))
+	defer server.Close()
+
+	resetCLIFlags()
+	errors.OutputJSON = false
+
+	originalStdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = originalStdout }()
+
+	var lines []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if lines = append(lines, scanner.Text()); len(lines) == 1 {
+				close(firstWritten)
+			}
+		}
+	}()
+
+	rootCmd.SetArgs([]string{"query", "severity >= 3", "--output", "singleline",
+		"--token", "test-token", "--server", server.URL})
+	require.NoError(t, rootCmd.Execute())
+	rootCmd.SetArgs(nil)
+	require.NoError(t, w.Close())
+	<-done
+
+	assert.Equal(t, []string{
+		"1700000000000000000 [3] first",
+		"1700000001000000000 [3] second",
+	}, lines)
+}
+
 func TestE2EQueryRequestPayload(t *testing.T) {
 	run := runCLI(t, mockQueryResponse,
 		"query", `$serverHost="host100"`,
@@ -216,6 +361,509 @@ func TestE2EQueryRequestPayload(t *testing.T) {
 	assert.Equal(t, "test-token", run.request["token"])
 }
 
+// Two pages of query results linked by a continuation token, used to check
+// that --all/--limit output matches what a single combined page would print.
+const (
+	mockQueryPage1 = `{"status":"success","matches":[` +
+		`{"timestamp":"1700000000000000000","severity":3,"message":"user logged in","thread":"main","attributes":{"host":"web-01"}}` +
+		`],"continuationToken":"page-2"}`
+
+	mockQueryPage2 = `{"status":"success","matches":[` +
+		`{"timestamp":"1700000001000000000","severity":5,"message":"db connection failed"}` +
+		`]}`
+)
+
+func TestE2EQueryAllMatchesSinglePageOutput(t *testing.T) {
+	formats := []string{"multiline", "singleline", "compact", "csv", "json", "json-pretty"}
+
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			single := runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", format)
+			paged := runCLIResponses(t, []string{mockQueryPage1, mockQueryPage2},
+				"query", "severity >= 3", "--all", "--output", format)
+
+			assert.Equal(t, single.stdout, paged.stdout)
+			require.Len(t, paged.requests, 2)
+			assert.Equal(t, "page-2", paged.requests[1]["continuationToken"])
+			assert.Equal(t, "severity >= 3", paged.requests[1]["filter"])
+		})
+	}
+}
+
+func TestE2EQueryAllFieldsJSON(t *testing.T) {
+	for _, format := range []string{"json", "json-pretty"} {
+		t.Run(format, func(t *testing.T) {
+			single := runCLI(t, mockQueryResponse,
+				"query", "severity >= 3", "--output", format, "--fields", "timestamp,message")
+			paged := runCLIResponses(t, []string{mockQueryPage1, mockQueryPage2},
+				"query", "severity >= 3", "--all", "--output", format, "--fields", "timestamp,message")
+
+			assert.Equal(t, single.stdout, paged.stdout)
+		})
+	}
+}
+
+func TestE2EQueryAllEmptyResult(t *testing.T) {
+	empty := `{"status":"success","matches":[]}`
+
+	run := runCLIResponses(t, []string{empty}, "query", "--all", "--output", "json")
+	assert.JSONEq(t, empty, run.stdout)
+
+	run = runCLIResponses(t, []string{empty}, "query", "--all", "--output", "json-pretty")
+	assert.JSONEq(t, empty, run.stdout)
+}
+
+func TestE2EQueryLimitRequestsRemainder(t *testing.T) {
+	run := runCLIResponses(t, []string{mockQueryPage1, mockQueryPage2},
+		"query", "severity >= 3", "--limit", "2", "--start", "24h", "--output", "compact")
+
+	assert.Equal(t, "22:13:20 I user logged in\n22:13:21 E db connection failed\n", run.stdout)
+	require.Len(t, run.requests, 2)
+	assert.Equal(t, float64(2), run.requests[0]["maxCount"])
+	assert.Equal(t, float64(1), run.requests[1]["maxCount"])
+	assert.Equal(t, "24h", run.requests[1]["startTime"])
+}
+
+func TestE2EQuerySplitQueriesEachWindow(t *testing.T) {
+	run := runCLIResponses(t, []string{mockQueryPage2},
+		"query", "severity >= 3", "--start", "2024-01-15 00:00", "--end", "2024-01-15 03:00",
+		"--split", "1h", "--parallel", "1", "--count", "100", "--output", "compact")
+
+	assert.Equal(t, strings.Repeat("22:13:21 E db connection failed\n", 3), run.stdout)
+	require.Len(t, run.requests, 3)
+
+	start, err := time.ParseInLocation("2006-01-02 15:04", "2024-01-15 00:00", time.Local)
+	require.NoError(t, err)
+	for i, req := range run.requests {
+		windowStart := start.Add(time.Duration(i) * time.Hour)
+		assert.Equal(t, strconv.FormatInt(windowStart.UnixNano(), 10), req["startTime"])
+		assert.Equal(t, strconv.FormatInt(windowStart.Add(time.Hour).UnixNano(), 10), req["endTime"])
+		assert.Equal(t, float64(100), req["maxCount"], "--count applies per window")
+		assert.Equal(t, "severity >= 3", req["filter"])
+	}
+}
+
+func TestE2EPowerQuerySplitMergesWindows(t *testing.T) {
+	response := `{"status":"success","matchingEvents":10,` +
+		`"columns":[{"name":"uriPath"},{"name":"requests"}],` +
+		`"values":[["/login",100]]}`
+
+	run := runCLIResponses(t, []string{response},
+		"power-query", "dataset='accesslog' | group requests = count() by uriPath",
+		"--start", "2024-01-15", "--end", "2024-01-17", "--split", "1d", "--output", "json")
+
+	require.Len(t, run.requests, 2)
+	var result client.PowerQueryResponse
+	require.NoError(t, json.Unmarshal([]byte(run.stdout), &result))
+	assert.Equal(t, float64(20), result.MatchingEvents)
+	assert.Len(t, result.Values, 2)
+}
+
+func TestE2EPowerQueryLRQ(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodPost:
+			_, _ = io.WriteString(w, `{"id":"q1","stepsCompleted":0,"stepsTotal":1}`)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			_, _ = io.WriteString(w, `{"id":"q1","stepsCompleted":1,"stepsTotal":1,"data":`+mockPowerQueryResponse+`}`)
+		}
+	}))
+	defer server.Close()
+
+	resetCLIFlags()
+	out := captureStdout(t, func() {
+		rootCmd.SetArgs([]string{"power-query", "dataset='accesslog' | group requests = count() by uriPath",
+			"--start", "1h", "--lrq", "--output", "json", "--token", "test-token", "--server", server.URL})
+		require.NoError(t, rootCmd.Execute())
+	})
+	rootCmd.SetArgs(nil)
+
+	assert.Equal(t, []string{"POST /api/v2/query", "GET /api/v2/query/q1", "DELETE /api/v2/query/q1"}, methods,
+		"the completed query is released on the server")
+	var result client.PowerQueryResponse
+	require.NoError(t, json.Unmarshal([]byte(out), &result))
+	assert.Equal(t, "success", result.Status)
+	assert.Equal(t, [][]any{{"/login", float64(100)}, {"/home", float64(250)}}, result.Values)
+}
+
+// writeProfilesConfig isolates the config search paths in a temp directory
+// holding a logbasset.yaml with a "mock" profile for serverURL and a "staging"
+// profile that must never be contacted.
+func writeProfilesConfig(t *testing.T, serverURL string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("scalyr_readlog_token", "")
+	t.Setenv("scalyr_server", "")
+	t.Setenv("scalyr_profile", "")
+	t.Chdir(dir)
+
+	contents := "token: default-token\n" +
+		"server: https://unreachable.invalid\n" +
+		"profiles:\n" +
+		"  mock:\n" +
+		"    server: " + serverURL + "\n" +
+		"    token: mock-token\n" +
+		"    priority: low\n" +
+		"  staging:\n" +
+		"    server: https://staging.invalid\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logbasset.yaml"), []byte(contents), 0600))
+}
+
+func TestE2EQueryProfileSelectsServerAndToken(t *testing.T) {
+	run := runCLIServer(t, []string{mockQueryResponse}, func(serverURL string) []string {
+		writeProfilesConfig(t, serverURL)
+		return []string{"query", "--profile", "mock", "--output", "json"}
+	})
+
+	require.Len(t, run.requests, 1)
+	assert.Equal(t, "mock-token", run.request["token"])
+	assert.Equal(t, "low", run.request["priority"], "profile priority is not masked by the --priority default")
+	assert.JSONEq(t, mockQueryResponse, run.stdout)
+}
+
+func TestE2EQueryProfileFromEnv(t *testing.T) {
+	run := runCLIServer(t, []string{mockQueryResponse}, func(serverURL string) []string {
+		writeProfilesConfig(t, serverURL)
+		t.Setenv("scalyr_profile", "mock")
+		return []string{"query", "--priority", "high", "--output", "json"}
+	})
+
+	require.Len(t, run.requests, 1)
+	assert.Equal(t, "mock-token", run.request["token"])
+	assert.Equal(t, "high", run.request["priority"], "an explicit flag overrides the profile")
+}
+
+// runConfigCLI executes a command that needs no API server, such as the
+// config subcommands, and returns its stdout.
+func runConfigCLI(t *testing.T, args ...string) string {
+	t.Helper()
+	resetCLIFlags()
+	defer func() { errors.OutputJSON = false }()
+
+	out := captureStdout(t, func() {
+		rootCmd.SetArgs(args)
+		require.NoError(t, rootCmd.Execute())
+	})
+	rootCmd.SetArgs(nil)
+	return out
+}
+
+func TestE2EConfigProfiles(t *testing.T) {
+	writeProfilesConfig(t, "https://mock.invalid")
+
+	out := runConfigCLI(t, "config", "profiles", "--profile", "staging", "--output", "json")
+
+	var profiles []profileSummary
+	require.NoError(t, json.Unmarshal([]byte(out), &profiles))
+	assert.Equal(t, []profileSummary{
+		{Name: "mock", Server: "https://mock.invalid", Priority: "low", HasToken: true},
+		{Name: "staging", Active: true, Server: "https://staging.invalid"},
+	}, profiles)
+	assert.NotContains(t, out, "mock-token", "tokens must never be printed")
+}
+
+func TestE2EConfigSetGetUnset(t *testing.T) {
+	writeProfilesConfig(t, "https://mock.invalid")
+	path, err := filepath.Abs("logbasset.yaml")
+	require.NoError(t, err)
+
+	out := runConfigCLI(t, "config", "set", "profiles.eu.server", "https://eu.scalyr.com")
+	assert.Contains(t, out, "Set profiles.eu.server")
+
+	assert.Equal(t, "https://eu.scalyr.com\n", runConfigCLI(t, "config", "get", "profiles.eu.server"))
+	assert.Equal(t, "https://eu.scalyr.com\n", runConfigCLI(t, "config", "get", "server", "--profile", "eu"))
+
+	runConfigCLI(t, "config", "set", "timeout", "90s")
+	assert.Equal(t, "1m30s\n", runConfigCLI(t, "config", "get", "timeout"))
+	assert.Equal(t, "5s\n", runConfigCLI(t, "config", "get", "timeout", "--timeout", "5s"))
+
+	runConfigCLI(t, "config", "unset", "timeout")
+	assert.Equal(t, "30s\n", runConfigCLI(t, "config", "get", "timeout"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "  eu:\n    server: https://eu.scalyr.com\n")
+	assert.NotContains(t, string(data), "timeout")
+}
+
+func TestE2EConfigProfileVerbose(t *testing.T) {
+	writeProfilesConfig(t, "https://mock.invalid")
+	t.Setenv("scalyr_verbose", "")
+	runConfigCLI(t, "config", "set", "profiles.staging.verbose", "true")
+
+	assert.Equal(t, "true\n", runConfigCLI(t, "config", "get", "verbose", "--profile", "staging"),
+		"a profile's verbose is not masked by the --verbose default")
+	assert.Equal(t, "false\n", runConfigCLI(t, "config", "get", "verbose"))
+	assert.Equal(t, "true\n", runConfigCLI(t, "config", "get", "verbose", "--verbose"))
+}
+
+func TestE2EConfigListReportsSources(t *testing.T) {
+	writeProfilesConfig(t, "https://mock.invalid")
+	t.Setenv("scalyr_server", "https://env.scalyr.com")
+
+	out := runConfigCLI(t, "config", "list", "--profile", "mock", "--priority", "high", "--output", "json")
+
+	var settings []settingSummary
+	require.NoError(t, json.Unmarshal([]byte(out), &settings))
+	sources := make(map[string]settingSummary)
+	for _, s := range settings {
+		sources[s.Key] = s
+	}
+
+	assert.Equal(t, settingSummary{Key: "server", Value: "https://env.scalyr.com", Source: "env"}, sources["server"])
+	assert.Equal(t, settingSummary{Key: "token", Value: "****oken", Source: "profile"}, sources["token"])
+	assert.Equal(t, settingSummary{Key: "priority", Value: "high", Source: "flag"}, sources["priority"])
+	assert.Equal(t, settingSummary{Key: "log_level", Value: "info", Source: "default"}, sources["log_level"])
+	assert.Equal(t, settingSummary{Key: "profile", Value: "mock", Source: "flag"}, sources["profile"])
+	assert.NotContains(t, out, "mock-token")
+}
+
+func TestE2EConfigInitNonInteractive(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("scalyr_readlog_token", "")
+	t.Setenv("scalyr_server", "")
+	t.Setenv("scalyr_profile", "")
+	t.Chdir(dir)
+
+	out := runConfigCLI(t, "config", "init", "--non-interactive",
+		"--token", "init-token", "--server", "https://eu.scalyr.com")
+
+	path := filepath.Join(dir, ".config", "logbasset", "logbasset.yaml")
+	assert.Equal(t, "Wrote "+path+"\n", out)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "server: https://eu.scalyr.com\ntoken: init-token\npriority: high\n", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Equal(t, path+"\n", runConfigCLI(t, "config", "path"))
+	assert.Contains(t, runConfigCLI(t, "config", "validate"), "Configuration is valid")
+}
+
+func TestE2EConfigValidateWithoutLiteralToken(t *testing.T) {
+	for _, source := range []string{"token_command: echo command-token\n", "credential_store: file\n"} {
+		writeProfilesConfig(t, "https://mock.invalid")
+		contents := source +
+			"server: https://mock.invalid\n" +
+			"profiles:\n" +
+			"  staging:\n" +
+			"    server: https://staging.invalid\n"
+		require.NoError(t, os.WriteFile("logbasset.yaml", []byte(contents), 0600))
+
+		assert.Contains(t, runConfigCLI(t, "config", "validate"), "Configuration is valid", source)
+	}
+}
+
+func TestE2EQueryTokenCommand(t *testing.T) {
+	run := runCLIServer(t, []string{mockQueryResponse}, func(serverURL string) []string {
+		writeProfilesConfig(t, serverURL)
+		_, err := config.UnsetInFile("logbasset.yaml", "profiles.mock.token")
+		require.NoError(t, err)
+		require.NoError(t, config.SetInFile("logbasset.yaml", "profiles.mock.token_command", "echo command-token"))
+		return []string{"query", "--profile", "mock", "--output", "json"}
+	})
+
+	require.Len(t, run.requests, 1)
+	assert.Equal(t, "command-token", run.request["token"])
+}
+
+func TestE2EConfigCredentialsSetStoresToken(t *testing.T) {
+	writeProfilesConfig(t, "https://mock.invalid")
+	t.Setenv("scalyr_credentials_passphrase", "test passphrase")
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	_, err = io.WriteString(w, "stored-token\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	originalStdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = originalStdin }()
+
+	out := runConfigCLI(t, "config", "credentials", "set", "--profile", "staging")
+	assert.Equal(t, "Stored token for staging\n", out)
+
+	store, err := config.OpenCredentialStore("file")
+	require.NoError(t, err)
+	token, err := store.Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "stored-token", token)
+}
+
+func TestE2EIngestSendsLinesAsEvents(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "app.log")
+	second := filepath.Join(dir, "worker.log")
+	require.NoError(t, os.WriteFile(first, []byte("started\n\nlistening on :8080\n"), 0600))
+	require.NoError(t, os.WriteFile(second, []byte("job done\n"), 0600))
+
+	run := runCLI(t, `{"status":"success"}`,
+		"ingest", first, second, "--server-host", "web-1", "--parser", "json",
+		"--attr", "env=staging", "--severity", "4", "--output", "json")
+
+	require.Len(t, run.requests, 1)
+	req := run.request
+	assert.Equal(t, "test-token", req["token"], "--token is the write token for ingest")
+	assert.NotEmpty(t, req["session"])
+	assert.Equal(t, map[string]any{"serverHost": "web-1", "env": "staging"}, req["sessionInfo"])
+	assert.Equal(t, []any{
+		map[string]any{"id": "1", "name": first},
+		map[string]any{"id": "2", "name": second},
+	}, req["threads"])
+
+	events := req["events"].([]any)
+	require.Len(t, events, 3, "empty lines are skipped")
+	var messages []string
+	for _, e := range events {
+		event := e.(map[string]any)
+		attrs := event["attrs"].(map[string]any)
+		messages = append(messages, attrs["message"].(string))
+		assert.Equal(t, "json", attrs["parser"])
+		assert.Equal(t, float64(4), event["sev"])
+	}
+	assert.Equal(t, []string{"started", "listening on :8080", "job done"}, messages)
+	assert.Equal(t, "2", events[2].(map[string]any)["thread"])
+
+	var summary map[string]any
+	require.NoError(t, json.Unmarshal([]byte(run.stdout), &summary))
+	assert.Equal(t, req["session"], summary["session"])
+	assert.Equal(t, float64(3), summary["events"])
+	assert.Equal(t, float64(1), summary["requests"])
+}
+
+func TestE2EIngestUsesProfileWriteToken(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "in.log")
+	require.NoError(t, os.WriteFile(input, []byte("hello\n"), 0600))
+
+	run := runCLIServer(t, []string{`{"status":"success"}`}, func(serverURL string) []string {
+		writeProfilesConfig(t, serverURL)
+		require.NoError(t, config.SetInFile("logbasset.yaml", "profiles.mock.write_token", "mock-write-token"))
+		return []string{"ingest", input, "--profile", "mock", "--output", "json"}
+	})
+
+	require.Len(t, run.requests, 1)
+	assert.Equal(t, "mock-write-token", run.request["token"], "the read token is never sent to the write API")
+}
+
+func TestE2EFilesPullThenPush(t *testing.T) {
+	dir := t.TempDir()
+
+	pull := runCLIResponses(t, []string{
+		`{"status":"success","paths":["/scalyr/parsers/app","/scalyr-old/parsers/app","/dashboards/errors"]}`,
+		`{"status":"success","path":"/scalyr/parsers/app","version":3,"content":"{ patterns: {} }\n"}`,
+	}, "files", "pull", "/scalyr", "--dir", dir, "--output", "json")
+
+	require.Len(t, pull.requests, 2, "only files under the prefix are fetched")
+	assert.Equal(t, "test-token", pull.requests[0]["token"], "--token is the config token for files")
+	assert.Equal(t, "/scalyr/parsers/app", pull.request["path"])
+	assert.JSONEq(t, `[{"path":"/scalyr/parsers/app","action":"pulled","version":3}]`, pull.stdout)
+
+	local := filepath.Join(dir, "scalyr", "parsers", "app")
+	data, err := os.ReadFile(local)
+	require.NoError(t, err)
+	assert.Equal(t, "{ patterns: {} }\n", string(data))
+
+	require.NoError(t, os.WriteFile(local, []byte("{ patterns: { x: 1 } }\n"), 0644))
+
+	push := runCLIResponses(t, []string{
+		`{"status":"success"}`,
+		`{"status":"success","path":"/scalyr/parsers/app","version":4,"content":"{ patterns: { x: 1 } }\n"}`,
+	}, "files", "push", "--dir", dir, "--output", "json")
+
+	require.Len(t, push.requests, 2)
+	put := push.requests[0]
+	assert.Equal(t, "/scalyr/parsers/app", put["path"])
+	assert.Equal(t, "{ patterns: { x: 1 } }\n", put["content"])
+	assert.Equal(t, float64(3), put["expectedVersion"], "the pulled version guards the write")
+	assert.JSONEq(t, `[{"path":"/scalyr/parsers/app","action":"updated","version":4}]`, push.stdout)
+
+	manifest, err := filesync.LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), manifest.Files["/scalyr/parsers/app"].Version)
+
+	again := runCLIResponses(t, []string{`{"status":"success"}`}, "files", "push", "--dir", dir, "--output", "json")
+	assert.Empty(t, again.requests, "unchanged files are not pushed")
+	assert.JSONEq(t, `[]`, again.stdout)
+}
+
+func TestE2EFilesPullThenPushHiddenFile(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLIResponses(t, []string{
+		`{"status":"success","paths":["/scalyr/.lookups"]}`,
+		`{"status":"success","path":"/scalyr/.lookups","version":2,"content":"a,b\n"}`,
+	}, "files", "pull", "--dir", dir, "--output", "json")
+
+	local := filepath.Join(dir, "scalyr", ".lookups")
+	require.NoError(t, os.WriteFile(local, []byte("a,c\n"), 0644))
+
+	push := runCLIResponses(t, []string{
+		`{"status":"success"}`,
+		`{"status":"success","path":"/scalyr/.lookups","version":3,"content":"a,c\n"}`,
+	}, "files", "push", "--dir", dir, "--output", "json")
+
+	require.NotEmpty(t, push.requests, "a pulled hidden file is pushed through the manifest")
+	assert.Equal(t, "/scalyr/.lookups", push.requests[0]["path"])
+	assert.Equal(t, float64(2), push.requests[0]["expectedVersion"])
+}
+
+func TestE2EFilesPushSkipsUntrackedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, filesync.WriteLocal(dir, "/scalyr/parsers/app", []byte("edited\n")))
+	require.NoError(t, filesync.WriteLocal(dir, "/scalyr/parsers/new", []byte("new\n")))
+	require.NoError(t, filesync.WriteLocal(dir, "/README.md", []byte("# config\n")))
+	manifest, err := filesync.LoadManifest(dir)
+	require.NoError(t, err)
+	manifest.Files["/scalyr/parsers/app"] = filesync.Entry{Version: 3, SHA256: filesync.Hash([]byte("pulled\n"))}
+	require.NoError(t, manifest.Save(dir))
+
+	listFiles := `{"status":"success","paths":["/scalyr/parsers/app"]}`
+
+	run := runCLIResponses(t, []string{listFiles}, "files", "push", "--dir", dir, "--dry-run", "--output", "json")
+	require.Len(t, run.requests, 1, "remote paths are listed once")
+	assert.JSONEq(t, `[
+		{"path":"/scalyr/parsers/app","action":"would be updated","version":3},
+		{"path":"/README.md","action":"untracked"},
+		{"path":"/scalyr/parsers/new","action":"untracked"}
+	]`, run.stdout, "files that were never pulled are listed after the others, not uploaded")
+
+	run = runCLIResponses(t, []string{listFiles}, "files", "push", "--dir", dir, "--dry-run", "--create", "--output", "json")
+	assert.JSONEq(t, `[
+		{"path":"/README.md","action":"would be created"},
+		{"path":"/scalyr/parsers/app","action":"would be updated","version":3},
+		{"path":"/scalyr/parsers/new","action":"would be created"}
+	]`, run.stdout)
+
+	run = runCLIResponses(t, []string{listFiles}, "files", "push", "/scalyr/parsers/new", "--dir", dir, "--dry-run", "--output", "json")
+	assert.JSONEq(t, `[{"path":"/scalyr/parsers/new","action":"would be created"}]`, run.stdout,
+		"a path named on the command line is created")
+}
+
+func TestE2EFilesDiff(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, filesync.WriteLocal(dir, "/scalyr/parsers/app", []byte("a\nb\n")))
+
+	run := runCLIResponses(t, []string{
+		`{"status":"success","path":"/scalyr/parsers/app","version":3,"content":"a\nc\n"}`,
+	}, "files", "diff", "--dir", dir, "--output", "json")
+
+	var diffs []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(run.stdout), &diffs))
+	require.Len(t, diffs, 1)
+	assert.Equal(t, "changed", diffs[0]["status"])
+	assert.Equal(t, "--- remote:/scalyr/parsers/app\n+++ local:/scalyr/parsers/app\n@@ -1,2 +1,2 @@\n a\n-c\n+b\n", diffs[0]["diff"])
+}
+
 func TestE2EPowerQueryOutputFormats(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -256,6 +904,112 @@ func TestE2EPowerQueryOutputFormats(t *testing.T) {
 	}
 }
 
+func TestE2ETableOutput(t *testing.T) {
+	t.Setenv("COLUMNS", "80")
+
+	run := runCLI(t, mockPowerQueryResponse,
+		"power-query", "dataset='accesslog' | group requests = count() by uriPath",
+		"--start", "24h", "--output", "table")
+	assert.Equal(t, ""+
+		"┌─────────┬──────────┐\n"+
+		"│ uriPath │ requests │\n"+
+		"├─────────┼──────────┤\n"+
+		"│ /login  │      100 │\n"+
+		"│ /home   │      250 │\n"+
+		"└─────────┴──────────┘\n", run.stdout)
+
+	run = runCLI(t, mockFacetQueryResponse,
+		"facet-query", `$dataset="accesslog"`, "uriPath", "--start", "24h", "--output", "table-ascii")
+	assert.Equal(t, ""+
+		"+-------+-------------+\n"+
+		"| count | value       |\n"+
+		"+-------+-------------+\n"+
+		"|    42 | /index.html |\n"+
+		"|    17 | /about      |\n"+
+		"+-------+-------------+\n", run.stdout)
+
+	run = runCLI(t, mockQueryResponse,
+		"query", "severity >= 3", "--columns", "severity,message,host", "--output", "table-ascii")
+	assert.Equal(t, ""+
+		"+----------+----------------------+--------+\n"+
+		"| severity | message              | host   |\n"+
+		"+----------+----------------------+--------+\n"+
+		"|        3 | user logged in       | web-01 |\n"+
+		"|        5 | db connection failed |        |\n"+
+		"+----------+----------------------+--------+\n", run.stdout)
+}
+
+func TestE2EReportOutputFormats(t *testing.T) {
+	run := runCLI(t, mockPowerQueryResponse,
+		"power-query", "dataset='accesslog' | group requests = count() by uriPath",
+		"--start", "24h", "--output", "markdown")
+	assert.Equal(t, ""+
+		"| uriPath | requests |\n"+
+		"| --- | ---: |\n"+
+		"| /login | 100 |\n"+
+		"| /home | 250 |\n", run.stdout)
+
+	run = runCLI(t, mockFacetQueryResponse,
+		"facet-query", `$dataset="accesslog"`, "uriPath", "--start", "24h", "--output", "tsv")
+	assert.Equal(t, "count\tvalue\n42\t/index.html\n17\t/about\n", run.stdout)
+
+	run = runCLI(t, mockQueryResponse,
+		"query", "severity >= 3", "--columns", "severity,message,host", "--output", "logfmt")
+	assert.Equal(t, ""+
+		"severity=3 message=\"user logged in\" host=web-01\n"+
+		"severity=5 message=\"db connection failed\" host=\"\"\n", run.stdout)
+
+	run = runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", "yaml")
+	assert.True(t, strings.HasPrefix(run.stdout, "status: success\n"), run.stdout)
+	assert.Contains(t, run.stdout, "message: user logged in\n")
+
+	run = runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", "html")
+	assert.Contains(t, run.stdout, "<!DOCTYPE html>")
+	assert.Contains(t, run.stdout, "<p class=\"count\">2 records</p>")
+}
+
+func TestE2EAttributeLayout(t *testing.T) {
+	response := `{"status":"success","matches":[{"timestamp":"1700000000000000000","severity":5,"message":"boom",` +
+		`"attributes":{"zone":"eu-1","app":"api","http":{"status":500,"method":"GET"}}}]}`
+
+	run := runCLI(t, response, "query", "severity >= 3", "--output", "singleline")
+	assert.Equal(t, `1700000000000000000 [5] boom [app=api, http={"method":"GET","status":500}, zone=eu-1]`+"\n", run.stdout)
+
+	run = runCLI(t, response, "query", "severity >= 3", "--output", "singleline", "--flatten", "--attr-order", "zone,http.status")
+	assert.Equal(t, "1700000000000000000 [5] boom [zone=eu-1, http.status=500, app=api, http.method=GET]\n", run.stdout)
+
+	run = runCLI(t, response, "query", "severity >= 3", "--output", "logfmt", "--flatten")
+	assert.Equal(t, "timestamp=1700000000000000000 severity=5 message=boom app=api http.method=GET http.status=500 zone=eu-1\n", run.stdout)
+}
+
+func TestE2EOutFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "errors.csv")
+	run := runCLI(t, mockQueryResponse,
+		"query", "severity >= 3", "--columns", "severity,message", "--output", "csv", "--out", path)
+	assert.Empty(t, run.stdout)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "severity,message\n3,user logged in\n5,db connection failed\n", string(data))
+
+	path = filepath.Join(dir, "errors.parquet")
+	run = runCLI(t, mockQueryResponse, "query", "severity >= 3", "--output", "parquet", "--out", path)
+	assert.Empty(t, run.stdout)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "PAR1") && strings.HasSuffix(string(data), "PAR1"), "a complete Parquet file")
+
+	path = filepath.Join(dir, "requests.arrow")
+	run = runCLI(t, mockPowerQueryResponse,
+		"power-query", "dataset='accesslog' | group requests = count() by uriPath",
+		"--start", "24h", "--output", "arrow", "--out", path)
+	assert.Empty(t, run.stdout)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "ARROW1"), "an Arrow IPC file")
+}
+
 func TestE2ENumericQueryOutputFormats(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -376,6 +1130,117 @@ func TestE2ETimeseriesQueryOutputFormats(t *testing.T) {
 	}
 }
 
+// assertBucketValues checks JSON numeric or timeseries output in the long
+// layout: one object per bucket, each starting where the last one ended.
+func assertBucketValues(t *testing.T, out string, values ...float64) {
+	t.Helper()
+
+	var rows []struct {
+		Start string  `json:"bucket_start"`
+		End   string  `json:"bucket_end"`
+		Value float64 `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &rows), out)
+	require.Len(t, rows, len(values))
+	for i, row := range rows {
+		assert.Equal(t, values[i], row.Value)
+		if i > 0 {
+			assert.Equal(t, rows[i-1].End, row.Start, "bucket %d", i)
+		}
+	}
+}
+
+func TestE2EBucketOutput(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+	bound := func(hours int) string {
+		return start.Add(time.Duration(hours) * time.Hour).UTC().Format(time.RFC3339)
+	}
+
+	run := runCLI(t, mockNumericQueryResponse,
+		"numeric-query", `$dataset="accesslog"`, "--start", "2024-01-02 00:00", "--end", "2024-01-02 03:00",
+		"--buckets", "3", "--long", "--output", "csv")
+	assert.Equal(t, "bucket_start,bucket_end,value\n"+
+		bound(0)+","+bound(1)+",1.5\n"+
+		bound(1)+","+bound(2)+",2\n"+
+		bound(2)+","+bound(3)+",3.14\n", run.stdout)
+
+	run = runCLI(t, mockTimeseriesQueryResponse,
+		"timeseries-query", `$dataset="accesslog"`, "--start", "2024-01-02 00:00", "--end", "2024-01-02 03:00",
+		"--buckets", "3", "--long", "--output", "json", "--time-format", "unix")
+	assert.JSONEq(t, fmt.Sprintf(`[
+		{"bucket_start":"%d","bucket_end":"%d","value":10},
+		{"bucket_start":"%d","bucket_end":"%d","value":20},
+		{"bucket_start":"%d","bucket_end":"%d","value":30}]`,
+		start.Unix(), start.Add(time.Hour).Unix(),
+		start.Add(time.Hour).Unix(), start.Add(2*time.Hour).Unix(),
+		start.Add(2*time.Hour).Unix(), start.Add(3*time.Hour).Unix()), run.stdout)
+
+	run = runCLI(t, mockTimeseriesQueryResponse,
+		"timeseries-query", `$dataset="accesslog"`, "--start", "24h", "--buckets", "3", "--long", "--output", "table-ascii")
+	assert.Contains(t, run.stdout, "| bucket_start ")
+	assert.Contains(t, run.stdout, "|    30 |")
+
+	run = runCLI(t, mockNumericQueryResponse,
+		"numeric-query", `$dataset="accesslog"`, "--start", "24h", "--buckets", "3", "--long", "--output", "json")
+	assertBucketValues(t, run.stdout, 1.5, 2, 3.14)
+}
+
+func TestE2ETimeseriesMultiQuery(t *testing.T) {
+	const response = `{"status":"success","results":[{"values":[1,2]},{"values":[3.5,4]}]}`
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local)
+	bound := func(hours int) string {
+		return start.Add(time.Duration(hours) * time.Hour).UTC().Format(time.RFC3339)
+	}
+
+	run := runCLI(t, response,
+		"timeseries-query", "--start", "2024-01-02 00:00", "--end", "2024-01-02 02:00", "--buckets", "2",
+		"--function", "count", "--query", "errors:severity >= 5:", "--query", `latency:$serverHost == "web-01":mean(latency)`,
+		"--long", "--output", "csv")
+	queries, ok := run.request["queries"].([]any)
+	require.True(t, ok)
+	require.Len(t, queries, 2, "every query goes in one request")
+	assert.Equal(t, "severity >= 5", queries[0].(map[string]any)["filter"])
+	assert.Equal(t, "count", queries[0].(map[string]any)["function"])
+	assert.Equal(t, "mean(latency)", queries[1].(map[string]any)["function"])
+	assert.Equal(t, "query,bucket_start,bucket_end,value\n"+
+		"errors,"+bound(0)+","+bound(1)+",1\n"+
+		"errors,"+bound(1)+","+bound(2)+",2\n"+
+		"latency,"+bound(0)+","+bound(1)+",3.5\n"+
+		"latency,"+bound(1)+","+bound(2)+",4\n", run.stdout)
+
+	file := filepath.Join(t.TempDir(), "dashboard.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("queries:\n  - name: errors\n    filter: severity >= 5\n    function: count\n"), 0644))
+	run = runCLI(t, response,
+		"timeseries-query", "--start", "24h", "--queries-file", file, "--query", "rate::rate", "--output", "json")
+	assert.Len(t, run.request["queries"], 2)
+	assert.JSONEq(t, `{"status":"success","results":[{"name":"errors","values":[1,2]},{"name":"rate","values":[3.5,4]}]}`, run.stdout)
+
+	run = runCLI(t, response,
+		"timeseries-query", "--start", "24h", "--query", "errors:error:count", "--query", "warnings:warn:count", "--output", "csv")
+	assert.Equal(t, "errors,1,2\nwarnings,3.5,4\n", run.stdout)
+}
+
+func TestE2ECheck(t *testing.T) {
+	run := runCLI(t, `{"status":"success","values":[4]}`,
+		"check", "--filter", "severity >= 5", "--function", "count", "--start", "10m",
+		"--warn", "10", "--crit", "50", "--label", "errors")
+	assert.Equal(t, "severity >= 5", run.request["filter"])
+	assert.Equal(t, "count", run.request["function"])
+	assert.EqualValues(t, 1, run.request["buckets"])
+	assert.Equal(t, "ERRORS OK - value 4 | errors=4;10;50\n", run.stdout,
+		"the status line is text even when piped")
+
+	run = runCLI(t, `{"status":"success","values":[10,12]}`,
+		"check", "--function", "count", "--start", "20m", "--change", "--crit", "200", "--output", "json")
+	assert.EqualValues(t, 2, run.request["buckets"], "--change compares the last two buckets")
+	var report map[string]any
+	require.NoError(t, json.Unmarshal([]byte(run.stdout), &report))
+	assert.Equal(t, "OK", report["state"])
+	assert.EqualValues(t, 0, report["code"])
+	assert.EqualValues(t, 20, report["value"])
+	assert.Equal(t, "change 20% (10 to 12)", report["message"])
+}
+
 // TestE2EDocsExamples runs the representative command invocations from README.md
 // end-to-end against mocked responses, exercising one example per command.
 func TestE2EDocsExamples(t *testing.T) {
@@ -2,14 +2,13 @@ package cli
 
 import (
 	"context"
-	"encoding/csv"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 
 	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
 	"github.com/andreagrandi/logbasset/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -28,13 +27,15 @@ var (
 	facetQueryEndTime   string
 	facetQueryCount     int
 	facetQueryOutput    string
+	facetQueryOut       string
 )
 
 func init() {
 	facetQueryCmd.Flags().StringVar(&facetQueryStartTime, "start", "", "Start time for the query (required)")
 	facetQueryCmd.Flags().StringVar(&facetQueryEndTime, "end", "", "End time for the query")
 	facetQueryCmd.Flags().IntVar(&facetQueryCount, "count", 100, "Number of distinct values to return (1-1000)")
-	facetQueryCmd.Flags().StringVar(&facetQueryOutput, "output", "csv", "Output format: csv|json|json-pretty")
+	facetQueryCmd.Flags().StringVar(&facetQueryOutput, "output", "csv", outputFlagUsage())
+	facetQueryCmd.Flags().StringVar(&facetQueryOut, "out", "", outFlagUsage)
 	facetQueryCmd.MarkFlagRequired("start")
 }
 
@@ -97,27 +98,11 @@ func runFacetQuery(cmd *cobra.Command, args []string) {
 		errors.OutputJSON = true
 	}
 
-	switch facetQueryOutput {
-	case "json":
-		outputJSON(result, false)
-	case "json-pretty":
-		outputJSON(result, true)
-	default:
-		outputFacetCSV(result.Values)
+	rows := make([][]any, len(result.Values))
+	for i, val := range result.Values {
+		rows[i] = []any{val.Count, val.Value}
 	}
-}
-
-func outputFacetCSV(values []client.FacetValue) {
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
 
-	writer.Write([]string{"count", "value"})
-
-	for _, val := range values {
-		record := []string{
-			strconv.Itoa(val.Count),
-			val.Value,
-		}
-		writer.Write(record)
-	}
+	f := newFormatter(facetQueryOutput, facetQueryOut, output.Options{})
+	exitOnOutputError(output.WriteRows(f, output.Header{Columns: []string{"count", "value"}, Document: result}, rows))
 }
@@ -0,0 +1,649 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/config"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/filesync"
+	"github.com/andreagrandi/logbasset/internal/logging"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+var filesCmd = &cobra.Command{
+	Use:   "files",
+	Short: "Manage Scalyr configuration files",
+	Long: `Parsers, dashboards, alerts and lookup tables are stored in Scalyr as configuration
+files. These commands list, read and write them, and pull or push a whole
+directory tree so the files can be kept in git.
+
+Reading needs a Read Config token (config_read_token, scalyr_readconfig_token);
+writing needs a Write Config token (config_write_token, scalyr_writeconfig_token),
+which can also read. --token sets the token for the command being run.`,
+}
+
+var filesLsCmd = &cobra.Command{
+	Use:         "ls [prefix]",
+	Short:       "List configuration files",
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{tokenAnnotation: string(config.TokenConfigRead)},
+	Run:         runFilesLs,
+}
+
+var filesGetCmd = &cobra.Command{
+	Use:         "get <path>",
+	Short:       "Print a configuration file",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{tokenAnnotation: string(config.TokenConfigRead)},
+	Run:         runFilesGet,
+}
+
+var filesPutCmd = &cobra.Command{
+	Use:   "put <path> [file]",
+	Short: "Create, replace or delete a configuration file",
+	Long: `Put writes the content of file, or stdin when no file is given, to the configuration
+file at path. With --expected-version the write fails if the file has been
+changed since that version was read.`,
+	Args:        cobra.RangeArgs(1, 2),
+	Annotations: map[string]string{tokenAnnotation: string(config.TokenConfigWrite)},
+	Run:         runFilesPut,
+}
+
+var filesDiffCmd = &cobra.Command{
+	Use:   "diff [path...]",
+	Short: "Show differences between local and remote configuration files",
+	Long: `Diff compares the local copies under --dir with the remote configuration files and
+prints a unified diff for each one that differs. Without paths it compares every
+file under --dir and every file recorded by the last pull or push.`,
+	Annotations: map[string]string{tokenAnnotation: string(config.TokenConfigRead)},
+	Run:         runFilesDiff,
+}
+
+var filesPullCmd = &cobra.Command{
+	Use:   "pull [prefix...]",
+	Short: "Download configuration files into a directory",
+	Long: `Pull downloads every configuration file, or those under the given path prefixes,
+into --dir, mirroring the remote paths. The version of each file is recorded in
+` + filesync.ManifestName + ` so a later push can detect concurrent changes.
+
+Local files edited since the last pull are not overwritten unless --force is set.`,
+	Annotations: map[string]string{tokenAnnotation: string(config.TokenConfigRead)},
+	Run:         runFilesPull,
+}
+
+var filesPushCmd = &cobra.Command{
+	Use:   "push [path...]",
+	Short: "Upload changed files from a directory",
+	Long: `Push uploads the files under --dir, or only the given remote paths, whose content
+changed since the last pull or push. Each write sends the version recorded at
+pull time as expectedVersion, so a file changed by someone else in the meantime
+is rejected instead of overwritten. Push never deletes remote files.
+
+Files that were never pulled are only uploaded when they exist remotely, or when
+--create is set or they are named as paths; the others are reported as untracked,
+so a README or go.mod next to the configuration files is never uploaded by
+accident. A never-pulled file that exists remotely is refused unless --force is
+set.`,
+	Annotations: map[string]string{tokenAnnotation: string(config.TokenConfigWrite)},
+	Run:         runFilesPush,
+}
+
+var (
+	filesOutput          string
+	filesDir             string
+	filesForce           bool
+	filesCreate          bool
+	filesDryRun          bool
+	filesExpectedVersion int64
+	filesDelete          bool
+)
+
+func init() {
+	filesLsCmd.Flags().StringVar(&filesOutput, "output", "text", "Output format: text|json")
+
+	filesGetCmd.Flags().StringVar(&filesOutput, "output", "text", "Output format: text (raw content)|json (content with version and dates)")
+
+	filesPutCmd.Flags().Int64Var(&filesExpectedVersion, "expected-version", 0, "Fail if the remote file is no longer at this version")
+	filesPutCmd.Flags().BoolVar(&filesDelete, "delete", false, "Delete the file instead of writing it")
+	filesPutCmd.Flags().StringVar(&filesOutput, "output", "text", "Output format: text|json")
+
+	filesDiffCmd.Flags().StringVar(&filesDir, "dir", ".", "Directory holding the local copies")
+	filesDiffCmd.Flags().StringVar(&filesOutput, "output", "text", "Output format: text|json")
+
+	filesPullCmd.Flags().StringVar(&filesDir, "dir", ".", "Directory to download into")
+	filesPullCmd.Flags().BoolVar(&filesForce, "force", false, "Overwrite local files that were edited since the last pull")
+	filesPullCmd.Flags().StringVar(&filesOutput, "output", "text", "Output format: text|json")
+
+	filesPushCmd.Flags().StringVar(&filesDir, "dir", ".", "Directory to upload from")
+	filesPushCmd.Flags().BoolVar(&filesDryRun, "dry-run", false, "Show what would be uploaded without writing anything")
+	filesPushCmd.Flags().BoolVar(&filesForce, "force", false, "Overwrite remote files that exist but were never pulled")
+	filesPushCmd.Flags().BoolVar(&filesCreate, "create", false, "Create remote files for local files that were never pulled")
+	filesPushCmd.Flags().StringVar(&filesOutput, "output", "text", "Output format: text|json")
+
+	filesCmd.AddCommand(filesLsCmd)
+	filesCmd.AddCommand(filesGetCmd)
+	filesCmd.AddCommand(filesPutCmd)
+	filesCmd.AddCommand(filesDiffCmd)
+	filesCmd.AddCommand(filesPullCmd)
+	filesCmd.AddCommand(filesPushCmd)
+}
+
+// fileResult reports what pull, push or put did with one file.
+type fileResult struct {
+	Path    string `json:"path"`
+	Action  string `json:"action"`
+	Version int64  `json:"version,omitempty"`
+}
+
+// fileDiff is one entry of files diff output.
+type fileDiff struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Diff   string `json:"diff,omitempty"`
+}
+
+// filesContext prepares --output and returns the context a files command
+// runs under. Commands that make one request per file are only cancellable,
+// so --timeout does not cut a large pull or push short.
+func filesContext(cmd *cobra.Command, perFile bool) (context.Context, context.CancelFunc) {
+	if !cmd.Flags().Changed("output") && !IsTTY() {
+		filesOutput = "json"
+		errors.OutputJSON = true
+	}
+	validateTextOrJSON(filesOutput)
+
+	ctx, cancel := commandContext(perFile)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	return ctx, cancel
+}
+
+func runFilesLs(cmd *cobra.Command, args []string) {
+	ctx, cancel := filesContext(cmd, false)
+	defer cancel()
+
+	c := getConfig().ClientFor(config.TokenConfigRead)
+	paths, err := listRemoteFiles(ctx, c, args)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	if filesOutput == "json" {
+		outputJSON(map[string][]string{"paths": paths}, false)
+		return
+	}
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+}
+
+func runFilesGet(cmd *cobra.Command, args []string) {
+	path, err := filesync.CleanRemotePath(args[0])
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	ctx, cancel := filesContext(cmd, false)
+	defer cancel()
+
+	c := getConfig().ClientFor(config.TokenConfigRead)
+	file, err := c.GetFile(ctx, path)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	if filesOutput == "json" {
+		file.Status = ""
+		outputJSON(file, false)
+		return
+	}
+	fmt.Print(file.Content)
+}
+
+func runFilesPut(cmd *cobra.Command, args []string) {
+	path, err := filesync.CleanRemotePath(args[0])
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	if filesDelete && len(args) > 1 {
+		errors.HandleErrorAndExit(errors.NewUsageError("--delete does not take a file", nil))
+	}
+	if filesExpectedVersion < 0 {
+		errors.HandleErrorAndExit(errors.NewValidationError("--expected-version must not be negative", nil))
+	}
+
+	var content []byte
+	if !filesDelete {
+		content, err = readPutContent(args[1:])
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+	}
+
+	ctx, cancel := filesContext(cmd, false)
+	defer cancel()
+
+	c := getConfig().ClientFor(config.TokenConfigWrite)
+	err = c.PutFile(ctx, client.PutFileParams{
+		Path:            path,
+		Content:         string(content),
+		ExpectedVersion: filesExpectedVersion,
+		Delete:          filesDelete,
+	})
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	action := "written"
+	if filesDelete {
+		action = "deleted"
+	}
+	outputFileResults([]fileResult{{Path: path, Action: action}})
+}
+
+func readPutContent(args []string) ([]byte, error) {
+	if len(args) == 0 || args[0] == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, errors.NewValidationError("failed to read stdin", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return nil, errors.NewValidationError("cannot read "+args[0], err)
+	}
+	return data, nil
+}
+
+func runFilesDiff(cmd *cobra.Command, args []string) {
+	manifest, err := filesync.LoadManifest(filesDir)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	paths, err := localFilePaths(filesDir, manifest, args)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	ctx, cancel := filesContext(cmd, true)
+	defer cancel()
+
+	c := getConfig().ClientFor(config.TokenConfigRead)
+
+	var diffs []fileDiff
+	for _, path := range paths {
+		local, hasLocal, err := filesync.ReadLocal(filesDir, path)
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+
+		var remote string
+		hasRemote := true
+		file, err := c.GetFile(ctx, path)
+		switch {
+		case client.IsNoSuchFile(err):
+			hasRemote = false
+		case err != nil:
+			errors.HandleErrorAndExit(err)
+		default:
+			remote = file.Content
+		}
+
+		d := fileDiff{Path: path, Status: "unchanged"}
+		switch {
+		case !hasLocal && !hasRemote:
+			continue
+		case !hasLocal:
+			d.Status = "remote-only"
+		case !hasRemote:
+			d.Status = "local-only"
+		case remote != string(local):
+			d.Status = "changed"
+		}
+		if d.Status != "unchanged" {
+			d.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        diffLines(remote),
+				B:        diffLines(string(local)),
+				FromFile: "remote:" + path,
+				ToFile:   "local:" + path,
+				Context:  3,
+			})
+		}
+		diffs = append(diffs, d)
+	}
+
+	if filesOutput == "json" {
+		if diffs == nil {
+			diffs = []fileDiff{}
+		}
+		outputJSON(diffs, false)
+		return
+	}
+	for _, d := range diffs {
+		fmt.Print(d.Diff)
+	}
+}
+
+// diffLines splits s into newline-terminated lines for difflib, terminating
+// a final partial line too so the diff output stays line-aligned.
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
+
+// localFilePaths returns the remote paths to compare or push: the cleaned
+// args, or else every file under dir plus every file in the manifest.
+func localFilePaths(dir string, manifest *filesync.Manifest, args []string) ([]string, error) {
+	if len(args) > 0 {
+		paths := make([]string, 0, len(args))
+		for _, arg := range args {
+			path, err := filesync.CleanRemotePath(arg)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, path)
+		}
+		return paths, nil
+	}
+
+	paths, err := filesync.Walk(dir)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		seen[p] = true
+	}
+	for _, p := range sortedManifestPaths(manifest) {
+		if !seen[p] {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+func sortedManifestPaths(manifest *filesync.Manifest) []string {
+	paths := make([]string, 0, len(manifest.Files))
+	for p := range manifest.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// listRemoteFiles returns the remote paths under any of prefixes, or every
+// path when none are given.
+func listRemoteFiles(ctx context.Context, c client.ClientInterface, prefixes []string) ([]string, error) {
+	result, err := c.ListFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(prefixes) == 0 {
+		return result.Paths, nil
+	}
+
+	paths := []string{}
+	for _, p := range result.Paths {
+		for _, prefix := range prefixes {
+			// A prefix matches whole path segments, so /scalyr does not
+			// match /scalyr-old
+			prefix = "/" + strings.Trim(prefix, "/")
+			if p == prefix || strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/") {
+				paths = append(paths, p)
+				break
+			}
+		}
+	}
+	return paths, nil
+}
+
+func runFilesPull(cmd *cobra.Command, args []string) {
+	manifest, err := filesync.LoadManifest(filesDir)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	ctx, cancel := filesContext(cmd, true)
+	defer cancel()
+
+	c := getConfig().ClientFor(config.TokenConfigRead)
+	paths, err := listRemoteFiles(ctx, c, args)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	// Fetch everything first so a conflict leaves the directory untouched
+	files := make([]*client.ConfigFile, 0, len(paths))
+	var conflicts []string
+	for _, path := range paths {
+		if _, err := filesync.CleanRemotePath(path); err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+		file, err := c.GetFile(ctx, path)
+		if client.IsNoSuchFile(err) {
+			continue
+		}
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+		files = append(files, file)
+
+		local, ok, err := filesync.ReadLocal(filesDir, path)
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+		if ok && string(local) != file.Content && filesync.Hash(local) != manifest.Files[path].SHA256 {
+			conflicts = append(conflicts, path)
+		}
+	}
+
+	if len(conflicts) > 0 && !filesForce {
+		errors.HandleErrorAndExit(errors.NewValidationError(
+			fmt.Sprintf("local changes would be overwritten: %s", strings.Join(conflicts, ", ")),
+			fmt.Errorf("push or discard them first, or pull with --force"),
+		))
+	}
+
+	results := make([]fileResult, 0, len(files))
+	for _, file := range files {
+		content := []byte(file.Content)
+		action := "pulled"
+		if local, ok, _ := filesync.ReadLocal(filesDir, file.Path); ok && string(local) == file.Content {
+			action = "unchanged"
+		}
+		if action == "pulled" {
+			if err := filesync.WriteLocal(filesDir, file.Path, content); err != nil {
+				errors.HandleErrorAndExit(err)
+			}
+		}
+		manifest.Files[file.Path] = filesync.Entry{Version: file.Version, SHA256: filesync.Hash(content)}
+		results = append(results, fileResult{Path: file.Path, Action: action, Version: file.Version})
+		if filesync.Hidden(file.Path) {
+			logging.Warnf("%s has a hidden name: push and diff find it only through %s, so keep that file", file.Path, filesync.ManifestName)
+		}
+	}
+
+	if err := manifest.Save(filesDir); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	outputFileResults(results)
+}
+
+func runFilesPush(cmd *cobra.Command, args []string) {
+	manifest, err := filesync.LoadManifest(filesDir)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	paths, err := localFilePaths(filesDir, manifest, args)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	ctx, cancel := filesContext(cmd, true)
+	defer cancel()
+
+	c := getConfig().ClientFor(config.TokenConfigWrite)
+
+	// Files never pulled are created only when asked for, by --create or
+	// by naming them
+	create := filesCreate || len(args) > 0
+	var remotePaths map[string]bool
+
+	results := []fileResult{}
+	var untracked []fileResult
+	for _, path := range paths {
+		content, ok, err := filesync.ReadLocal(filesDir, path)
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+		if !ok {
+			continue
+		}
+
+		entry, tracked := manifest.Files[path]
+		hash := filesync.Hash(content)
+		if tracked && entry.SHA256 == hash {
+			continue
+		}
+
+		action := "updated"
+		if !tracked {
+			if remotePaths == nil {
+				remotePaths, err = listRemotePathSet(ctx, c)
+				if err != nil {
+					pushFailed(manifest, results, err)
+					return
+				}
+			}
+			switch {
+			case remotePaths[path] && !filesForce:
+				pushFailed(manifest, results, errors.NewValidationError(
+					fmt.Sprintf("%s already exists remotely but was never pulled", path),
+					fmt.Errorf("pull it first to review the remote version, or push with --force"),
+				))
+				return
+			case remotePaths[path]:
+			case !create:
+				untracked = append(untracked, fileResult{Path: path, Action: "untracked"})
+				continue
+			default:
+				action = "created"
+			}
+		}
+
+		if filesDryRun {
+			results = append(results, fileResult{Path: path, Action: "would be " + action, Version: entry.Version})
+			continue
+		}
+
+		err = c.PutFile(ctx, client.PutFileParams{Path: path, Content: string(content), ExpectedVersion: entry.Version})
+		if client.IsVersionMismatch(err) {
+			err = changedRemotely(path, entry.Version)
+		}
+		if err != nil {
+			pushFailed(manifest, results, err)
+			return
+		}
+
+		// Record the version the write produced for the next push, unless
+		// another write has already replaced it
+		file, err := c.GetFile(ctx, path)
+		if err != nil {
+			pushFailed(manifest, results, err)
+			return
+		}
+		if file.Content != string(content) {
+			pushFailed(manifest, results, changedRemotely(path, entry.Version))
+			return
+		}
+		manifest.Files[path] = filesync.Entry{Version: file.Version, SHA256: hash}
+		results = append(results, fileResult{Path: path, Action: action, Version: file.Version})
+	}
+
+	if !filesDryRun {
+		if err := manifest.Save(filesDir); err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+	}
+	outputFileResults(append(results, untracked...))
+}
+
+// listRemotePathSet returns the set of every remote path.
+func listRemotePathSet(ctx context.Context, c client.ClientInterface) (map[string]bool, error) {
+	paths, err := listRemoteFiles(ctx, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+	return set, nil
+}
+
+// changedRemotely is the error for a push that raced with a remote edit of
+// path since version.
+func changedRemotely(path string, version int64) error {
+	return errors.NewValidationError(
+		fmt.Sprintf("%s was changed remotely since version %d", path, version),
+		fmt.Errorf("run files diff and files pull to merge the remote changes, then push again"),
+	)
+}
+
+// pushFailed saves the versions of the files already pushed, reports them,
+// and exits with err.
+func pushFailed(manifest *filesync.Manifest, results []fileResult, err error) {
+	if !filesDryRun {
+		if saveErr := manifest.Save(filesDir); saveErr != nil {
+			errors.HandleErrorAndExit(saveErr)
+		}
+	}
+	if len(results) > 0 && filesOutput != "json" {
+		outputFileResults(results)
+	}
+	errors.HandleErrorAndExit(err)
+}
+
+func outputFileResults(results []fileResult) {
+	if filesOutput == "json" {
+		outputJSON(results, false)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+	for _, r := range results {
+		if r.Version > 0 {
+			fmt.Fprintf(w, "%s\t%s\tversion %d\n", r.Action, r.Path, r.Version)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\n", r.Action, r.Path)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/config"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/spf13/cobra"
+)
+
+var ingestCmd = &cobra.Command{
+	Use:   "ingest [file...]",
+	Short: "Send log lines to Scalyr",
+	Long: `Ingest reads log lines from the given files, or from stdin when no file (or "-") is
+given, and sends one event per non-empty line through the addEvents API. Lines
+are batched into requests below the 6MB API limit, all sharing one session.
+Pending events are also sent every --flush-interval, so a slow stream such as
+"tail -f" reaches Scalyr promptly, and once more when ingest is interrupted.
+
+With --upload the text is sent through the uploadLogs API instead, which is
+simpler but intended for low volumes. An interrupt then sends the lines already
+read and stops before the next file.
+
+Ingest needs a write token: set write_token in the config file, export
+scalyr_writelog_token, or pass --token.`,
+	Annotations: map[string]string{tokenAnnotation: string(config.TokenWrite)},
+	Run:         runIngest,
+}
+
+var (
+	ingestServerHost string
+	ingestParser     string
+	ingestLogFile    string
+	ingestAttrs      []string
+	ingestSeverity   int
+	ingestUpload     bool
+	ingestOutput     string
+
+	ingestFlushInterval time.Duration
+)
+
+func init() {
+	ingestCmd.Flags().StringVar(&ingestServerHost, "server-host", "", "serverHost attribute for the events (default: this machine's hostname)")
+	ingestCmd.Flags().StringVar(&ingestParser, "parser", "", "Scalyr parser applied to each line")
+	ingestCmd.Flags().StringVar(&ingestLogFile, "logfile", "", "logfile attribute for the events (default: the file path, or 'stdin')")
+	ingestCmd.Flags().StringArrayVar(&ingestAttrs, "attr", nil, "Server attribute key=value added to every event (repeatable)")
+	ingestCmd.Flags().IntVar(&ingestSeverity, "severity", 3, "Severity of the events, 0 (finest) to 6 (fatal)")
+	ingestCmd.Flags().BoolVar(&ingestUpload, "upload", false, "Send raw text through the uploadLogs API instead of addEvents")
+	ingestCmd.Flags().StringVar(&ingestOutput, "output", "text", "Summary format: text|json")
+	ingestCmd.Flags().DurationVar(&ingestFlushInterval, "flush-interval", 5*time.Second, "Send pending events at least this often (0 sends only full batches and at the end)")
+}
+
+// ingestInput is one source of log lines.
+type ingestInput struct {
+	name   string
+	reader io.Reader
+}
+
+type ingestSummary struct {
+	Session  string `json:"session,omitempty"`
+	Events   int    `json:"events"`
+	Requests int    `json:"requests"`
+	Bytes    int64  `json:"bytes"`
+}
+
+func runIngest(cmd *cobra.Command, args []string) {
+	if !cmd.Flags().Changed("output") && !IsTTY() {
+		ingestOutput = "json"
+		errors.OutputJSON = true
+	}
+	validateTextOrJSON(ingestOutput)
+
+	if ingestSeverity < 0 || ingestSeverity > 6 {
+		errors.HandleErrorAndExit(errors.NewValidationError("severity must be between 0 and 6", nil))
+	}
+	if ingestFlushInterval < 0 {
+		errors.HandleErrorAndExit(errors.NewValidationError("flush interval must not be negative", nil))
+	}
+
+	attrs, err := parseAttrs(ingestAttrs)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	serverHost := ingestServerHost
+	if serverHost == "" {
+		serverHost, _ = os.Hostname()
+	}
+
+	inputs, closeInputs, err := openIngestInputs(args)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	defer closeInputs()
+
+	c := getConfig().ClientFor(config.TokenWrite)
+
+	// Ingesting a stream may run indefinitely, so only cancellation applies
+	ctx, cancel := commandContext(true)
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	var summary ingestSummary
+	if ingestUpload {
+		summary, err = uploadInputs(ctx, c, inputs, serverHost, attrs)
+	} else {
+		summary, err = addEventInputs(ctx, c, inputs, serverHost, attrs)
+	}
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	if ingestOutput == "json" {
+		outputJSON(summary, false)
+		return
+	}
+	fmt.Printf("Sent %d events in %d requests (%d bytes)\n", summary.Events, summary.Requests, summary.Bytes)
+}
+
+// parseAttrs parses --attr key=value pairs.
+func parseAttrs(pairs []string) (map[string]string, error) {
+	attrs := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, errors.NewValidationError(
+				fmt.Sprintf("invalid --attr: %s", pair),
+				fmt.Errorf("use key=value"),
+			)
+		}
+		attrs[key] = value
+	}
+	return attrs, nil
+}
+
+// openIngestInputs opens the files named in args, using stdin for "-" or
+// when no file is given. The returned func closes the opened files.
+func openIngestInputs(args []string) ([]ingestInput, func(), error) {
+	if len(args) == 0 {
+		args = []string{"-"}
+	}
+
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	inputs := make([]ingestInput, 0, len(args))
+	for _, arg := range args {
+		if arg == "-" {
+			inputs = append(inputs, ingestInput{name: "stdin", reader: os.Stdin})
+			continue
+		}
+		f, err := os.Open(arg)
+		if err != nil {
+			closeAll()
+			return nil, nil, errors.NewValidationError("cannot open "+arg, err)
+		}
+		files = append(files, f)
+		inputs = append(inputs, ingestInput{name: arg, reader: f})
+	}
+	return inputs, closeAll, nil
+}
+
+// newLineScanner returns a scanner that accepts lines up to the API's
+// request size limit.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), client.MaxRequestBytes)
+	return scanner
+}
+
+// ingestLine is a non-empty line read from input number input, or the error
+// that ended reading.
+type ingestLine struct {
+	input int
+	text  string
+	err   error
+}
+
+// readIngestLines reads the inputs in order, sending their non-empty lines
+// until they are exhausted or done is closed.
+func readIngestLines(inputs []ingestInput, done <-chan struct{}) <-chan ingestLine {
+	lines := make(chan ingestLine)
+	send := func(line ingestLine) bool {
+		select {
+		case lines <- line:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	go func() {
+		defer close(lines)
+		for i, input := range inputs {
+			scanner := newLineScanner(input.reader)
+			for scanner.Scan() {
+				if line := scanner.Text(); line != "" && !send(ingestLine{input: i, text: line}) {
+					return
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				send(ingestLine{input: i, err: errors.NewValidationError("failed to read "+input.name, err)})
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+// addEventInputs sends every non-empty line as an event of one addEvents
+// session, with a thread per input. Pending events are flushed every
+// ingestFlushInterval, and when ctx is cancelled reading stops and they are
+// flushed under a fresh timeout so an interrupt does not lose them.
+func addEventInputs(ctx context.Context, c client.ClientInterface, inputs []ingestInput, serverHost string, attrs map[string]string) (ingestSummary, error) {
+	info := map[string]interface{}{"serverHost": serverHost}
+	for k, v := range attrs {
+		info[k] = v
+	}
+
+	threads := make([]client.Thread, len(inputs))
+	for i, input := range inputs {
+		threads[i] = client.Thread{ID: strconv.Itoa(i + 1), Name: ingestLogFileName(input)}
+	}
+
+	var tick <-chan time.Time
+	if ingestFlushInterval > 0 {
+		ticker := time.NewTicker(ingestFlushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	lines := readIngestLines(inputs, done)
+
+	session := c.NewEventSession(info, threads)
+	var unqueued *client.Event
+read:
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				break read
+			}
+			if line.err != nil {
+				return ingestSummary{}, line.err
+			}
+			eventAttrs := map[string]interface{}{
+				"message": line.text,
+				"logfile": threads[line.input].Name,
+			}
+			if ingestParser != "" {
+				eventAttrs["parser"] = ingestParser
+			}
+			event := client.Event{
+				Thread:   threads[line.input].ID,
+				Severity: ingestSeverity,
+				Attrs:    eventAttrs,
+			}
+			if err := session.Add(ctx, event); err != nil {
+				if ctx.Err() == nil {
+					return ingestSummary{}, err
+				}
+				// The interrupt cancelled the flush making room for the
+				// event, so it was not queued and is added again below
+				unqueued = &event
+			}
+		case <-tick:
+			if err := session.Flush(ctx); err != nil && ctx.Err() == nil {
+				return ingestSummary{}, err
+			}
+		case <-ctx.Done():
+			break read
+		}
+		if ctx.Err() != nil {
+			break read
+		}
+	}
+
+	// The command context may already be cancelled by an interrupt
+	flushCtx, cancel := context.WithTimeout(context.Background(), getTimeout())
+	defer cancel()
+	if unqueued != nil {
+		if err := session.Add(flushCtx, *unqueued); err != nil {
+			return ingestSummary{}, err
+		}
+	}
+	if err := session.Flush(flushCtx); err != nil {
+		return ingestSummary{}, err
+	}
+
+	stats := session.Stats()
+	return ingestSummary{Session: session.ID(), Events: stats.Events, Requests: stats.Requests, Bytes: stats.Bytes}, nil
+}
+
+// uploadInputs sends each input through uploadLogs, splitting it on line
+// boundaries into bodies below the request size limit. When ctx is cancelled
+// reading stops, so no further input is started, and the lines already read
+// are uploaded under a fresh timeout as addEventInputs flushes its events.
+func uploadInputs(ctx context.Context, c client.ClientInterface, inputs []ingestInput, serverHost string, attrs map[string]string) (ingestSummary, error) {
+	var (
+		summary ingestSummary
+		body    bytes.Buffer
+		lines   int
+		current int
+	)
+	// upload sends the lines of input current read so far
+	upload := func(ctx context.Context) error {
+		if body.Len() == 0 {
+			return nil
+		}
+		params := client.UploadLogsParams{
+			ServerHost: serverHost,
+			LogFile:    ingestLogFileName(inputs[current]),
+			Parser:     ingestParser,
+			Attributes: attrs,
+		}
+		if err := c.UploadLogs(ctx, params, body.Bytes()); err != nil {
+			return err
+		}
+		summary.Events += lines
+		summary.Requests++
+		summary.Bytes += int64(body.Len())
+		body.Reset()
+		lines = 0
+		return nil
+	}
+	// add appends line to the body, first uploading the lines before it when
+	// it starts another input or would take the body over the limit
+	add := func(ctx context.Context, line ingestLine) error {
+		if line.input != current || body.Len()+len(line.text)+1 > client.MaxRequestBytes {
+			if err := upload(ctx); err != nil {
+				return err
+			}
+			current = line.input
+		}
+		body.WriteString(line.text)
+		body.WriteByte('\n')
+		lines++
+		return nil
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	input := readIngestLines(inputs, done)
+
+	var unqueued *ingestLine
+read:
+	for {
+		select {
+		case line, ok := <-input:
+			if !ok {
+				break read
+			}
+			if line.err != nil {
+				return ingestSummary{}, line.err
+			}
+			if err := add(ctx, line); err != nil {
+				if ctx.Err() == nil {
+					return ingestSummary{}, err
+				}
+				// The interrupt cancelled the upload making room for the
+				// line, so it was not added and is added again below
+				unqueued = &line
+			}
+		case <-ctx.Done():
+			break read
+		}
+		if ctx.Err() != nil {
+			break read
+		}
+	}
+
+	// The command context may already be cancelled by an interrupt
+	flushCtx, cancel := context.WithTimeout(context.Background(), getTimeout())
+	defer cancel()
+	if unqueued != nil {
+		if err := add(flushCtx, *unqueued); err != nil {
+			return ingestSummary{}, err
+		}
+	}
+	if err := upload(flushCtx); err != nil {
+		return ingestSummary{}, err
+	}
+	return summary, nil
+}
+
+func ingestLogFileName(input ingestInput) string {
+	if ingestLogFile != "" {
+		return ingestLogFile
+	}
+	return input.name
+}
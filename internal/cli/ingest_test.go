@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addEventsServer records the messages of every addEvents request it
+// receives, one slice per request.
+func addEventsServer(t *testing.T) (*httptest.Server, func() [][]string) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests [][]string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Events []struct {
+				Attrs map[string]any `json:"attrs"`
+			} `json:"events"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		var messages []string
+		for _, e := range body.Events {
+			messages = append(messages, e.Attrs["message"].(string))
+		}
+		mu.Lock()
+		requests = append(requests, messages)
+		mu.Unlock()
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]string(nil), requests...)
+	}
+}
+
+func TestAddEventInputs_FlushInterval(t *testing.T) {
+	server, requests := addEventsServer(t)
+	original := ingestFlushInterval
+	ingestFlushInterval = 20 * time.Millisecond
+	defer func() { ingestFlushInterval = original }()
+
+	r, w := io.Pipe()
+	done := make(chan ingestSummary)
+	go func() {
+		summary, err := addEventInputs(context.Background(), client.New("test-token", server.URL, false),
+			[]ingestInput{{name: "stdin", reader: r}}, "web-1", nil)
+		assert.NoError(t, err)
+		done <- summary
+	}()
+
+	io.WriteString(w, "first\n")
+	assert.Eventually(t, func() bool { return len(requests()) == 1 }, time.Second, 5*time.Millisecond,
+		"a pending event is sent while the input is still open")
+
+	io.WriteString(w, "second\n")
+	w.Close()
+	summary := <-done
+	assert.Equal(t, 2, summary.Events)
+	assert.Equal(t, [][]string{{"first"}, {"second"}}, requests())
+}
+
+func TestAddEventInputs_InterruptSendsPendingEvents(t *testing.T) {
+	server, requests := addEventsServer(t)
+	original := ingestFlushInterval
+	ingestFlushInterval = 0
+	defer func() { ingestFlushInterval = original }()
+
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan ingestSummary)
+	go func() {
+		summary, err := addEventInputs(ctx, client.New("test-token", server.URL, false),
+			[]ingestInput{{name: "stdin", reader: r}}, "web-1", nil)
+		assert.NoError(t, err)
+		done <- summary
+	}()
+
+	// A pipe write returns once it has been read, and the scanner reads on
+	// only after the previous line was taken, so once the unterminated
+	// "four" is written every complete line has been queued
+	io.WriteString(w, "one\n")
+	io.WriteString(w, "two\n")
+	io.WriteString(w, "three\n")
+	io.WriteString(w, "four")
+	assert.Empty(t, requests(), "nothing is sent before the batch fills or the interval passes")
+
+	cancel()
+	summary := <-done
+	assert.Equal(t, 3, summary.Events, "the input is still open, but the interrupt flushes the batch")
+	assert.Equal(t, [][]string{{"one", "two", "three"}}, requests())
+}
+
+func TestAddEventInputs_InterruptDuringFullBatchFlush(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches []int
+		calls   int
+	)
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body struct {
+			Events []json.RawMessage `json:"events"`
+		}
+		require.NoError(t, json.Unmarshal(raw, &body))
+
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			// Hold the flush of the full batch until the interrupt cancels it
+			close(blocked)
+			<-r.Context().Done()
+			return
+		}
+
+		mu.Lock()
+		batches = append(batches, len(body.Events))
+		mu.Unlock()
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	original := ingestFlushInterval
+	ingestFlushInterval = 0
+	defer func() { ingestFlushInterval = original }()
+
+	r, w := io.Pipe()
+	defer w.Close()
+	go func() {
+		// Two lines fill a batch, so the third has to flush them first
+		line := strings.Repeat("x", client.MaxRequestBytes/3) + "\n"
+		for range 3 {
+			io.WriteString(w, line)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan ingestSummary)
+	go func() {
+		summary, err := addEventInputs(ctx, client.New("test-token", server.URL, false),
+			[]ingestInput{{name: "stdin", reader: r}}, "web-1", nil)
+		assert.NoError(t, err)
+		done <- summary
+	}()
+
+	<-blocked
+	cancel()
+	summary := <-done
+	assert.Equal(t, 3, summary.Events, "the line whose flush was interrupted is still sent")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{2, 1}, batches)
+}
+
+func TestUploadInputs_InterruptStopsBeforeNextInput(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		uploads []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		uploads = append(uploads, r.Header.Get("logfile")+": "+string(body))
+		mu.Unlock()
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan ingestSummary)
+	go func() {
+		summary, err := uploadInputs(ctx, client.New("test-token", server.URL, false), []ingestInput{
+			{name: "stdin", reader: r},
+			{name: "next.log", reader: strings.NewReader("never read\n")},
+		}, "web-1", nil)
+		assert.NoError(t, err)
+		done <- summary
+	}()
+
+	// As in the addEvents test, once the unterminated "three" is written
+	// every complete line has been read
+	io.WriteString(w, "one\n")
+	io.WriteString(w, "two\n")
+	io.WriteString(w, "three")
+
+	cancel()
+	summary := <-done
+	assert.Equal(t, 2, summary.Events)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"stdin: one\ntwo\n"}, uploads,
+		"the interrupt uploads the lines read and starts no further input")
+}
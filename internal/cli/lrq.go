@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// lrqProgress returns the progress callback for --lrq queries and a function
+// that clears the progress line once the query is over. Progress is only drawn
+// when stderr is a terminal, so piped and agent output stays clean.
+func lrqProgress() (func(completed, total int), func()) {
+	if !isStderrTTY() {
+		return nil, func() {}
+	}
+	return progressPrinter(os.Stderr)
+}
+
+func progressPrinter(w io.Writer) (func(completed, total int), func()) {
+	drawn := false
+	progress := func(completed, total int) {
+		percent := 0
+		if total > 0 {
+			percent = completed * 100 / total
+		}
+		fmt.Fprintf(w, "\rQuery progress: %d/%d steps (%d%%)", completed, total, percent)
+		drawn = true
+	}
+	done := func() {
+		if drawn {
+			fmt.Fprint(w, "\r\033[K")
+		}
+	}
+	return progress, done
+}
@@ -5,9 +5,12 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
+	"github.com/andreagrandi/logbasset/internal/timerange"
 	"github.com/andreagrandi/logbasset/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +30,8 @@ var (
 	numericQueryEndTime   string
 	numericQueryBuckets   int
 	numericQueryOutput    string
+	numericQueryOut       string
+	numericQueryLong      bool
 )
 
 func init() {
@@ -34,7 +39,9 @@ func init() {
 	numericQueryCmd.Flags().StringVar(&numericQueryStartTime, "start", "", "Start time for the query (required)")
 	numericQueryCmd.Flags().StringVar(&numericQueryEndTime, "end", "", "End time for the query")
 	numericQueryCmd.Flags().IntVar(&numericQueryBuckets, "buckets", 1, "Number of time buckets (1-5000)")
-	numericQueryCmd.Flags().StringVar(&numericQueryOutput, "output", "csv", "Output format: csv|json|json-pretty")
+	numericQueryCmd.Flags().StringVar(&numericQueryOutput, "output", "csv", outputFlagUsage())
+	numericQueryCmd.Flags().StringVar(&numericQueryOut, "out", "", outFlagUsage)
+	numericQueryCmd.Flags().BoolVar(&numericQueryLong, "long", false, longFlagUsage)
 	numericQueryCmd.MarkFlagRequired("start")
 }
 
@@ -64,6 +71,7 @@ func runNumericQuery(cmd *cobra.Command, args []string) {
 	if err := validation.ValidateRequiredField("start", numericQueryStartTime); err != nil {
 		errors.HandleErrorAndExit(err)
 	}
+	window := resolveBucketRange(numericQueryStartTime, numericQueryEndTime)
 
 	c := getConfig().GetClient()
 
@@ -99,12 +107,83 @@ func runNumericQuery(cmd *cobra.Command, args []string) {
 		errors.OutputJSON = true
 	}
 
-	switch numericQueryOutput {
-	case "json":
-		outputJSON(result, false)
-	case "json-pretty":
-		outputJSON(result, true)
-	default:
-		outputNumericCSV(result.Values)
+	f := newFormatter(numericQueryOutput, numericQueryOut, output.Options{})
+	exitOnOutputError(writeBuckets(f, result, window, result.Values, numericQueryLong))
+}
+
+// longFlagUsage is the --long help of numeric-query and timeseries-query.
+const longFlagUsage = "Write a bucket_start,bucket_end,value row per bucket instead of one row with a column per bucket, and JSON as an array of those rows instead of the API response"
+
+// bucketColumns are the columns numeric and timeseries values are written
+// with by --long, one row per bucket.
+var bucketColumns = []string{"bucket_start", "bucket_end", "value"}
+
+// resolveBucketRange resolves the time range of a numeric or timeseries
+// query, which the bounds of its buckets are computed from.
+func resolveBucketRange(start, end string) timerange.Window {
+	window, err := timerange.ResolveRange(start, end, time.Now())
+	if err != nil {
+		errors.HandleErrorAndExit(errors.NewValidationError("invalid time range", err))
+	}
+	return window
+}
+
+// bucketSeries is the values one numeric or timeseries query returned, and
+// the time range its buckets divide. name labels it when a timeseries-query
+// runs several queries.
+type bucketSeries struct {
+	name   string
+	window timerange.Window
+	values []float64
+}
+
+// writeBuckets writes the values of a numeric or timeseries query as the
+// single row of positional values the API returns, and document as JSON.
+// With long it writes a bucket_start,bucket_end,value row per bucket
+// instead, the bounds rendered with --time-format.
+func writeBuckets(f output.Formatter, document any, window timerange.Window, values []float64, long bool) error {
+	if !long {
+		return output.WriteRows(f, output.Header{Document: document}, [][]any{numericRow(values)})
+	}
+	return output.WriteRows(f, output.Header{Columns: bucketColumns}, bucketRows(bucketSeries{window: window, values: values}))
+}
+
+// writeNamedBuckets writes the values of several named queries: a row per
+// query of its name followed by its values, and document as JSON, or with
+// long a query,bucket_start,bucket_end,value row per bucket.
+func writeNamedBuckets(f output.Formatter, document any, series []bucketSeries, long bool) error {
+	var rows [][]any
+	if !long {
+		for _, s := range series {
+			rows = append(rows, append([]any{s.name}, numericRow(s.values)...))
+		}
+		return output.WriteRows(f, output.Header{Document: document}, rows)
+	}
+
+	for _, s := range series {
+		for _, row := range bucketRows(s) {
+			rows = append(rows, append([]any{s.name}, row...))
+		}
+	}
+	return output.WriteRows(f, output.Header{Columns: append([]string{"query"}, bucketColumns...)}, rows)
+}
+
+// bucketRows returns a bucket_start,bucket_end,value row per value of s.
+func bucketRows(s bucketSeries) [][]any {
+	tf := timeFormat()
+	rows := make([][]any, len(s.values))
+	for i, bucket := range timerange.Buckets(s.window, len(s.values)) {
+		rows[i] = []any{tf.Time(bucket.Start), tf.Time(bucket.End), s.values[i]}
+	}
+	return rows
+}
+
+// numericRow is the single row of positional bucket values that numeric and
+// timeseries queries are written as.
+func numericRow(values []float64) []any {
+	row := make([]any, len(values))
+	for i, v := range values {
+		row[i] = v
 	}
+	return row
 }
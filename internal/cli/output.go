@@ -1,71 +1,149 @@
 package cli
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
-	"time"
+	"strings"
 
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/logging"
+	"github.com/andreagrandi/logbasset/internal/output"
 )
 
-// formatCompactTimestamp converts a Scalyr nanosecond timestamp string into HH:MM:SS.
-// Falls back to the original value if parsing fails.
-func formatCompactTimestamp(ts string) string {
-	if ts == "" {
-		return ""
-	}
-	if nanos, err := strconv.ParseInt(ts, 10, 64); err == nil {
-		return time.Unix(0, nanos).UTC().Format("15:04:05")
+func outputJSON(data any, pretty bool) {
+	var out []byte
+	var err error
+
+	if pretty {
+		out, err = json.MarshalIndent(data, "", "  ")
+	} else {
+		out, err = json.Marshal(data)
 	}
-	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
-		return t.UTC().Format("15:04:05")
+
+	if err != nil {
+		errors.HandleErrorAndExit(errors.NewParseError("failed to marshal JSON", err))
 	}
-	return ts
+
+	fmt.Println(string(out))
 }
 
-// severityChar maps a Scalyr severity level to a single character for compact output.
-func severityChar(sev int) string {
-	switch {
-	case sev <= 2:
-		return "D"
-	case sev == 3:
-		return "I"
-	case sev == 4:
-		return "W"
-	case sev == 5:
-		return "E"
-	default:
-		return "F"
-	}
+// outputFlagUsage is the --output help of the commands whose results go
+// through the output package.
+func outputFlagUsage() string {
+	return "Output format: " + output.Usage()
 }
 
-func outputJSON(data any, pretty bool) {
-	var output []byte
-	var err error
+// outFlagUsage is the --out help of the commands whose results go through
+// the output package.
+const outFlagUsage = "Write the results to FILE instead of stdout (needed for parquet and arrow on a terminal)"
 
-	if pretty {
-		output, err = json.MarshalIndent(data, "", "  ")
+// The --flatten and --attr-order help of the commands that print events.
+const (
+	flattenFlagUsage   = "Write nested attributes as dotted keys (http.status=500) in multiline, singleline and logfmt output"
+	attrOrderFlagUsage = "Comma-separated attributes to write first in multiline, singleline and logfmt output; the rest follow sorted"
+)
+
+// newFormatter returns the formatter for an --output value, writing to the
+// --out file when out is set and to stdout, with the terminalOptions,
+// otherwise.
+func newFormatter(format, out string, opts output.Options) output.Formatter {
+	opts.Time = timeFormat()
+	var file *os.File
+	w := io.Writer(os.Stdout)
+	if out == "" {
+		checkBinaryOutput(format)
+		opts = terminalOptions(opts)
 	} else {
-		output, err = json.Marshal(data)
+		file = createOut(out)
+		w = file
 	}
 
+	f, err := output.New(format, w, opts)
 	if err != nil {
-		errors.HandleErrorAndExit(errors.NewParseError("failed to marshal JSON", err))
+		errors.HandleErrorAndExit(err)
+	}
+	if file != nil {
+		return &fileFormatter{Formatter: f, file: file}
 	}
+	return f
+}
 
-	fmt.Println(string(output))
+// checkBinaryOutput refuses to write a binary format such as parquet to a
+// terminal.
+func checkBinaryOutput(format string) {
+	if f, ok := output.Lookup(format); ok && f.Binary && IsTTY() {
+		errors.HandleErrorAndExit(errors.NewValidationError(
+			format+" output is binary and cannot be written to a terminal",
+			fmt.Errorf("use --out FILE or redirect stdout"),
+		))
+	}
 }
 
-func outputNumericCSV(values []float64) {
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
+// createOut creates (or truncates) the --out file.
+func createOut(path string) *os.File {
+	file, err := os.Create(path)
+	if err != nil {
+		errors.HandleErrorAndExit(errors.NewValidationError("cannot create "+path, err))
+	}
+	return file
+}
+
+// fileFormatter closes the --out file once the result is complete.
+type fileFormatter struct {
+	output.Formatter
+	file *os.File
+}
 
-	record := make([]string, len(values))
-	for i, val := range values {
-		record[i] = strconv.FormatFloat(val, 'f', -1, 64)
+func (f *fileFormatter) End() error {
+	if err := f.Formatter.End(); err != nil {
+		_ = f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
+// exitOnOutputError reports a failure to render or write results.
+func exitOnOutputError(err error) {
+	if err == nil {
+		return
+	}
+	if _, ok := err.(*errors.LogBassetError); !ok {
+		err = errors.NewParseError("failed to write output", err)
+	}
+	errors.HandleErrorAndExit(err)
+}
+
+// documentFormat reports whether format prints the complete API response,
+// which then has to be read in full before anything is written.
+func documentFormat(format string) bool {
+	f, ok := output.Lookup(format)
+	return ok && f.Document
+}
+
+// fieldsOption returns the --fields projection for format, warning when the
+// format does not support it.
+func fieldsOption(format, fields string) []string {
+	if fields == "" {
+		return nil
+	}
+	if f, ok := output.Lookup(format); !ok || !f.Fields {
+		logging.Warn("--fields is only supported with JSON output formats, ignoring")
+		return nil
+	}
+	return splitFields(fields)
+}
+
+// splitFields turns a comma-separated --fields or --columns value into
+// trimmed names, or nil when it is empty.
+func splitFields(fields string) []string {
+	if fields == "" {
+		return nil
+	}
+	fieldList := strings.Split(fields, ",")
+	for i, f := range fieldList {
+		fieldList[i] = strings.TrimSpace(f)
 	}
-	writer.Write(record)
+	return fieldList
 }
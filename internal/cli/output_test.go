@@ -56,55 +56,15 @@ func TestOutputJSON_Pretty(t *testing.T) {
 }
 
 func TestOutputNumericCSV(t *testing.T) {
-	out := captureStdout(t, func() {
-		outputNumericCSV([]float64{1.5, 2, 3.14})
-	})
+	run := runCLI(t, `{"status":"success","values":[1.5,2,3.14]}`,
+		"numeric-query", "--start", "24h", "--buckets", "3", "--output", "csv")
 
-	assert.Equal(t, "1.5,2,3.14\n", out)
+	assert.Equal(t, "1.5,2,3.14\n", run.stdout)
 }
 
 func TestOutputNumericCSV_Empty(t *testing.T) {
-	out := captureStdout(t, func() {
-		outputNumericCSV([]float64{})
-	})
-
-	assert.Equal(t, "\n", out)
-}
-
-func TestFormatCompactTimestamp(t *testing.T) {
-	tests := []struct {
-		name string
-		in   string
-		want string
-	}{
-		{name: "empty input", in: "", want: ""},
-		{name: "nanoseconds since epoch", in: "1700000000000000000", want: "22:13:20"},
-		{name: "rfc3339 utc", in: "2024-05-19T07:08:09Z", want: "07:08:09"},
-		{name: "rfc3339 with offset is normalised to utc", in: "2024-05-19T09:08:09+02:00", want: "07:08:09"},
-		{name: "unparseable falls back to input", in: "not-a-timestamp", want: "not-a-timestamp"},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, formatCompactTimestamp(tt.in))
-		})
-	}
-}
+	run := runCLI(t, `{"status":"success","values":[]}`,
+		"numeric-query", "--start", "24h", "--output", "csv")
 
-func TestSeverityChar(t *testing.T) {
-	tests := []struct {
-		sev  int
-		want string
-	}{
-		{sev: 0, want: "D"},
-		{sev: 1, want: "D"},
-		{sev: 2, want: "D"},
-		{sev: 3, want: "I"},
-		{sev: 4, want: "W"},
-		{sev: 5, want: "E"},
-		{sev: 6, want: "F"},
-		{sev: 9, want: "F"},
-	}
-	for _, tt := range tests {
-		assert.Equal(t, tt.want, severityChar(tt.sev), "severity %d", tt.sev)
-	}
+	assert.Equal(t, "\n", run.stdout)
 }
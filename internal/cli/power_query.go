@@ -1,15 +1,14 @@
 package cli
 
 import (
-	"context"
-	"encoding/csv"
-	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
+	"github.com/andreagrandi/logbasset/internal/timerange"
 	"github.com/andreagrandi/logbasset/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -27,13 +26,22 @@ var (
 	powerQueryStartTime string
 	powerQueryEndTime   string
 	powerQueryOutput    string
+	powerQuerySplit     string
+	powerQueryParallel  int
+	powerQueryLRQ       bool
+	powerQueryOut       string
 )
 
 func init() {
 	powerQueryCmd.Flags().StringVar(&powerQueryStartTime, "start", "", "Start time for the query (required)")
 	powerQueryCmd.Flags().StringVar(&powerQueryEndTime, "end", "", "End time for the query")
-	powerQueryCmd.Flags().StringVar(&powerQueryOutput, "output", "csv", "Output format: csv|json|json-pretty")
+	powerQueryCmd.Flags().StringVar(&powerQueryOutput, "output", "csv", outputFlagUsage())
+	powerQueryCmd.Flags().StringVar(&powerQuerySplit, "split", "", "Split the time range into windows of this size (e.g. 1h, 1d) and query them in parallel")
+	powerQueryCmd.Flags().IntVar(&powerQueryParallel, "parallel", 4, "Maximum number of --split windows queried at once")
+	powerQueryCmd.Flags().BoolVar(&powerQueryLRQ, "lrq", false, "Run through the long-running query API, showing progress on stderr")
+	powerQueryCmd.Flags().StringVar(&powerQueryOut, "out", "", outFlagUsage)
 	powerQueryCmd.MarkFlagRequired("start")
+	powerQueryCmd.MarkFlagsMutuallyExclusive("lrq", "split")
 }
 
 func runPowerQuery(cmd *cobra.Command, args []string) {
@@ -58,6 +66,11 @@ func runPowerQuery(cmd *cobra.Command, args []string) {
 		errors.HandleErrorAndExit(err)
 	}
 
+	var windows []timerange.Window
+	if powerQuerySplit != "" {
+		windows = resolveShards(powerQueryStartTime, powerQueryEndTime, powerQuerySplit, powerQueryParallel)
+	}
+
 	c := getConfig().GetClient()
 
 	clientParams := client.PowerQueryParams{
@@ -68,7 +81,7 @@ func runPowerQuery(cmd *cobra.Command, args []string) {
 	}
 
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), getTimeout())
+	ctx, cancel := commandContext(windows != nil || powerQueryLRQ)
 	defer cancel()
 
 	// Set up signal handling for graceful cancellation
@@ -80,7 +93,18 @@ func runPowerQuery(cmd *cobra.Command, args []string) {
 		cancel()
 	}()
 
-	result, err := c.PowerQuery(ctx, clientParams)
+	var result *client.PowerQueryResponse
+	var err error
+	switch {
+	case windows != nil:
+		result, err = c.PowerQueryWindows(ctx, clientParams, windows, shardOptions(powerQueryParallel))
+	case powerQueryLRQ:
+		progress, done := lrqProgress()
+		result, err = c.PowerQueryLRQ(ctx, clientParams, progress)
+		done()
+	default:
+		result, err = c.PowerQuery(ctx, clientParams)
+	}
 	if err != nil {
 		errors.HandleErrorAndExit(err)
 	}
@@ -90,35 +114,11 @@ func runPowerQuery(cmd *cobra.Command, args []string) {
 		errors.OutputJSON = true
 	}
 
-	switch powerQueryOutput {
-	case "json":
-		outputJSON(result, false)
-	case "json-pretty":
-		outputJSON(result, true)
-	default:
-		outputPowerQueryCSV(result)
-	}
-}
-
-func outputPowerQueryCSV(result *client.PowerQueryResponse) {
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
-
-	// Extract column names from column objects
-	if len(result.Columns) > 0 {
-		columnNames := make([]string, len(result.Columns))
-		for i, col := range result.Columns {
-			columnNames[i] = col.Name
-		}
-		writer.Write(columnNames)
+	columns := make([]string, len(result.Columns))
+	for i, col := range result.Columns {
+		columns[i] = col.Name
 	}
 
-	// Output values (array of arrays)
-	for _, row := range result.Values {
-		record := make([]string, len(row))
-		for i, val := range row {
-			record[i] = fmt.Sprintf("%v", val)
-		}
-		writer.Write(record)
-	}
+	f := newFormatter(powerQueryOutput, powerQueryOut, output.Options{})
+	exitOnOutputError(output.WriteRows(f, output.Header{Columns: columns, Document: result}, result.Values))
 }
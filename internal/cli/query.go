@@ -1,17 +1,14 @@
 package cli
 
 import (
-	"context"
-	"encoding/csv"
-	"fmt"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 
 	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/andreagrandi/logbasset/internal/errors"
-	"github.com/andreagrandi/logbasset/internal/logging"
+	"github.com/andreagrandi/logbasset/internal/output"
+	"github.com/andreagrandi/logbasset/internal/timerange"
 	"github.com/andreagrandi/logbasset/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -34,6 +31,17 @@ var (
 	queryColumns   string
 	queryOutput    string
 	queryFields    string
+	queryAll       bool
+	queryLimit     int
+	querySplit     string
+	queryParallel  int
+	queryLRQ       bool
+	queryOut       string
+	queryFlatten   bool
+	queryAttrOrder string
+
+	queryTemplate     string
+	queryTemplateFile string
 )
 
 func init() {
@@ -42,8 +50,26 @@ func init() {
 	queryCmd.Flags().IntVar(&queryCount, "count", 10, "Number of log records to retrieve (1-5000)")
 	queryCmd.Flags().StringVar(&queryMode, "mode", "", "Display mode: head or tail")
 	queryCmd.Flags().StringVar(&queryColumns, "columns", "", "Comma-separated list of columns to display")
-	queryCmd.Flags().StringVar(&queryOutput, "output", "multiline", "Output format: multiline|singleline|compact|csv|json|json-pretty")
+	queryCmd.Flags().StringVar(&queryOutput, "output", "multiline", outputFlagUsage())
 	queryCmd.Flags().StringVar(&queryFields, "fields", "", "Comma-separated fields to include in JSON output (e.g., timestamp,message,severity)")
+	queryCmd.Flags().BoolVar(&queryAll, "all", false, "Follow continuation tokens and retrieve every matching record")
+	queryCmd.Flags().IntVar(&queryLimit, "limit", 0, "Follow continuation tokens until N records have been retrieved")
+	queryCmd.Flags().StringVar(&querySplit, "split", "", "Split the time range into windows of this size (e.g. 1h, 1d) and query them in parallel")
+	queryCmd.Flags().IntVar(&queryParallel, "parallel", 4, "Maximum number of --split windows queried at once")
+	queryCmd.Flags().BoolVar(&queryLRQ, "lrq", false, "Run through the long-running query API, showing progress on stderr")
+	queryCmd.Flags().StringVar(&queryOut, "out", "", outFlagUsage)
+	queryCmd.Flags().BoolVar(&queryFlatten, "flatten", false, flattenFlagUsage)
+	queryCmd.Flags().StringVar(&queryAttrOrder, "attr-order", "", attrOrderFlagUsage)
+	queryCmd.Flags().StringVar(&queryTemplate, "template", "", "Go text/template rendering each event, e.g. '{{.Timestamp | time \"15:04:05\"}} {{.Message}}'")
+	queryCmd.Flags().StringVar(&queryTemplateFile, "template-file", "", "File containing a --template")
+	queryCmd.MarkFlagsMutuallyExclusive("all", "limit", "count")
+	queryCmd.MarkFlagsMutuallyExclusive("lrq", "all")
+	queryCmd.MarkFlagsMutuallyExclusive("lrq", "limit")
+	queryCmd.MarkFlagsMutuallyExclusive("lrq", "split")
+	// The long-running query API has no head/tail mode or column selection
+	queryCmd.MarkFlagsMutuallyExclusive("lrq", "mode")
+	queryCmd.MarkFlagsMutuallyExclusive("lrq", "columns")
+	queryCmd.MarkFlagsMutuallyExclusive("template", "template-file", "output")
 }
 
 func runQuery(cmd *cobra.Command, args []string) {
@@ -52,6 +78,8 @@ func runQuery(cmd *cobra.Command, args []string) {
 		filter = args[0]
 	}
 
+	paginate := queryAll || cmd.Flags().Changed("limit")
+
 	// Validate inputs
 	validationConfig := validation.DefaultConfig()
 	params := validation.QueryValidationParams{
@@ -63,19 +91,36 @@ func runQuery(cmd *cobra.Command, args []string) {
 		Output:        queryOutput,
 		Priority:      getConfig().Priority,
 		Query:         filter,
-		ValidateCount: true,
+		ValidateCount: !paginate,
 	}
 
 	if err := validation.ValidateQueryParams(params, validationConfig); err != nil {
 		errors.HandleErrorAndExit(err)
 	}
 
-	if queryFields != "" {
-		if err := validation.ValidateFields(queryFields); err != nil {
+	if cmd.Flags().Changed("limit") {
+		if err := validation.ValidateLimit(queryLimit); err != nil {
 			errors.HandleErrorAndExit(err)
 		}
 	}
 
+	for _, fields := range []string{queryFields, queryAttrOrder} {
+		if err := validation.ValidateFields(fields); err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+	}
+
+	highlight := output.FilterTerms(filter)
+	tmpl, err := parseTemplateFlags(queryTemplate, queryTemplateFile, queryOut, highlight)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	var windows []timerange.Window
+	if querySplit != "" {
+		windows = resolveShards(queryStartTime, queryEndTime, querySplit, queryParallel)
+	}
+
 	c := getConfig().GetClient()
 
 	clientParams := client.QueryParams{
@@ -88,8 +133,13 @@ func runQuery(cmd *cobra.Command, args []string) {
 		Priority:  getConfig().Priority,
 	}
 
+	// A paged fetch applies --timeout to each page instead of the whole run
+	if paginate && windows == nil {
+		clientParams.PageTimeout = getTimeout()
+	}
+
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), getTimeout())
+	ctx, cancel := commandContext(windows != nil || queryLRQ || paginate)
 	defer cancel()
 
 	// Set up signal handling for graceful cancellation
@@ -101,169 +151,56 @@ func runQuery(cmd *cobra.Command, args []string) {
 		cancel()
 	}()
 
-	result, err := c.Query(ctx, clientParams)
-	if err != nil {
-		errors.HandleErrorAndExit(err)
-	}
-
 	if !cmd.Flags().Changed("output") && !IsTTY() {
 		queryOutput = "json"
 		errors.OutputJSON = true
 	}
 
-	switch queryOutput {
-	case "json":
-		if queryFields != "" {
-			outputFilteredJSON(result.Matches, queryFields, false)
-		} else {
-			outputJSON(result, false)
-		}
-	case "json-pretty":
-		if queryFields != "" {
-			outputFilteredJSON(result.Matches, queryFields, true)
-		} else {
-			outputJSON(result, true)
-		}
-	case "csv":
-		if queryFields != "" {
-			logging.Warn("--fields is only supported with json/json-pretty output, ignoring")
+	if paginate || windows != nil {
+		limit := queryLimit
+		if !paginate {
+			limit = queryCount
 		}
-		outputCSV(result.Matches, queryColumns)
-	case "singleline":
-		if queryFields != "" {
-			logging.Warn("--fields is only supported with json/json-pretty output, ignoring")
-		}
-		outputSingleLine(result.Matches)
-	case "compact":
-		if queryFields != "" {
-			logging.Warn("--fields is only supported with json/json-pretty output, ignoring")
-		}
-		outputCompact(result.Matches)
-	default:
-		if queryFields != "" {
-			logging.Warn("--fields is only supported with json/json-pretty output, ignoring")
-		}
-		outputMultiLine(result.Matches)
-	}
-}
 
-func outputFilteredJSON(events []client.LogEvent, fields string, pretty bool) {
-	fieldList := strings.Split(fields, ",")
-	for i, f := range fieldList {
-		fieldList[i] = strings.TrimSpace(f)
-	}
-
-	fieldSet := make(map[string]bool, len(fieldList))
-	for _, f := range fieldList {
-		fieldSet[f] = true
-	}
-
-	var filtered []map[string]interface{}
-	for _, event := range events {
-		m := make(map[string]interface{})
-		if fieldSet["timestamp"] {
-			m["timestamp"] = event.Timestamp
-		}
-		if fieldSet["severity"] {
-			m["severity"] = event.Severity
-		}
-		if fieldSet["message"] {
-			m["message"] = event.Message
-		}
-		if fieldSet["thread"] {
-			m["thread"] = event.Thread
-		}
-		// Check attribute keys
-		for _, f := range fieldList {
-			if f == "timestamp" || f == "severity" || f == "message" || f == "thread" {
-				continue
-			}
-			if val, ok := event.Attributes[f]; ok {
-				m[f] = val
-			}
+		f := newEventFormatter(tmpl, queryOutput, queryOut, queryOptions(highlight))
+		if windows != nil {
+			runQueryStream(f, c.QueryWindows(ctx, clientParams, windows, limit, shardOptions(queryParallel)))
+		} else {
+			runQueryStream(f, c.QueryAll(ctx, clientParams, limit))
 		}
-		filtered = append(filtered, m)
-	}
-
-	outputJSON(filtered, pretty)
-}
-
-func outputCSV(events []client.LogEvent, columns string) {
-	writer := csv.NewWriter(os.Stdout)
-	defer writer.Flush()
-
-	if columns == "" {
-		columns = "timestamp,severity,message"
+		return
 	}
 
-	columnList := strings.Split(columns, ",")
-	for i, col := range columnList {
-		columnList[i] = strings.TrimSpace(col)
+	// Only the formats printing the API response unchanged need all of it;
+	// the rest write each event as soon as it is decoded
+	if !queryLRQ && (tmpl != nil || !documentFormat(queryOutput)) {
+		f := newEventFormatter(tmpl, queryOutput, queryOut, queryOptions(highlight))
+		runQueryStream(f, c.QueryIter(ctx, clientParams))
+		return
 	}
 
-	writer.Write(columnList)
-
-	for _, event := range events {
-		record := make([]string, len(columnList))
-		for i, col := range columnList {
-			switch col {
-			case "timestamp":
-				record[i] = event.Timestamp
-			case "severity":
-				record[i] = fmt.Sprintf("%d", event.Severity)
-			case "message":
-				record[i] = event.Message
-			case "thread":
-				record[i] = event.Thread
-			default:
-				if val, ok := event.Attributes[col]; ok {
-					record[i] = fmt.Sprintf("%v", val)
-				}
-			}
-		}
-		writer.Write(record)
+	var result *client.QueryResponse
+	if queryLRQ {
+		progress, done := lrqProgress()
+		result, err = c.QueryLRQ(ctx, clientParams, progress)
+		done()
+	} else {
+		result, err = c.Query(ctx, clientParams)
 	}
-}
-
-func outputSingleLine(events []client.LogEvent) {
-	for _, event := range events {
-		fmt.Printf("%s [%d] %s", event.Timestamp, event.Severity, event.Message)
-		if event.Thread != "" {
-			fmt.Printf(" (thread: %s)", event.Thread)
-		}
-		if len(event.Attributes) > 0 {
-			attrs := make([]string, 0, len(event.Attributes))
-			for k, v := range event.Attributes {
-				attrs = append(attrs, fmt.Sprintf("%s=%v", k, v))
-			}
-			fmt.Printf(" [%s]", strings.Join(attrs, ", "))
-		}
-		fmt.Println()
+	if err != nil {
+		errors.HandleErrorAndExit(err)
 	}
-}
 
-func outputCompact(events []client.LogEvent) {
-	for _, event := range events {
-		fmt.Printf("%s %s %s\n", formatCompactTimestamp(event.Timestamp), severityChar(event.Severity), event.Message)
-	}
+	f := newEventFormatter(tmpl, queryOutput, queryOut, queryOptions(highlight))
+	exitOnOutputError(output.WriteEvents(f, output.Header{Columns: splitFields(queryColumns), Document: result}, result.Matches))
 }
 
-func outputMultiLine(events []client.LogEvent) {
-	for i, event := range events {
-		if i > 0 {
-			fmt.Println()
-		}
-		fmt.Printf("Timestamp: %s\n", event.Timestamp)
-		fmt.Printf("Severity: %d\n", event.Severity)
-		fmt.Printf("Message: %s\n", event.Message)
-		if event.Thread != "" {
-			fmt.Printf("Thread: %s\n", event.Thread)
-		}
-		if len(event.Attributes) > 0 {
-			fmt.Println("Attributes:")
-			for k, v := range event.Attributes {
-				fmt.Printf("  %s: %v\n", k, v)
-			}
-		}
+// queryOptions returns the output options the query flags select.
+func queryOptions(highlight []string) output.Options {
+	return output.Options{
+		Fields:    fieldsOption(queryOutput, queryFields),
+		Highlight: highlight,
+		Flatten:   queryFlatten,
+		AttrOrder: splitFields(queryAttrOrder),
 	}
 }
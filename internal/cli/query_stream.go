@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"iter"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
+)
+
+// runQueryStream prints the events of a query as soon as they arrive, for
+// `query --all/--limit/--split` and for single pages in formats that do not
+// need the whole response. Without a Document the
+// formatter builds the same shape a single large page would have.
+func runQueryStream(f output.Formatter, events iter.Seq2[client.LogEvent, error]) {
+	exitOnOutputError(f.Begin(output.Header{Events: true, Columns: splitFields(queryColumns)}))
+	for event, err := range events {
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+		exitOnOutputError(f.Write(output.Record{Event: &event}))
+	}
+	exitOnOutputError(f.End())
+}
@@ -3,44 +3,40 @@ package cli
 import (
 	"testing"
 
-	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOutputCompact(t *testing.T) {
-	events := []client.LogEvent{
-		{Timestamp: "1700000000000000000", Severity: 3, Message: "service ready"},
-		{Timestamp: "1700000001000000000", Severity: 5, Message: "boom"},
-		{Timestamp: "not-a-time", Severity: 0, Message: "fallback"},
-	}
+	response := `{"status":"success","matches":[` +
+		`{"timestamp":"1700000000000000000","severity":3,"message":"service ready"},` +
+		`{"timestamp":"1700000001000000000","severity":5,"message":"boom"},` +
+		`{"timestamp":"not-a-time","severity":0,"message":"fallback"}` +
+		`]}`
 
-	out := captureStdout(t, func() {
-		outputCompact(events)
-	})
+	run := runCLI(t, response, "query", "--output", "compact")
 
 	expected := "22:13:20 I service ready\n" +
 		"22:13:21 E boom\n" +
 		"not-a-time D fallback\n"
-	assert.Equal(t, expected, out)
+	assert.Equal(t, expected, run.stdout)
 }
 
 func TestOutputCompact_Empty(t *testing.T) {
-	out := captureStdout(t, func() {
-		outputCompact(nil)
-	})
-	assert.Empty(t, out)
+	run := runCLI(t, `{"status":"success","matches":[]}`, "query", "--output", "compact")
+	assert.Empty(t, run.stdout)
 }
 
-func TestOutputTailCompact(t *testing.T) {
-	event := client.LogEvent{
-		Timestamp: "1700000000000000000",
-		Severity:  4,
-		Message:   "warning ahead",
+func TestQueryLRQRejectsModeAndColumns(t *testing.T) {
+	for _, flag := range []string{"--mode=tail", "--columns=message"} {
+		t.Run(flag, func(t *testing.T) {
+			resetCLIFlags()
+			defer resetCLIFlags()
+
+			require.NoError(t, queryCmd.ParseFlags([]string{"--lrq", flag}))
+			err := queryCmd.ValidateFlagGroups()
+			require.Error(t, err, "the long-running query API would ignore %s", flag)
+			assert.Contains(t, err.Error(), "lrq")
+		})
 	}
-
-	out := captureStdout(t, func() {
-		outputTailCompact(event)
-	})
-
-	assert.Equal(t, "22:13:20 W warning ahead\n", out)
 }
@@ -1,11 +1,13 @@
 package cli
 
 import (
+	"strings"
 	"time"
 
 	"github.com/andreagrandi/logbasset/internal/app"
 	"github.com/andreagrandi/logbasset/internal/config"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
 	"github.com/spf13/cobra"
 )
 
@@ -19,6 +21,10 @@ var (
 	flagTimeout     time.Duration
 	flagErrorFormat string
 	flagPager       bool
+	flagProfile     string
+	flagColor       string
+	flagTimeFormat  string
+	flagTZ          string
 
 	activePager *pagerProcess
 )
@@ -34,7 +40,12 @@ The following commands are currently supported:
 - numeric-query: Retrieve numeric / graph data
 - facet-query: Retrieve common values for a field
 - timeseries-query: Retrieve numeric / graph data from a timeseries
-- tail: Provide a live 'tail' of a log`,
+- tail: Provide a live 'tail' of a log
+- ingest: Send log lines to Scalyr
+- serve-metrics: Export query results as Prometheus metrics
+- check: Check a numeric query against thresholds
+- files: Manage configuration files (parsers, dashboards, alerts)
+- config: Inspect configuration and named profiles`,
 	Version: app.Version,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Apply error format before anything else so errors during init are formatted correctly
@@ -53,18 +64,67 @@ The following commands are currently supported:
 		}
 
 		var err error
-		cfg, err = config.NewWithoutValidation()
+		cfg, err = config.LoadProfile(flagProfile)
 		if err != nil {
 			return err
 		}
 
-		cfg.SetFromFlags(flagToken, flagServer, flagVerbose, flagPriority, flagLogLevel)
+		// Only explicitly set flags override the config file, so a profile's
+		// verbosity, priority or log level is not masked by the flag defaults
+		flags := cmd.Flags()
+		verbose, priority, logLevel := flagVerbose, flagPriority, flagLogLevel
+		if !flags.Changed("verbose") {
+			verbose = false
+		}
+		if !flags.Changed("priority") {
+			priority = ""
+		}
+		if !flags.Changed("log-level") {
+			logLevel = ""
+		}
+		// --token replaces the token of the kind the command authenticates with
+		kind := commandTokenKind(cmd)
+		cfg.SetFromFlags("", flagServer, verbose, priority, logLevel)
+		cfg.SetTokenFromFlag(kind, flagToken)
+		if flags.Changed("timeout") {
+			cfg.Timeout = flagTimeout
+			cfg.SetSource("timeout", config.SourceFlag)
+		}
+		if flags.Changed("profile") {
+			cfg.SetSource("profile", config.SourceFlag)
+		}
+		if flags.Changed("color") {
+			cfg.Color = flagColor
+			cfg.SetSource("color", config.SourceFlag)
+		}
+		if flags.Changed("time-format") {
+			cfg.TimeFormat = flagTimeFormat
+			cfg.SetSource("time_format", config.SourceFlag)
+		}
+		if flags.Changed("tz") {
+			cfg.TZ = flagTZ
+			cfg.SetSource("tz", config.SourceFlag)
+		}
 
 		if err := cfg.ApplyLogging(); err != nil {
 			return err
 		}
 
-		if err := cfg.Validate(); err != nil {
+		// config subcommands inspect the configuration and need no token
+		if isConfigCommand(cmd) {
+			return nil
+		}
+
+		if kind == config.TokenRead {
+			if err := cfg.ResolveToken(cmd.Context()); err != nil {
+				return err
+			}
+		}
+
+		if err := cfg.ValidateFor(kind); err != nil {
+			return err
+		}
+		if _, err := output.NewTimeFormat(cfg.TimeFormat, cfg.TZ); err != nil {
 			return err
 		}
 
@@ -77,14 +137,18 @@ The following commands are currently supported:
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&flagToken, "token", "", "API token (can also use scalyr_readlog_token env var)")
+	rootCmd.PersistentFlags().StringVar(&flagToken, "token", "", "API token (can also use scalyr_readlog_token env var; ingest and files use their own tokens)")
 	rootCmd.PersistentFlags().StringVar(&flagServer, "server", "", "Scalyr server URL (can also use scalyr_server env var)")
 	rootCmd.PersistentFlags().BoolVar(&flagVerbose, "verbose", false, "Enable verbose output")
 	rootCmd.PersistentFlags().StringVar(&flagPriority, "priority", "high", "Query priority (high|low)")
 	rootCmd.PersistentFlags().StringVar(&flagLogLevel, "log-level", "info", "Log level (debug|info|warn|error)")
-	rootCmd.PersistentFlags().DurationVar(&flagTimeout, "timeout", 30*time.Second, "Request timeout (e.g., 30s, 2m, 1h)")
+	rootCmd.PersistentFlags().DurationVar(&flagTimeout, "timeout", config.DefaultTimeout, "Request timeout (e.g., 30s, 2m, 1h)")
 	rootCmd.PersistentFlags().StringVar(&flagErrorFormat, "error-format", "text", "Error output format: text|json")
+	rootCmd.PersistentFlags().StringVar(&flagProfile, "profile", "", "Named profile from the config file (can also use scalyr_profile env var)")
 	rootCmd.PersistentFlags().BoolVar(&flagPager, "pager", false, "Pipe output through $PAGER (default 'less -RF') when stdout is a terminal")
+	rootCmd.PersistentFlags().StringVar(&flagColor, "color", "auto", "Colour output: auto|always|never (auto honours NO_COLOR and FORCE_COLOR)")
+	rootCmd.PersistentFlags().StringVar(&flagTimeFormat, "time-format", "", "Event timestamps in text, CSV and table output: "+strings.Join(output.TimeFormats, "|")+", or a Go layout")
+	rootCmd.PersistentFlags().StringVar(&flagTZ, "tz", "", "Time zone of rendered timestamps: local, UTC (default) or an IANA name such as Europe/London")
 
 	rootCmd.AddCommand(queryCmd)
 	rootCmd.AddCommand(powerQueryCmd)
@@ -92,14 +156,40 @@ func init() {
 	rootCmd.AddCommand(facetQueryCmd)
 	rootCmd.AddCommand(timeseriesQueryCmd)
 	rootCmd.AddCommand(tailCmd)
+	rootCmd.AddCommand(ingestCmd)
+	rootCmd.AddCommand(serveMetricsCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(filesCmd)
 	rootCmd.AddCommand(contextCmd)
 	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// tokenAnnotation names the config.TokenKind a command authenticates with;
+// commands without it use the read token.
+const tokenAnnotation = "token"
+
+func commandTokenKind(cmd *cobra.Command) config.TokenKind {
+	if kind := cmd.Annotations[tokenAnnotation]; kind != "" {
+		return config.TokenKind(kind)
+	}
+	return config.TokenRead
+}
+
+func isConfigCommand(cmd *cobra.Command) bool {
+	for c := cmd.Parent(); c != nil; c = c.Parent() {
+		if c == configCmd {
+			return true
+		}
+	}
+	return false
 }
 
 func Execute() error {
 	errors.BeforeExit = func() {
 		activePager.stop()
 	}
+	config.Passphrase = promptPassphrase(config.Passphrase)
 	defer activePager.stop()
 	return rootCmd.Execute()
 }
@@ -108,6 +198,11 @@ func getConfig() *config.Config {
 	return cfg
 }
 
+// getTimeout returns the effective timeout: --timeout, then the environment
+// or config file (including the active profile), then the default.
 func getTimeout() time.Duration {
+	if cfg != nil && cfg.Timeout > 0 {
+		return cfg.Timeout
+	}
 	return flagTimeout
 }
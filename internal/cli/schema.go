@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/andreagrandi/logbasset/internal/check"
+	"github.com/andreagrandi/logbasset/internal/config"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/filesync"
+	"github.com/andreagrandi/logbasset/internal/output"
 	"github.com/spf13/cobra"
 )
 
@@ -92,13 +96,32 @@ func getCommandList() []commandSummary {
 		{Name: "facet-query", Description: "Retrieve common values for a field"},
 		{Name: "timeseries-query", Description: "Retrieve timeseries data"},
 		{Name: "tail", Description: "Provide a live tail of a log"},
+		{Name: "ingest", Description: "Send log lines to Scalyr (writes data; needs a write token)"},
+		{Name: "serve-metrics", Description: "Export query results as Prometheus metrics (long-running HTTP server)"},
+		{Name: "check", Description: "Check a numeric query against thresholds; Nagios status line and exit codes 0-3"},
+		{Name: "files ls", Description: "List configuration files (parsers, dashboards, alerts, lookups)"},
+		{Name: "files get", Description: "Print a configuration file"},
+		{Name: "files put", Description: "Create, replace or delete a configuration file (writes data)"},
+		{Name: "files diff", Description: "Show differences between local and remote configuration files"},
+		{Name: "files pull", Description: "Download configuration files into a directory"},
+		{Name: "files push", Description: "Upload changed files from a directory (writes data)"},
+		{Name: "config init", Description: "Create a config file"},
+		{Name: "config get", Description: "Print the effective value of a config key"},
+		{Name: "config set", Description: "Write a config key to the config file"},
+		{Name: "config unset", Description: "Remove a config key from the config file"},
+		{Name: "config list", Description: "List effective settings and where each came from"},
+		{Name: "config validate", Description: "Check the effective configuration and every profile"},
+		{Name: "config path", Description: "Print the path of the loaded config file"},
+		{Name: "config profiles", Description: "List the named profiles in the config file"},
+		{Name: "config credentials set", Description: "Store a token in the credential store"},
+		{Name: "config credentials delete", Description: "Remove a token from the credential store"},
 		{Name: "global", Description: "Flags shared by every command (schema target only, not a runnable command)"},
 	}
 }
 
 func globalFlags() []paramSchema {
 	return []paramSchema{
-		{Name: "token", Type: "string", Required: false, Description: "API token (or scalyr_readlog_token env var)"},
+		{Name: "token", Type: "string", Required: false, Description: "API token (or scalyr_readlog_token env var); for ingest, the write token (or scalyr_writelog_token env var)"},
 		{Name: "server", Type: "string", Required: false, Default: "https://www.scalyr.com", Description: "Scalyr server URL (or scalyr_server env var)"},
 		{Name: "verbose", Type: "boolean", Required: false, Default: false, Description: "Enable verbose output"},
 		{Name: "priority", Type: "string", Required: false, Default: "high", Enum: []string{"high", "low"}, Description: "Query priority; use 'low' for heavy or background queries"},
@@ -106,9 +129,28 @@ func globalFlags() []paramSchema {
 		{Name: "timeout", Type: "string", Required: false, Default: "30s", Description: "Request timeout (e.g., 30s, 2m)"},
 		{Name: "error-format", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Error output format"},
 		{Name: "pager", Type: "boolean", Required: false, Default: false, Description: "Pipe output through $PAGER (default 'less -RF') when stdout is a terminal"},
+		{Name: "profile", Type: "string", Required: false, Description: "Named profile from the config file's profiles: map (or scalyr_profile env var)"},
+		{Name: "color", Type: "string", Required: false, Default: "auto", Enum: config.ColorModes, Description: "Colour text output by severity and highlight filter terms; auto colours terminals unless NO_COLOR is set, or when FORCE_COLOR is (or the color config key)"},
+		{Name: "time-format", Type: "string", Required: false, Description: "How event timestamps are rendered by the text, CSV/TSV, logfmt, table, markdown and html formats and the template timestamp function: raw (the nanosecond string, the default), rfc3339, rfc3339nano, unix, unixms, relative (e.g. 3m ago) or a Go layout such as '2006-01-02 15:04:05' (or the time_format config key). JSON, YAML, Parquet and Arrow are unaffected"},
+		{Name: "tz", Type: "string", Required: false, Description: "Time zone timestamps are rendered in by --time-format, compact output and the template time functions: local, UTC (the default) or an IANA name such as Europe/London (or the tz config key)"},
 	}
 }
 
+// outFlagSchema describes the --out flag shared by the commands whose results
+// go through the output package.
+var outFlagSchema = paramSchema{Name: "out", Type: "string", Required: false, Description: "Write the results to this file instead of stdout, created or truncated. Required for the binary parquet and arrow formats unless stdout is redirected"}
+
+// flattenFlagSchema and attrOrderFlagSchema describe the attribute layout
+// flags of the commands that print events.
+var (
+	flattenFlagSchema   = paramSchema{Name: "flatten", Type: "boolean", Required: false, Default: false, Description: "Write nested attribute objects as dotted keys (http.status=500) in the multiline, singleline and logfmt formats, instead of as JSON"}
+	attrOrderFlagSchema = paramSchema{Name: "attr-order", Type: "string", Required: false, Description: "Comma-separated attributes written first, in this order, by the multiline, singleline and logfmt formats; the rest follow sorted by name. Dotted keys name flattened attributes"}
+)
+
+// longFlagSchema describes the --long flag of numeric-query and
+// timeseries-query.
+var longFlagSchema = paramSchema{Name: "long", Type: "boolean", Required: false, Default: false, Description: "Write a bucket_start,bucket_end,value row per bucket, the bounds computed from --start/--end and rendered with --time-format (RFC 3339 by default); JSON becomes an array of those objects. Without it, JSON is the API response ({\"values\": [...]}) and other formats one row with a column per bucket"}
+
 var schemas = map[string]commandSchema{
 	"query": {
 		Command:  "query",
@@ -122,13 +164,29 @@ var schemas = map[string]commandSchema{
 			{Name: "count", Type: "integer", Required: false, Default: 10, Description: "Number of log records (1-5000)"},
 			{Name: "mode", Type: "string", Required: false, Enum: []string{"head", "tail"}, Description: "Display mode"},
 			{Name: "columns", Type: "string", Required: false, Description: "Comma-separated list of columns"},
-			{Name: "output", Type: "string", Required: false, Default: "multiline", Enum: []string{"multiline", "singleline", "compact", "csv", "json", "json-pretty", "messageonly"}, Description: "Output format"},
+			{Name: "output", Type: "string", Required: false, Default: "multiline", Enum: output.Names(), Description: "Output format"},
 			{Name: "fields", Type: "string", Required: false, Description: "Comma-separated fields to include in JSON output (e.g., timestamp,message,severity)"},
+			{Name: "all", Type: "boolean", Required: false, Default: false, Description: "Follow continuation tokens and retrieve every matching record (cannot be combined with --count or --limit)"},
+			{Name: "limit", Type: "integer", Required: false, Description: "Follow continuation tokens until N records have been retrieved; no 5000 cap (cannot be combined with --count or --all)"},
+			{Name: "split", Type: "string", Required: false, Description: "Split the --start/--end range into windows of this size (e.g., 1h, 1d) queried in parallel; requires --start. --count and --limit cap the total across windows; --timeout applies per window"},
+			{Name: "parallel", Type: "integer", Required: false, Default: 4, Description: "Maximum number of --split windows queried at once (1-16)"},
+			{Name: "lrq", Type: "boolean", Required: false, Default: false, Description: "Run through the long-running query API (/api/v2/query) with no timeout; progress goes to stderr when it is a terminal and Ctrl-C cancels the query on the server (cannot be combined with --all, --limit, --split, --mode or --columns)"},
+			{Name: "template", Type: "string", Required: false, Description: "Go text/template executed for each event instead of --output, one line per event. Fields: .Timestamp, .Severity, .Message, .Thread, .Attributes. Functions: time LAYOUT, compacttime, severity, sevchar, color NAME, sevcolor SEV, pad N, padleft N, trunc N, json, highlight, default VALUE"},
+			{Name: "template-file", Type: "string", Required: false, Description: "File containing a --template"},
+			outFlagSchema,
+			flattenFlagSchema,
+			attrOrderFlagSchema,
 		},
 		OutputKeys: []string{"timestamp", "severity", "message", "thread", "attributes"},
 		Examples: []string{
 			"logbasset query 'severity=\"error\"' --start 1h --count 100 --output json",
 			"logbasset query '\"req-abc123\"' --start 24h --end NOW --output json --fields timestamp,message",
+			"logbasset query 'severity=\"error\"' --start 6h --end NOW --limit 50000 --output compact",
+			"logbasset query 'severity=\"error\"' --start 7d --end NOW --split 1h --parallel 4 --all --output json",
+			"logbasset query 'severity=\"error\"' --start 30d --count 1000 --lrq --output json",
+			"logbasset query 'severity=\"error\"' --start 7d --all --output parquet --out errors.parquet",
+			"logbasset query 'severity=\"error\"' --start 1h --output singleline --flatten --attr-order serverHost,http.status",
+			"logbasset query 'severity=\"error\"' --start 1h --template '{{.Timestamp | time \"15:04:05\"}} {{.Attributes.serverHost | default \"-\"}} {{.Message}}'",
 		},
 	},
 	"power-query": {
@@ -140,10 +198,17 @@ var schemas = map[string]commandSchema{
 		Flags: []paramSchema{
 			{Name: "start", Type: "string", Required: true, Description: "Start time (required)"},
 			{Name: "end", Type: "string", Required: false, Description: "End time"},
-			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: []string{"csv", "json", "json-pretty"}, Description: "Output format"},
+			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: output.Names(), Description: "Output format"},
+			{Name: "split", Type: "string", Required: false, Description: "Split the time range into windows of this size (e.g., 1h, 1d) queried in parallel; rows are concatenated and aggregates are not recombined across windows"},
+			{Name: "parallel", Type: "integer", Required: false, Default: 4, Description: "Maximum number of --split windows queried at once (1-16)"},
+			{Name: "lrq", Type: "boolean", Required: false, Default: false, Description: "Run through the long-running query API (/api/v2/query) with no timeout; progress goes to stderr when it is a terminal and Ctrl-C cancels the query on the server (cannot be combined with --split)"},
+			outFlagSchema,
 		},
 		Examples: []string{
 			"logbasset power-query 'severity=\"error\" | group count by serverHost' --start 1h --output json",
+			"logbasset power-query 'severity=\"error\" | columns timestamp, message' --start 7d --end NOW --split 1d --output json",
+			"logbasset power-query 'dataset = \"accesslog\" | group count() by status' --start 30d --lrq --output json",
+			"logbasset power-query 'dataset = \"accesslog\" | columns timestamp, status, uriPath' --start 1d --output arrow --out access.arrow",
 		},
 	},
 	"numeric-query": {
@@ -157,11 +222,14 @@ var schemas = map[string]commandSchema{
 			{Name: "start", Type: "string", Required: true, Description: "Start time (required)"},
 			{Name: "end", Type: "string", Required: false, Description: "End time"},
 			{Name: "buckets", Type: "integer", Required: false, Default: 1, Description: "Number of time buckets (1-5000)"},
-			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: []string{"csv", "json", "json-pretty"}, Description: "Output format"},
+			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: output.Names(), Description: "Output format"},
+			outFlagSchema,
+			longFlagSchema,
 		},
 		OutputKeys: []string{"values"},
 		Examples: []string{
 			"logbasset numeric-query 'severity=\"error\"' --function count --start 24h --buckets 24 --output json",
+			"logbasset numeric-query 'severity=\"error\"' --function count --start 24h --buckets 24 --long --output csv --time-format unix > errors.csv",
 		},
 	},
 	"facet-query": {
@@ -175,7 +243,8 @@ var schemas = map[string]commandSchema{
 			{Name: "start", Type: "string", Required: true, Description: "Start time (required)"},
 			{Name: "end", Type: "string", Required: false, Description: "End time"},
 			{Name: "count", Type: "integer", Required: false, Default: 100, Description: "Number of distinct values (1-1000)"},
-			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: []string{"csv", "json", "json-pretty"}, Description: "Output format"},
+			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: output.Names(), Description: "Output format"},
+			outFlagSchema,
 		},
 		OutputKeys: []string{"value", "count"},
 		Examples: []string{
@@ -193,13 +262,19 @@ var schemas = map[string]commandSchema{
 			{Name: "start", Type: "string", Required: true, Description: "Start time (required)"},
 			{Name: "end", Type: "string", Required: false, Description: "End time"},
 			{Name: "buckets", Type: "integer", Required: false, Default: 1, Description: "Number of time buckets (1-5000)"},
-			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: []string{"csv", "json", "json-pretty"}, Description: "Output format"},
+			{Name: "output", Type: "string", Required: false, Default: "csv", Enum: output.Names(), Description: "Output format"},
+			outFlagSchema,
 			{Name: "only-use-summaries", Type: "boolean", Required: false, Default: false, Description: "Only query summaries"},
 			{Name: "no-create-summaries", Type: "boolean", Required: false, Default: false, Description: "Don't create summaries"},
+			longFlagSchema,
+			{Name: "query", Type: "string", Required: false, Description: "Named query as name:filter:function (repeatable); every query runs in one request and each result carries its name, or with --long each row starts with a query column holding it. The function follows the last colon, so filters may contain colons; leave it empty for --function. Cannot be combined with the filter argument"},
+			{Name: "queries-file", Type: "string", Required: false, Description: "YAML or JSON file with a \"queries\" list of {name, filter, function, start, end, buckets}; omitted fields come from the flags. Its queries run before any --query, in the same request"},
 		},
 		OutputKeys: []string{"values"},
 		Examples: []string{
 			"logbasset timeseries-query 'severity=\"error\"' --function count --start 24h --buckets 24 --output json",
+			"logbasset timeseries-query --start 1h --buckets 12 --query 'errors:severity >= 5:count' --query 'latency:$serverHost == \"web-01\":mean(latency)'",
+			"logbasset timeseries-query --start 24h --queries-file dashboard.yaml --output json",
 		},
 	},
 	"tail": {
@@ -210,11 +285,294 @@ var schemas = map[string]commandSchema{
 		},
 		Flags: []paramSchema{
 			{Name: "lines", Type: "integer", Required: false, Default: 10, Description: "Number of previous lines to show (-n)"},
-			{Name: "output", Type: "string", Required: false, Default: "messageonly", Enum: []string{"messageonly", "multiline", "singleline", "compact", "json"}, Description: "Output format"},
+			{Name: "output", Type: "string", Required: false, Default: "messageonly", Enum: output.Names(), Description: "Output format"},
+			{Name: "interval", Type: "string", Required: false, Default: "2s", Description: "Delay between polls for new records (e.g., 500ms, 2s, 1m; at least 500ms)"},
+			{Name: "max-interval", Type: "string", Required: false, Description: "Enable adaptive polling: a full page is followed immediately by the next poll, and idle polls back off up to this delay"},
+			{Name: "page-size", Type: "integer", Required: false, Default: 1000, Description: "Maximum records fetched per poll (1-5000)"},
+			{Name: "filter", Type: "string", Required: false, Description: "Tail several filters at once as name=expression (repeatable); each record is prefixed with its name, or carries it as \"source\" in JSON. Each round of polls is printed merged in timestamp order once every filter has answered. Cannot be combined with the filter argument, or with --checkpoint when repeated"},
+			{Name: "checkpoint", Type: "string", Required: false, Description: "JSON file updated with the continuation token and last event timestamp after each batch; when it exists, the tail resumes from it instead of printing --lines"},
+			{Name: "template", Type: "string", Required: false, Description: "Go text/template executed for each record instead of --output, as for query; .Source holds the --filter name"},
+			{Name: "template-file", Type: "string", Required: false, Description: "File containing a --template"},
+			outFlagSchema,
+			{Name: "rotate-size", Type: "string", Required: false, Description: "Rotate the --out file once it reaches this size (e.g., 500KB, 100MB, 1GB; units are powers of 1024). The file is moved aside as <name>-<UTC start time><ext> and a new one started; each rotated file is complete in its format"},
+			{Name: "rotate-every", Type: "string", Required: false, Description: "Rotate the --out file at every multiple of this interval in UTC (e.g., 1h, 1d; at least 1m), checked as records arrive"},
+			{Name: "compress", Type: "string", Required: false, Enum: output.Compressions, Description: "Compress rotated files, adding .gz or .zst; the active file stays uncompressed"},
+			{Name: "retain", Type: "integer", Required: false, Default: 0, Description: "Number of rotated files to keep, deleting the oldest (0 keeps all)"},
+			flattenFlagSchema,
+			attrOrderFlagSchema,
 		},
-		OutputKeys: []string{"timestamp", "severity", "message", "thread", "attributes"},
+		OutputKeys: []string{"source", "timestamp", "severity", "message", "thread", "attributes"},
 		Examples: []string{
 			"logbasset tail 'severity=\"error\"' --lines 50 --output json",
+			"logbasset tail --filter 'api=$serverHost=\"api-1\"' --filter 'db=$logfile contains \"postgres\"' --output json",
+			"logbasset tail --interval 1s --max-interval 30s --page-size 5000 --output json",
+			"logbasset tail 'severity=\"error\"' --checkpoint /var/lib/logbasset/errors.checkpoint --output json",
+			"logbasset tail 'severity=\"error\"' --output ndjson --out /var/log/logbasset/errors.ndjson --rotate-every 1h --rotate-size 100MB --compress zstd --retain 48",
+			"logbasset tail --template '{{compacttime .Timestamp}} {{.Severity | severity | pad 7}} {{.Message | trunc 120}}'",
+		},
+	},
+	"ingest": {
+		Command:  "ingest",
+		ReadOnly: false,
+		Args: []paramSchema{
+			{Name: "file", Type: "string", Required: false, Description: "Files to read, one event per non-empty line; stdin when omitted or '-' (repeatable)"},
+		},
+		Flags: []paramSchema{
+			{Name: "server-host", Type: "string", Required: false, Description: "serverHost attribute for the events (default: this machine's hostname)"},
+			{Name: "parser", Type: "string", Required: false, Description: "Scalyr parser applied to each line"},
+			{Name: "logfile", Type: "string", Required: false, Description: "logfile attribute for the events (default: the file path, or 'stdin')"},
+			{Name: "attr", Type: "string", Required: false, Description: "Server attribute key=value added to every event (repeatable)"},
+			{Name: "severity", Type: "integer", Required: false, Default: 3, Description: "Severity of the events, 0 (finest) to 6 (fatal)"},
+			{Name: "upload", Type: "boolean", Required: false, Default: false, Description: "Send raw text through the uploadLogs API instead of addEvents"},
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Summary format"},
+			{Name: "flush-interval", Type: "string", Required: false, Default: "5s", Description: "Send pending events at least this often (e.g., 1s); 0 sends only full batches and at the end"},
+		},
+		OutputKeys: []string{"session", "events", "requests", "bytes"},
+		Examples: []string{
+			"logbasset ingest /var/log/app.log --parser json --attr env=staging",
+			"some-job 2>&1 | logbasset ingest --server-host batch-1 --logfile some-job --output json",
+		},
+	},
+	"serve-metrics": {
+		Command:  "serve-metrics",
+		ReadOnly: true,
+		Flags: []paramSchema{
+			{Name: "config", Type: "string", Required: true, Description: "YAML metrics config: cache (how often the queries run again in the background, default 1m), start (default for every metric) and a metrics list of {name, help, type (gauge|counter|untyped), query (timeseries|numeric), filter, function, start, end, labels}. Each metric is the value of its query over start..end in one bucket; timeseries metrics share one request"},
+			{Name: "listen", Type: "string", Required: false, Default: ":9464", Description: "Address the /metrics endpoint is served on"},
+		},
+		Examples: []string{
+			"logbasset serve-metrics --config metrics.yaml --listen :9464",
+			"logbasset serve-metrics --config metrics.yaml --priority low --listen 127.0.0.1:9464",
+		},
+	},
+	"check": {
+		Command:  "check",
+		ReadOnly: true,
+		Flags: []paramSchema{
+			{Name: "filter", Type: "string", Required: false, Description: "Log filter expression of the query"},
+			{Name: "function", Type: "string", Required: false, Description: "Function to compute (e.g., count, mean(latency))"},
+			{Name: "start", Type: "string", Required: true, Description: "Start time (required)"},
+			{Name: "end", Type: "string", Required: false, Description: "End time"},
+			{Name: "buckets", Type: "integer", Required: false, Default: 1, Description: "Number of time buckets (1-5000); the last one is checked. Defaults to 2 with --change"},
+			{Name: "warn", Type: "string", Required: false, Description: "Warning threshold: N or >N is breached above N, <N below N"},
+			{Name: "crit", Type: "string", Required: false, Description: "Critical threshold, as --warn; checked first"},
+			{Name: "change", Type: "boolean", Required: false, Default: false, Description: "Apply the thresholds to the percent change from the second-to-last bucket to the last (a rise from 0 is infinite)"},
+			{Name: "absent", Type: "string", Required: false, Default: "unknown", Enum: check.States, Description: "State when the query returns no value"},
+			{Name: "label", Type: "string", Required: false, Default: "logbasset", Description: "Name of the check, upper-cased in the status line and used as the performance data label"},
+			{Name: "every", Type: "string", Required: false, Description: "Rerun the check at this interval (at least 10s) until interrupted, printing a line only when the state changes; failed queries are UNKNOWN states and exit 0 on interrupt"},
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "text is the Nagios status line, e.g. 'ERRORS CRITICAL - value 57 > 50 | errors=57;10;50'; json one object per report. Not switched to json when piped"},
+		},
+		OutputKeys: []string{"label", "state", "code", "value", "message", "time"},
+		Examples: []string{
+			"logbasset check --filter 'severity >= 5' --function count --start 10m --warn 10 --crit 50 --label errors",
+			"logbasset check --filter '$source=\"heartbeat\"' --function count --start 5m --crit '<1' --absent critical",
+			"logbasset check --filter 'severity >= 5' --function count --start 20m --buckets 2 --change --warn 50 --crit 200 --every 1m",
+		},
+	},
+	"files ls": {
+		Command:  "files ls",
+		ReadOnly: true,
+		Args: []paramSchema{
+			{Name: "prefix", Type: "string", Required: false, Description: "Only list paths starting with this prefix (e.g., /scalyr/parsers)"},
+		},
+		Flags: []paramSchema{
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Output format"},
+		},
+		OutputKeys: []string{"paths"},
+		Examples: []string{
+			"logbasset files ls /scalyr/parsers --output json",
+		},
+	},
+	"files get": {
+		Command:  "files get",
+		ReadOnly: true,
+		Args: []paramSchema{
+			{Name: "path", Type: "string", Required: true, Description: "Configuration file path (e.g., /scalyr/parsers/app)"},
+		},
+		Flags: []paramSchema{
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "text prints the raw content; json adds the version and dates"},
+		},
+		OutputKeys: []string{"path", "version", "createDate", "modDate", "content"},
+		Examples: []string{
+			"logbasset files get /scalyr/parsers/app",
+			"logbasset files get /dashboards/errors --output json",
+		},
+	},
+	"files put": {
+		Command:  "files put",
+		ReadOnly: false,
+		Args: []paramSchema{
+			{Name: "path", Type: "string", Required: true, Description: "Configuration file path"},
+			{Name: "file", Type: "string", Required: false, Description: "Local file with the new content; stdin when omitted or '-'"},
+		},
+		Flags: []paramSchema{
+			{Name: "expected-version", Type: "integer", Required: false, Default: 0, Description: "Fail if the remote file is no longer at this version (0 skips the check)"},
+			{Name: "delete", Type: "boolean", Required: false, Default: false, Description: "Delete the file instead of writing it"},
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Output format"},
+		},
+		OutputKeys: []string{"path", "action"},
+		Examples: []string{
+			"logbasset files put /scalyr/parsers/app parsers/app --expected-version 7",
+		},
+	},
+	"files diff": {
+		Command:  "files diff",
+		ReadOnly: true,
+		Args: []paramSchema{
+			{Name: "path", Type: "string", Required: false, Description: "Remote paths to compare (repeatable); default every local and previously synced file"},
+		},
+		Flags: []paramSchema{
+			{Name: "dir", Type: "string", Required: false, Default: ".", Description: "Directory holding the local copies"},
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "text prints unified diffs; json lists each file's status"},
+		},
+		OutputKeys: []string{"path", "status", "diff"},
+		Examples: []string{
+			"logbasset files diff --dir scalyr-config",
+		},
+	},
+	"files pull": {
+		Command:  "files pull",
+		ReadOnly: false,
+		Args: []paramSchema{
+			{Name: "prefix", Type: "string", Required: false, Description: "Only pull paths starting with these prefixes (repeatable)"},
+		},
+		Flags: []paramSchema{
+			{Name: "dir", Type: "string", Required: false, Default: ".", Description: "Directory to download into; versions are recorded in " + filesync.ManifestName},
+			{Name: "force", Type: "boolean", Required: false, Default: false, Description: "Overwrite local files that were edited since the last pull"},
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Output format"},
+		},
+		OutputKeys: []string{"path", "action", "version"},
+		Examples: []string{
+			"logbasset files pull /scalyr/parsers /dashboards --dir scalyr-config",
+		},
+	},
+	"files push": {
+		Command:  "files push",
+		ReadOnly: false,
+		Args: []paramSchema{
+			{Name: "path", Type: "string", Required: false, Description: "Remote paths to push (repeatable); default every changed file under --dir"},
+		},
+		Flags: []paramSchema{
+			{Name: "dir", Type: "string", Required: false, Default: ".", Description: "Directory to upload from"},
+			{Name: "dry-run", Type: "boolean", Required: false, Default: false, Description: "Show what would be uploaded without writing anything"},
+			{Name: "force", Type: "boolean", Required: false, Default: false, Description: "Overwrite remote files that exist but were never pulled"},
+			{Name: "create", Type: "boolean", Required: false, Default: false, Description: "Create remote files for local files that were never pulled; without it they are reported as 'untracked' unless named as paths"},
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Output format"},
+		},
+		OutputKeys: []string{"path", "action", "version"},
+		Examples: []string{
+			"logbasset files push --dir scalyr-config --dry-run",
+			"logbasset files push --dir scalyr-config",
+		},
+	},
+	"config init": {
+		Command:  "config init",
+		ReadOnly: false,
+		Flags: []paramSchema{
+			{Name: "file", Type: "string", Required: false, Description: "Config file to create (default ~/.config/logbasset/logbasset.yaml)"},
+			{Name: "force", Type: "boolean", Required: false, Default: false, Description: "Overwrite an existing config file"},
+			{Name: "non-interactive", Type: "boolean", Required: false, Default: false, Description: "Do not prompt; write the --server/--token/--priority flag and environment values"},
+		},
+		Examples: []string{
+			"logbasset config init --non-interactive --token \"$SCALYR_TOKEN\" --server https://eu.scalyr.com",
+		},
+	},
+	"config get": {
+		Command:  "config get",
+		ReadOnly: true,
+		Args: []paramSchema{
+			{Name: "key", Type: "string", Required: true, Description: "Setting (server, token, token_command, credential_store, verbose, priority, log_level, timeout, profile) or profiles.<name>.<setting>"},
+		},
+		Flags: []paramSchema{},
+		Examples: []string{
+			"logbasset config get server",
+			"logbasset config get profiles.eu.server",
+		},
+	},
+	"config set": {
+		Command:  "config set",
+		ReadOnly: false,
+		Args: []paramSchema{
+			{Name: "key", Type: "string", Required: true, Description: "Setting (server, token, token_command, credential_store, verbose, priority, log_level, timeout, profile) or profiles.<name>.<setting>"},
+			{Name: "value", Type: "string", Required: true, Description: "Value to write; validated before the file is changed"},
+		},
+		Flags: []paramSchema{
+			{Name: "file", Type: "string", Required: false, Description: "Config file to edit (default: the loaded file, else ~/.config/logbasset/logbasset.yaml)"},
+		},
+		Examples: []string{
+			"logbasset config set priority low",
+			"logbasset config set profiles.eu.server https://eu.scalyr.com",
+		},
+	},
+	"config unset": {
+		Command:  "config unset",
+		ReadOnly: false,
+		Args: []paramSchema{
+			{Name: "key", Type: "string", Required: true, Description: "Setting or profiles.<name>.<setting> to remove"},
+		},
+		Flags: []paramSchema{
+			{Name: "file", Type: "string", Required: false, Description: "Config file to edit (default: the loaded file)"},
+		},
+		Examples: []string{
+			"logbasset config unset timeout",
+		},
+	},
+	"config list": {
+		Command:  "config list",
+		ReadOnly: true,
+		Flags: []paramSchema{
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Output format"},
+		},
+		OutputKeys: []string{"key", "value", "source"},
+		Examples: []string{
+			"logbasset config list --output json",
+		},
+	},
+	"config validate": {
+		Command:  "config validate",
+		ReadOnly: true,
+		Flags:    []paramSchema{},
+		Examples: []string{
+			"logbasset config validate",
+		},
+	},
+	"config path": {
+		Command:  "config path",
+		ReadOnly: true,
+		Flags:    []paramSchema{},
+		Examples: []string{
+			"logbasset config path",
+		},
+	},
+	"config profiles": {
+		Command:  "config profiles",
+		ReadOnly: true,
+		Flags: []paramSchema{
+			{Name: "output", Type: "string", Required: false, Default: "text", Enum: []string{"text", "json"}, Description: "Output format"},
+		},
+		OutputKeys: []string{"name", "active", "server", "priority", "timeout", "has_token"},
+		Examples: []string{
+			"logbasset config profiles --output json",
+		},
+	},
+	"config credentials set": {
+		Command:  "config credentials set",
+		ReadOnly: false,
+		Flags: []paramSchema{
+			{Name: "name", Type: "string", Required: false, Description: "Credential name (default: the active profile, or \"default\"); the token is read from stdin"},
+		},
+		Examples: []string{
+			"pass show scalyr/eu | logbasset config credentials set --name eu",
+		},
+	},
+	"config credentials delete": {
+		Command:  "config credentials delete",
+		ReadOnly: false,
+		Flags: []paramSchema{
+			{Name: "name", Type: "string", Required: false, Description: "Credential name (default: the active profile, or \"default\")"},
+		},
+		Examples: []string{
+			"logbasset config credentials delete --name eu",
 		},
 	},
 	"global": {
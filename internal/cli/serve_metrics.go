@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/logging"
+	"github.com/andreagrandi/logbasset/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var serveMetricsCmd = &cobra.Command{
+	Use:   "serve-metrics",
+	Short: "Export query results as Prometheus metrics",
+	Long: `Serve-metrics runs the timeseries and numeric queries listed in a metrics config
+file and exposes their values on /metrics in the Prometheus text format, so
+Scalyr-derived signals can be scraped and graphed like any other target.
+
+Queries run at startup and again every cache period of the file (default 1m),
+whether or not anything scrapes; scrapes are answered from the last values.`,
+	Args: cobra.NoArgs,
+	Run:  runServeMetrics,
+}
+
+var (
+	serveMetricsConfig string
+	serveMetricsListen string
+)
+
+func init() {
+	serveMetricsCmd.Flags().StringVar(&serveMetricsConfig, "config", "", "Metrics config file listing the queries to export (required)")
+	serveMetricsCmd.Flags().StringVar(&serveMetricsListen, "listen", ":9464", "Address to serve /metrics on")
+	serveMetricsCmd.MarkFlagRequired("config")
+}
+
+func runServeMetrics(cmd *cobra.Command, args []string) {
+	conf, err := metrics.Load(serveMetricsConfig)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	exporter := metrics.NewExporter(getConfig().GetClient(), conf, getConfig().Priority, getTimeout())
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	server := &http.Server{Addr: serveMetricsListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	// Set up signal handling for graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go exporter.Run(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logging.Infof("Serving %d metrics on %s/metrics", len(conf.Metrics), serveMetricsListen)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		errors.HandleErrorAndExit(errors.NewNetworkError("failed to serve metrics on "+serveMetricsListen, err))
+	}
+}
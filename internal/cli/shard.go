@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/timerange"
+	"github.com/andreagrandi/logbasset/internal/validation"
+)
+
+// resolveShards validates --split/--parallel and cuts the query's time range
+// into the windows that are queried separately.
+func resolveShards(start, end, split string, parallel int) []timerange.Window {
+	validationConfig := validation.DefaultConfig()
+
+	if err := validation.ValidateRequiredField("start", start); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	if err := validation.ValidateSplit(split); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	if err := validation.ValidateParallel(parallel, validationConfig.MaxParallel); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	step, _ := timerange.ParseDuration(split)
+	window, err := timerange.ResolveRange(start, end, time.Now())
+	if err != nil {
+		errors.HandleErrorAndExit(errors.NewValidationError("invalid time range for --split", err))
+	}
+
+	if err := validation.ValidateShardCount(timerange.Count(window, step), validationConfig.MaxShards); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
+	return timerange.Split(window, step)
+}
+
+// shardOptions applies --timeout to each window rather than to the whole
+// sharded query, which may legitimately run for much longer.
+func shardOptions(parallel int) client.ShardOptions {
+	return client.ShardOptions{Parallel: parallel, Timeout: getTimeout()}
+}
+
+// commandContext returns the context a query command runs under. Sharded,
+// paged and long-running queries are only cancellable here; a sharded
+// query's timeout is applied per window, a paged query's per page, and a
+// long-running query runs until it completes.
+func commandContext(unbounded bool) (context.Context, context.CancelFunc) {
+	if unbounded {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), getTimeout())
+}
@@ -1,16 +1,23 @@
 package cli
 
 import (
+	"container/heap"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
+	"unicode"
 
 	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
+	"github.com/andreagrandi/logbasset/internal/timerange"
 	"github.com/andreagrandi/logbasset/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -19,19 +26,58 @@ var tailCmd = &cobra.Command{
 	Use:   "tail [filter]",
 	Short: "Provide a live 'tail' of a log",
 	Long: `Tail is similar to the query command, except it runs continually, printing query results to stdout.
-It provides a live tail of log records matching the specified filter.`,
+It provides a live tail of log records matching the specified filter.
+
+Several filters can be followed at once with repeated --filter name=expression flags; each
+record is prefixed with the name of the filter it matched. Each round of polls is printed once
+every filter has answered, merged in timestamp order, so a filter that is reconnecting holds
+back the others until it is back.
+
+Transient network and server failures are retried indefinitely. With --checkpoint, the position
+reached is saved after every batch of records, and a restarted tail resumes from it.`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  runTail,
 }
 
 var (
-	tailLines  int
-	tailOutput string
+	tailLines       int
+	tailOutput      string
+	tailCheckpoint  string
+	tailInterval    time.Duration
+	tailMaxInterval time.Duration
+	tailPageSize    int
+	tailFilters     []string
+
+	tailTemplate     string
+	tailTemplateFile string
+	tailFlatten      bool
+	tailAttrOrder    string
+
+	tailOut         string
+	tailRotateSize  string
+	tailRotateEvery string
+	tailCompress    string
+	tailRetain      int
 )
 
 func init() {
 	tailCmd.Flags().IntVarP(&tailLines, "lines", "n", 10, "Output the previous K lines when starting the tail")
-	tailCmd.Flags().StringVar(&tailOutput, "output", "messageonly", "Output format: multiline|singleline|compact|messageonly|json")
+	tailCmd.Flags().StringVar(&tailOutput, "output", "messageonly", outputFlagUsage())
+	tailCmd.Flags().DurationVar(&tailInterval, "interval", client.DefaultTailInterval, "Delay between polls for new records")
+	tailCmd.Flags().DurationVar(&tailMaxInterval, "max-interval", 0, "Enable adaptive polling: fetch full pages back to back and back off up to this delay when idle")
+	tailCmd.Flags().IntVar(&tailPageSize, "page-size", client.DefaultTailPageSize, "Maximum records fetched per poll (1-5000)")
+	tailCmd.Flags().StringArrayVar(&tailFilters, "filter", nil, "Tail several filters at once as name=expression, labelling each record with its name (repeatable)")
+	tailCmd.Flags().StringVar(&tailTemplate, "template", "", "Go text/template rendering each record, e.g. '{{.Timestamp | time \"15:04:05\"}} {{.Source}} {{.Message}}'")
+	tailCmd.Flags().StringVar(&tailTemplateFile, "template-file", "", "File containing a --template")
+	tailCmd.Flags().BoolVar(&tailFlatten, "flatten", false, flattenFlagUsage)
+	tailCmd.Flags().StringVar(&tailAttrOrder, "attr-order", "", attrOrderFlagUsage)
+	tailCmd.MarkFlagsMutuallyExclusive("template", "template-file", "output")
+	tailCmd.Flags().StringVar(&tailCheckpoint, "checkpoint", "", "File that records the tail position; an existing checkpoint is resumed instead of printing --lines")
+	tailCmd.Flags().StringVar(&tailOut, "out", "", outFlagUsage)
+	tailCmd.Flags().StringVar(&tailRotateSize, "rotate-size", "", "Rotate the --out file once it reaches this size (e.g. 100MB)")
+	tailCmd.Flags().StringVar(&tailRotateEvery, "rotate-every", "", "Rotate the --out file at every multiple of this interval (e.g. 1h, 1d)")
+	tailCmd.Flags().StringVar(&tailCompress, "compress", "", "Compress rotated files: "+strings.Join(output.Compressions, "|"))
+	tailCmd.Flags().IntVar(&tailRetain, "retain", 0, "Number of rotated files to keep, deleting the oldest (0 keeps all)")
 }
 
 func runTail(cmd *cobra.Command, args []string) {
@@ -40,26 +86,59 @@ func runTail(cmd *cobra.Command, args []string) {
 		filter = args[0]
 	}
 
+	sources, err := parseTailSources(filter, tailFilters)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+
 	// Validate inputs
 	validationConfig := validation.DefaultConfig()
-	params := validation.QueryValidationParams{
-		Output:        tailOutput,
-		Priority:      getConfig().Priority,
-		Query:         filter,
-		Lines:         tailLines,
-		ValidateLines: true,
+	for _, source := range sources {
+		params := validation.QueryValidationParams{
+			Output:        tailOutput,
+			Priority:      getConfig().Priority,
+			Query:         source.filter,
+			Lines:         tailLines,
+			ValidateLines: true,
+		}
+		if err := validation.ValidateQueryParams(params, validationConfig); err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+	}
+	if err := validation.ValidateTailPolling(tailInterval, tailMaxInterval, validationConfig.MinTailInterval); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	if err := validation.ValidatePageSize(tailPageSize, validationConfig.MaxCount); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	if tailCheckpoint != "" && len(sources) > 1 {
+		errors.HandleErrorAndExit(errors.NewValidationError("--checkpoint cannot be combined with more than one --filter", nil))
+	}
+	if err := validation.ValidateFields(tailAttrOrder); err != nil {
+		errors.HandleErrorAndExit(err)
+	}
+	rotate, err := tailRotateOptions(validationConfig)
+	if err != nil {
+		errors.HandleErrorAndExit(err)
 	}
 
-	if err := validation.ValidateQueryParams(params, validationConfig); err != nil {
+	var highlight []string
+	for _, source := range sources {
+		highlight = append(highlight, output.FilterTerms(source.filter)...)
+	}
+	tmpl, err := parseTemplateFlags(tailTemplate, tailTemplateFile, tailOut, highlight)
+	if err != nil {
 		errors.HandleErrorAndExit(err)
 	}
 
 	c := getConfig().GetClient()
 
-	clientParams := client.TailParams{
-		Filter:   filter,
-		Lines:    tailLines,
-		Priority: getConfig().Priority,
+	var resume *client.TailCheckpoint
+	if tailCheckpoint != "" {
+		resume, err = loadTailCheckpoint(tailCheckpoint)
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
 	}
 
 	// Create context that can be cancelled by user signals
@@ -75,81 +154,343 @@ func runTail(cmd *cobra.Command, args []string) {
 		cancel()
 	}()
 
-	eventChan := make(chan client.LogEvent)
-
-	go func() {
-		if err := c.Tail(ctx, clientParams, eventChan); err != nil {
-			// Only report error if it's not due to cancellation
-			if ctx.Err() == nil {
-				errors.HandleErrorAndExit(err)
-			}
+	rounds := make([]<-chan tailItem, len(sources))
+	for i, source := range sources {
+		clientParams := client.TailParams{
+			Filter:      source.filter,
+			Lines:       tailLines,
+			Priority:    getConfig().Priority,
+			Interval:    tailInterval,
+			MaxInterval: tailMaxInterval,
+			PageSize:    tailPageSize,
 		}
-	}()
+		if tailCheckpoint != "" {
+			clientParams.Resume = resume
+		}
+
+		items := make(chan tailItem)
+		rounds[i] = items
+		go runTailSource(ctx, c, source.label, clientParams, items)
+	}
 
 	if !cmd.Flags().Changed("output") && !IsTTY() {
 		tailOutput = "json"
 		errors.OutputJSON = true
 	}
 
-	for event := range eventChan {
-		switch tailOutput {
-		case "multiline":
-			outputTailMultiLine(event)
-		case "singleline":
-			outputTailSingleLine(event)
-		case "compact":
-			outputTailCompact(event)
-		case "json":
-			outputTailJSON(event)
-		default:
-			outputTailMessageOnly(event)
+	var labels []string
+	for _, source := range sources {
+		if source.label != "" {
+			labels = append(labels, source.label)
 		}
 	}
+
+	opts := output.Options{Flatten: tailFlatten, AttrOrder: splitFields(tailAttrOrder)}
+	var f output.Formatter
+	if rotate != nil {
+		f = output.NewRotating(tailOut, *rotate, eventFormatterFactory(tmpl, tailOutput, opts))
+	} else {
+		opts.Highlight = highlight
+		f = newEventFormatter(tmpl, tailOutput, tailOut, opts)
+	}
+	writeTail(f, labels, rounds)
 }
 
-func outputTailJSON(event client.LogEvent) {
-	data, err := json.Marshal(event)
-	if err != nil {
-		return
+// writeTail writes the tailed events to f until every source has ended. Each
+// source ends a poll with a checkpoint, and the events of a poll round are
+// held until every source has finished its poll, then written merged in
+// timestamp order; a source that finishes first is not read from again, and
+// so waits, until then. Checkpoints are saved with --checkpoint once the
+// events before them have been written. A failed filter ends the output
+// before its error is reported.
+func writeTail(f output.Formatter, labels []string, sources []<-chan tailItem) {
+	exitOnOutputError(f.Begin(output.Header{Events: true, Follow: true, Sources: labels}))
+
+	sources = slices.Clone(sources)
+	for live := len(sources); live > 0; {
+		batches := make([][]tailItem, len(sources))
+		var checkpoints []client.TailCheckpoint
+		var failed error
+		for i, items := range sources {
+			if items == nil {
+				continue
+			}
+			for failed == nil {
+				item, ok := <-items
+				if !ok {
+					sources[i] = nil
+					live--
+					break
+				}
+				if item.err != nil {
+					failed = item.err
+					break
+				}
+				if item.checkpoint != nil {
+					checkpoints = append(checkpoints, *item.checkpoint)
+					break
+				}
+				batches[i] = append(batches[i], item)
+			}
+		}
+
+		for _, item := range mergeTailRound(batches) {
+			exitOnOutputError(f.Write(output.Record{Source: item.source, Event: item.event}))
+		}
+		if failed != nil {
+			// End the output first, so buffered formats and the --out file
+			// are complete when the failure is reported
+			_ = f.End()
+			errors.HandleErrorAndExit(failed)
+		}
+		if tailCheckpoint != "" {
+			for _, cp := range checkpoints {
+				if err := saveTailCheckpoint(tailCheckpoint, cp); err != nil {
+					errors.HandleErrorAndExit(err)
+				}
+			}
+		}
 	}
-	fmt.Println(string(data))
+	exitOnOutputError(f.End())
 }
 
-func outputTailMessageOnly(event client.LogEvent) {
-	fmt.Println(event.Message)
+// mergeTailRound merges the events each source returned in one poll round,
+// each already in timestamp order, into a single list in timestamp order.
+// Events with the same timestamp keep the order of their sources.
+func mergeTailRound(batches [][]tailItem) []tailItem {
+	var merged []tailItem
+	h := &tailHeap{}
+	for i, batch := range batches {
+		if len(batch) > 0 {
+			*h = append(*h, tailBatch{source: i, items: batch})
+			merged = slices.Grow(merged, len(batch))
+		}
+	}
+	heap.Init(h)
+	for h.Len() > 0 {
+		next := &(*h)[0]
+		merged = append(merged, next.items[0])
+		if next.items = next.items[1:]; len(next.items) == 0 {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return merged
 }
 
-func outputTailCompact(event client.LogEvent) {
-	fmt.Printf("%s %s %s\n", formatCompactTimestamp(event.Timestamp), severityChar(event.Severity), event.Message)
+// tailBatch is what remains of one source's events in a poll round.
+type tailBatch struct {
+	source int
+	items  []tailItem
 }
 
-func outputTailSingleLine(event client.LogEvent) {
-	fmt.Printf("%s [%d] %s", event.Timestamp, event.Severity, event.Message)
-	if event.Thread != "" {
-		fmt.Printf(" (thread: %s)", event.Thread)
+// tailHeap orders the batches of a poll round by their next event's
+// timestamp, implementing heap.Interface.
+type tailHeap []tailBatch
+
+func (h tailHeap) Len() int { return len(h) }
+
+func (h tailHeap) Less(i, j int) bool {
+	ti, _ := output.ParseTimestamp(h[i].items[0].event.Timestamp)
+	tj, _ := output.ParseTimestamp(h[j].items[0].event.Timestamp)
+	if !ti.Equal(tj) {
+		return ti.Before(tj)
 	}
-	if len(event.Attributes) > 0 {
-		attrs := make([]string, 0, len(event.Attributes))
-		for k, v := range event.Attributes {
-			attrs = append(attrs, fmt.Sprintf("%s=%v", k, v))
+	return h[i].source < h[j].source
+}
+
+func (h tailHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *tailHeap) Push(x any) { *h = append(*h, x.(tailBatch)) }
+
+func (h *tailHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// tailRotateOptions returns how tail rotates its --out file, or nil when
+// neither --rotate-size nor --rotate-every is set.
+func tailRotateOptions(config *validation.ValidationConfig) (*output.RotateOptions, error) {
+	if tailRotateSize == "" && tailRotateEvery == "" {
+		if tailCompress != "" || tailRetain != 0 {
+			return nil, errors.NewValidationError("--compress and --retain apply to rotated files and need --rotate-size or --rotate-every", nil)
+		}
+		return nil, nil
+	}
+	if tailOut == "" {
+		return nil, errors.NewValidationError("--rotate-size and --rotate-every need --out", nil)
+	}
+
+	var opts output.RotateOptions
+	if tailRotateSize != "" {
+		size, err := validation.ParseSize(tailRotateSize)
+		if err != nil {
+			return nil, err
+		}
+		opts.Size = size
+	}
+	if tailRotateEvery != "" {
+		if err := validation.ValidateRotateEvery(tailRotateEvery); err != nil {
+			return nil, err
+		}
+		opts.Every, _ = timerange.ParseDuration(tailRotateEvery)
+	}
+	if err := validation.ValidateCompression(tailCompress, config.ValidCompressions); err != nil {
+		return nil, err
+	}
+	if err := validation.ValidateRetain(tailRetain); err != nil {
+		return nil, err
+	}
+	opts.Compress = tailCompress
+	opts.Retain = tailRetain
+	return &opts, nil
+}
+
+// tailSource is one filter being tailed. The label is empty for the
+// positional filter and names a --filter otherwise.
+type tailSource struct {
+	label  string
+	filter string
+}
+
+// tailItem is what the tail goroutines send to the output loop: an event, the
+// checkpoint that ends a poll and is saved once the events before it have
+// been printed, or the error that ended a filter's tail.
+type tailItem struct {
+	source     string
+	event      *client.LogEvent
+	checkpoint *client.TailCheckpoint
+	err        error
+}
+
+// parseTailSources combines the positional filter and repeated --filter
+// name=expr values into the list of filters to tail.
+func parseTailSources(filter string, labeled []string) ([]tailSource, error) {
+	if len(labeled) == 0 {
+		return []tailSource{{filter: filter}}, nil
+	}
+	if filter != "" {
+		return nil, errors.NewValidationError("pass either a filter argument or --filter, not both", nil)
+	}
+
+	sources := make([]tailSource, 0, len(labeled))
+	seen := make(map[string]bool)
+	for _, value := range labeled {
+		label, expr, ok := strings.Cut(value, "=")
+		label = strings.TrimSpace(label)
+		if !ok || !validLabel(label) {
+			return nil, errors.NewValidationError(
+				"invalid --filter "+value,
+				fmt.Errorf("use name=expression, where name contains only letters, digits, '-', '_' or '.'"),
+			)
+		}
+		if seen[label] {
+			return nil, errors.NewValidationError("duplicate --filter name "+label, nil)
+		}
+		seen[label] = true
+		sources = append(sources, tailSource{label: label, filter: expr})
+	}
+	return sources, nil
+}
+
+// validLabel reports whether label can name a tail --filter or a
+// timeseries-query --query: letters, digits, '-', '_' and '.' only.
+func validLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+	for _, r := range label {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' {
+			return false
 		}
-		fmt.Printf(" [%s]", strings.Join(attrs, ", "))
 	}
-	fmt.Println()
+	return true
 }
 
-func outputTailMultiLine(event client.LogEvent) {
-	fmt.Printf("Timestamp: %s\n", event.Timestamp)
-	fmt.Printf("Severity: %d\n", event.Severity)
-	fmt.Printf("Message: %s\n", event.Message)
-	if event.Thread != "" {
-		fmt.Printf("Thread: %s\n", event.Thread)
+// runTailSource tails one filter, forwarding its events and the checkpoint
+// ending each poll to items in the order they happened, followed by the error
+// that ended it unless ctx was cancelled. It closes items when done.
+func runTailSource(ctx context.Context, c client.ClientInterface, label string, params client.TailParams, items chan<- tailItem) {
+	defer close(items)
+
+	events := make(chan client.LogEvent)
+	checkpointChan := make(chan client.TailCheckpoint)
+	params.OnCheckpoint = func(cp client.TailCheckpoint) {
+		checkpointChan <- cp
 	}
-	if len(event.Attributes) > 0 {
-		fmt.Println("Attributes:")
-		for k, v := range event.Attributes {
-			fmt.Printf("  %s: %v\n", k, v)
+
+	tailErr := make(chan error, 1)
+	go func() {
+		tailErr <- c.Tail(ctx, params, events)
+	}()
+
+	// Tail only reports a checkpoint after this loop has taken the page's
+	// last event, and this loop hands each item on before taking the next,
+	// so a checkpoint never overtakes the events it covers.
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// Only report an error if it's not due to cancellation
+				if err := <-tailErr; err != nil && ctx.Err() == nil {
+					if lbErr, ok := err.(*errors.LogBassetError); ok && label != "" {
+						lbErr.Message = "filter " + label + ": " + lbErr.Message
+					}
+					items <- tailItem{source: label, err: err}
+				}
+				return
+			}
+			items <- tailItem{source: label, event: &event}
+		case cp := <-checkpointChan:
+			items <- tailItem{source: label, checkpoint: &cp}
 		}
 	}
-	fmt.Println("---")
+}
+
+// loadTailCheckpoint reads a --checkpoint file, returning nil when it does not
+// exist yet.
+func loadTailCheckpoint(path string) (*client.TailCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.NewConfigError("failed to read checkpoint "+path, err)
+	}
+	var cp client.TailCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, errors.NewParseError("failed to parse checkpoint "+path, err)
+	}
+	if cp.ContinuationToken == "" {
+		return nil, errors.NewValidationError("checkpoint "+path+" has no continuationToken", nil)
+	}
+	return &cp, nil
+}
+
+// saveTailCheckpoint replaces the --checkpoint file through a rename, so an
+// interrupted write never leaves a truncated checkpoint behind.
+func saveTailCheckpoint(path string, cp client.TailCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return errors.NewParseError("failed to encode checkpoint", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return errors.NewConfigError("failed to write checkpoint "+path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return errors.NewConfigError("failed to write checkpoint "+path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.NewConfigError("failed to write checkpoint "+path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.NewConfigError("failed to write checkpoint "+path, err)
+	}
+	return nil
 }
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/output"
+	"github.com/andreagrandi/logbasset/internal/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tail.checkpoint")
+
+	cp, err := loadTailCheckpoint(path)
+	require.NoError(t, err)
+	assert.Nil(t, cp, "a missing checkpoint starts a new tail")
+
+	saved := client.TailCheckpoint{ContinuationToken: "token-1", LastTimestamp: "1700000000000000000"}
+	require.NoError(t, saveTailCheckpoint(path, saved))
+	require.NoError(t, saveTailCheckpoint(path, client.TailCheckpoint{ContinuationToken: "token-2"}))
+
+	cp, err = loadTailCheckpoint(path)
+	require.NoError(t, err)
+	assert.Equal(t, &client.TailCheckpoint{ContinuationToken: "token-2"}, cp)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files are left behind")
+}
+
+func TestLoadTailCheckpoint_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	garbled := filepath.Join(dir, "garbled")
+	require.NoError(t, os.WriteFile(garbled, []byte("{"), 0644))
+	_, err := loadTailCheckpoint(garbled)
+	assert.Error(t, err)
+
+	empty := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(empty, []byte(`{"lastTimestamp":"1"}`), 0644))
+	_, err = loadTailCheckpoint(empty)
+	assert.Error(t, err)
+}
+
+func TestParseTailSources(t *testing.T) {
+	sources, err := parseTailSources("error", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []tailSource{{filter: "error"}}, sources)
+
+	sources, err = parseTailSources("", []string{"api=$serverHost=\"api-1\"", "db=severity >= 5"})
+	require.NoError(t, err)
+	assert.Equal(t, []tailSource{
+		{label: "api", filter: "$serverHost=\"api-1\""},
+		{label: "db", filter: "severity >= 5"},
+	}, sources)
+
+	for _, bad := range [][]string{{"no-equals"}, {"=expr"}, {"bad label=x"}, {"a=x", "a=y"}} {
+		_, err := parseTailSources("", bad)
+		assert.Error(t, err, "%v", bad)
+	}
+
+	_, err = parseTailSources("error", []string{"api=x"})
+	assert.Error(t, err, "a positional filter and --filter are exclusive")
+}
+
+func TestTailRotateOptions(t *testing.T) {
+	defer func() {
+		tailOut, tailRotateSize, tailRotateEvery, tailCompress, tailRetain = "", "", "", "", 0
+	}()
+	config := validation.DefaultConfig()
+
+	tailOut, tailRotateSize, tailRotateEvery, tailCompress, tailRetain = "errors.ndjson", "", "", "", 0
+	opts, err := tailRotateOptions(config)
+	require.NoError(t, err)
+	assert.Nil(t, opts, "--out alone does not rotate")
+
+	tailRotateSize, tailRotateEvery, tailCompress, tailRetain = "100MB", "1h", "zstd", 24
+	opts, err = tailRotateOptions(config)
+	require.NoError(t, err)
+	assert.Equal(t, &output.RotateOptions{Size: 100 << 20, Every: time.Hour, Compress: "zstd", Retain: 24}, opts)
+
+	tailOut = ""
+	_, err = tailRotateOptions(config)
+	assert.Error(t, err, "rotation needs --out")
+
+	tailOut, tailRotateSize, tailRotateEvery = "errors.ndjson", "", ""
+	_, err = tailRotateOptions(config)
+	assert.Error(t, err, "--compress and --retain need rotation")
+
+	tailRotateSize, tailCompress = "lots", ""
+	_, err = tailRotateOptions(config)
+	assert.Error(t, err)
+}
+
+func TestRunTailSource_ForwardsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"status":"error/client/badParam","message":"bad filter"}`)
+	}))
+	defer server.Close()
+	c := client.New("test-token", server.URL, false)
+
+	items := make(chan tailItem)
+	go runTailSource(context.Background(), c, "api", client.TailParams{Lines: 10}, items)
+
+	select {
+	case item := <-items:
+		require.Error(t, item.err, "the error is handed to the output loop, which ends the formatter")
+		assert.Equal(t, "api", item.source)
+		assert.Contains(t, item.err.Error(), "filter api: ")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the tail error was not forwarded")
+	}
+}
+
+// writeTailItems writes items through writeTail with the formatter tail builds
+// for format on stdout, and returns what was written. Each source's items are
+// sent on their own channel, in order.
+func writeTailItems(t *testing.T, format string, labels []string, items ...tailItem) string {
+	t.Helper()
+	return captureStdout(t, func() {
+		var sources []<-chan tailItem
+		channels := make(map[string]chan tailItem)
+		for _, item := range items {
+			ch, ok := channels[item.source]
+			if !ok {
+				ch = make(chan tailItem, len(items))
+				channels[item.source] = ch
+				sources = append(sources, ch)
+			}
+			ch <- item
+		}
+		for _, ch := range channels {
+			close(ch)
+		}
+		writeTail(newEventFormatter(nil, format, "", output.Options{}), labels, sources)
+	})
+}
+
+func TestOutputTailCompact(t *testing.T) {
+	event := client.LogEvent{
+		Timestamp: "1700000000000000000",
+		Severity:  4,
+		Message:   "warning ahead",
+	}
+
+	out := writeTailItems(t, "compact", nil, tailItem{event: &event})
+	assert.Equal(t, "22:13:20 W warning ahead\n", out)
+}
+
+func TestOutputTailLabels(t *testing.T) {
+	event := client.LogEvent{Timestamp: "1", Severity: 3, Message: "hello"}
+
+	out := writeTailItems(t, "messageonly", []string{"api", "worker"},
+		tailItem{source: "api", event: &event},
+		tailItem{source: "worker", event: &event})
+	assert.Equal(t, "[api]    hello\n[worker] hello\n", out)
+
+	out = writeTailItems(t, "json", []string{"api"}, tailItem{source: "api", event: &event})
+	assert.Equal(t, `{"source":"api","timestamp":"1","severity":3,"message":"hello"}`+"\n", out)
+}
+
+func TestWriteTail_MergesRoundsByTimestamp(t *testing.T) {
+	event := func(source, ts string) tailItem {
+		return tailItem{source: source, event: &client.LogEvent{Timestamp: ts, Message: source + " " + ts}}
+	}
+	endOfPoll := func(source string) tailItem {
+		return tailItem{source: source, checkpoint: &client.TailCheckpoint{ContinuationToken: source}}
+	}
+
+	// Each filter's polls arrive as a whole, but their timestamps alternate,
+	// within a round and with a second round of api ahead of worker's.
+	out := writeTailItems(t, "messageonly", []string{"api", "worker"},
+		event("api", "1"), event("api", "3"), event("api", "5"), endOfPoll("api"),
+		event("api", "7"), endOfPoll("api"),
+		event("worker", "2"), event("worker", "4"), endOfPoll("worker"),
+		event("worker", "6"), event("worker", "8"), endOfPoll("worker"))
+	assert.Equal(t, "[api]    api 1\n"+
+		"[worker] worker 2\n"+
+		"[api]    api 3\n"+
+		"[worker] worker 4\n"+
+		"[api]    api 5\n"+
+		"[worker] worker 6\n"+
+		"[api]    api 7\n"+
+		"[worker] worker 8\n", out)
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"text/template"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
+)
+
+// parseTemplateFlags parses --template, or the contents of --template-file,
+// returning nil when neither is set. highlight lists the filter terms for
+// the template's highlight function. Colours only apply on stdout, so a
+// template written to an --out file is never coloured.
+func parseTemplateFlags(text, file, out string, highlight []string) (*template.Template, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.NewValidationError("cannot read "+file, err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return nil, nil
+	}
+	opts := output.Options{Highlight: highlight, Time: timeFormat()}
+	if out == "" {
+		opts = terminalOptions(opts)
+	}
+	return output.ParseTemplate(text, opts)
+}
+
+// newEventFormatter returns the formatter for the events of query and tail:
+// the template when there is one, otherwise the --output format. Both write
+// to the --out file when out is set.
+func newEventFormatter(tmpl *template.Template, format, out string, opts output.Options) output.Formatter {
+	switch {
+	case tmpl == nil:
+		return newFormatter(format, out, opts)
+	case out == "":
+		return output.NewTemplate(tmpl, os.Stdout)
+	}
+	file := createOut(out)
+	return &fileFormatter{Formatter: output.NewTemplate(tmpl, file), file: file}
+}
+
+// eventFormatterFactory returns a constructor of the formatter
+// newEventFormatter would use, writing to any writer, for the segments of a
+// rotating --out file. The format has been validated with the other flags.
+func eventFormatterFactory(tmpl *template.Template, format string, opts output.Options) func(w io.Writer) output.Formatter {
+	return func(w io.Writer) output.Formatter {
+		if tmpl != nil {
+			return output.NewTemplate(tmpl, w)
+		}
+		opts.Time = timeFormat()
+		f, err := output.New(format, w, opts)
+		if err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+		return f
+	}
+}
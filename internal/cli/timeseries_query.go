@@ -1,15 +1,20 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/output"
 	"github.com/andreagrandi/logbasset/internal/validation"
 	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
 )
 
 var timeseriesQueryCmd = &cobra.Command{
@@ -17,7 +22,10 @@ var timeseriesQueryCmd = &cobra.Command{
 	Short: "Retrieve numeric / graph data from a timeseries",
 	Long: `Timeseries-query precomputes a numeric query, allowing you to execute queries almost instantaneously,
 and without consuming your account's query budget. This is especially useful if you are using the Scalyr API
-to feed a home-built dashboard, alerting system, or other automated tool.`,
+to feed a home-built dashboard, alerting system, or other automated tool.
+
+Several queries can run in one request with repeated --query name:filter:function
+flags or a --queries-file, and every result is labelled with its name.`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  runTimeseriesQuery,
 }
@@ -28,8 +36,12 @@ var (
 	timeseriesQueryEndTime           string
 	timeseriesQueryBuckets           int
 	timeseriesQueryOutput            string
+	timeseriesQueryOut               string
+	timeseriesQueryLong              bool
 	timeseriesQueryOnlyUseSummaries  bool
 	timeseriesQueryNoCreateSummaries bool
+	timeseriesQueryQueries           []string
+	timeseriesQueryQueriesFile       string
 )
 
 func init() {
@@ -37,9 +49,13 @@ func init() {
 	timeseriesQueryCmd.Flags().StringVar(&timeseriesQueryStartTime, "start", "", "Start time for the query (required)")
 	timeseriesQueryCmd.Flags().StringVar(&timeseriesQueryEndTime, "end", "", "End time for the query")
 	timeseriesQueryCmd.Flags().IntVar(&timeseriesQueryBuckets, "buckets", 1, "Number of time buckets (1-5000)")
-	timeseriesQueryCmd.Flags().StringVar(&timeseriesQueryOutput, "output", "csv", "Output format: csv|json|json-pretty")
+	timeseriesQueryCmd.Flags().StringVar(&timeseriesQueryOutput, "output", "csv", outputFlagUsage())
+	timeseriesQueryCmd.Flags().StringVar(&timeseriesQueryOut, "out", "", outFlagUsage)
+	timeseriesQueryCmd.Flags().BoolVar(&timeseriesQueryLong, "long", false, longFlagUsage)
 	timeseriesQueryCmd.Flags().BoolVar(&timeseriesQueryOnlyUseSummaries, "only-use-summaries", false, "Only query summaries, not the column store")
 	timeseriesQueryCmd.Flags().BoolVar(&timeseriesQueryNoCreateSummaries, "no-create-summaries", false, "Don't create summaries for this query")
+	timeseriesQueryCmd.Flags().StringArrayVar(&timeseriesQueryQueries, "query", nil, "Named query as name:filter:function, run in the same request as the others (repeatable)")
+	timeseriesQueryCmd.Flags().StringVar(&timeseriesQueryQueriesFile, "queries-file", "", "YAML or JSON file listing named queries to run in one request")
 	timeseriesQueryCmd.MarkFlagRequired("start")
 }
 
@@ -49,40 +65,50 @@ func runTimeseriesQuery(cmd *cobra.Command, args []string) {
 		filter = args[0]
 	}
 
-	// Validate inputs
-	validationConfig := validation.DefaultConfig()
-	params := validation.QueryValidationParams{
-		StartTime:       timeseriesQueryStartTime,
-		EndTime:         timeseriesQueryEndTime,
-		Buckets:         timeseriesQueryBuckets,
-		Output:          timeseriesQueryOutput,
-		Priority:        getConfig().Priority,
-		Query:           filter,
-		ValidateBuckets: true,
-	}
-
-	if err := validation.ValidateQueryParams(params, validationConfig); err != nil {
+	// Validate required field
+	if err := validation.ValidateRequiredField("start", timeseriesQueryStartTime); err != nil {
 		errors.HandleErrorAndExit(err)
 	}
 
-	// Validate required field
-	if err := validation.ValidateRequiredField("start", timeseriesQueryStartTime); err != nil {
+	queries, err := parseTimeseriesQueries(filter, timeseriesQueryQueries, timeseriesQueryQueriesFile)
+	if err != nil {
 		errors.HandleErrorAndExit(err)
 	}
 
-	c := getConfig().GetClient()
+	// Validate inputs
+	validationConfig := validation.DefaultConfig()
+	series := make([]bucketSeries, len(queries))
+	clientParams := make([]client.TimeseriesQueryParams, len(queries))
+	for i, q := range queries {
+		params := validation.QueryValidationParams{
+			StartTime:       q.Start,
+			EndTime:         q.End,
+			Buckets:         q.Buckets,
+			Output:          timeseriesQueryOutput,
+			Priority:        getConfig().Priority,
+			Query:           q.Filter,
+			ValidateBuckets: true,
+		}
 
-	clientParams := client.TimeseriesQueryParams{
-		Filter:            filter,
-		Function:          timeseriesQueryFunction,
-		StartTime:         timeseriesQueryStartTime,
-		EndTime:           timeseriesQueryEndTime,
-		Buckets:           timeseriesQueryBuckets,
-		Priority:          getConfig().Priority,
-		OnlyUseSummaries:  timeseriesQueryOnlyUseSummaries,
-		NoCreateSummaries: timeseriesQueryNoCreateSummaries,
+		if err := validation.ValidateQueryParams(params, validationConfig); err != nil {
+			errors.HandleErrorAndExit(err)
+		}
+
+		series[i] = bucketSeries{name: q.Name, window: resolveBucketRange(q.Start, q.End)}
+		clientParams[i] = client.TimeseriesQueryParams{
+			Filter:            q.Filter,
+			Function:          q.Function,
+			StartTime:         q.Start,
+			EndTime:           q.End,
+			Buckets:           q.Buckets,
+			Priority:          getConfig().Priority,
+			OnlyUseSummaries:  timeseriesQueryOnlyUseSummaries,
+			NoCreateSummaries: timeseriesQueryNoCreateSummaries,
+		}
 	}
 
+	c := getConfig().GetClient()
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), getTimeout())
 	defer cancel()
@@ -96,7 +122,7 @@ func runTimeseriesQuery(cmd *cobra.Command, args []string) {
 		cancel()
 	}()
 
-	result, err := c.TimeseriesQuery(ctx, clientParams)
+	result, err := c.TimeseriesQueries(ctx, clientParams)
 	if err != nil {
 		errors.HandleErrorAndExit(err)
 	}
@@ -106,18 +132,158 @@ func runTimeseriesQuery(cmd *cobra.Command, args []string) {
 		errors.OutputJSON = true
 	}
 
-	// Extract values from first result in the results array
-	var values []float64
-	if len(result.Results) > 0 {
-		values = result.Results[0].Values
+	// Results come back in the order the queries were sent
+	for i := range series {
+		if i < len(result.Results) {
+			series[i].values = result.Results[i].Values
+		}
+	}
+
+	f := newFormatter(timeseriesQueryOutput, timeseriesQueryOut, output.Options{})
+	if series[0].name == "" {
+		exitOnOutputError(writeBuckets(f, result, series[0].window, series[0].values, timeseriesQueryLong))
+		return
+	}
+	exitOnOutputError(writeNamedBuckets(f, namedTimeseriesDocument(result.Status, series), series, timeseriesQueryLong))
+}
+
+// timeseriesQueryDef is one query of a timeseries-query. Name is empty for
+// the single query of the filter argument. A --queries-file lists them under
+// "queries"; the fields it leaves out are taken from the command's flags.
+type timeseriesQueryDef struct {
+	Name     string `yaml:"name"`
+	Filter   string `yaml:"filter"`
+	Function string `yaml:"function"`
+	Start    string `yaml:"start"`
+	End      string `yaml:"end"`
+	Buckets  int    `yaml:"buckets"`
+}
+
+// timeseriesQueriesFile is the document a --queries-file holds.
+type timeseriesQueriesFile struct {
+	Queries []timeseriesQueryDef `yaml:"queries"`
+}
+
+// parseTimeseriesQueries returns the queries to run: those of the
+// --queries-file followed by the --query flags, or the filter argument alone
+// when there are none. Each is completed from --function, --start, --end and
+// --buckets.
+func parseTimeseriesQueries(filter string, flags []string, file string) ([]timeseriesQueryDef, error) {
+	if len(flags) == 0 && file == "" {
+		return []timeseriesQueryDef{withTimeseriesFlags(timeseriesQueryDef{Filter: filter})}, nil
+	}
+	if filter != "" {
+		return nil, errors.NewValidationError("pass either a filter argument or --query/--queries-file, not both", nil)
+	}
+
+	var queries []timeseriesQueryDef
+	if file != "" {
+		fromFile, err := readTimeseriesQueriesFile(file)
+		if err != nil {
+			return nil, err
+		}
+		queries = fromFile
+	}
+	for _, value := range flags {
+		q, err := parseTimeseriesQueryFlag(value)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+
+	seen := make(map[string]bool)
+	for i, q := range queries {
+		if !validLabel(q.Name) {
+			return nil, errors.NewValidationError(
+				fmt.Sprintf("invalid query name %q", q.Name),
+				fmt.Errorf("names contain only letters, digits, '-', '_' or '.'"),
+			)
+		}
+		if seen[q.Name] {
+			return nil, errors.NewValidationError("duplicate query name "+q.Name, nil)
+		}
+		seen[q.Name] = true
+		queries[i] = withTimeseriesFlags(q)
+	}
+	return queries, nil
+}
+
+// parseTimeseriesQueryFlag parses a --query name:filter:function. The
+// function follows the last colon, so the filter may contain colons; an empty
+// function means --function.
+func parseTimeseriesQueryFlag(value string) (timeseriesQueryDef, error) {
+	name, rest, ok := strings.Cut(value, ":")
+	i := strings.LastIndex(rest, ":")
+	if !ok || i < 0 {
+		return timeseriesQueryDef{}, errors.NewValidationError(
+			"invalid --query "+value,
+			fmt.Errorf("use name:filter:function, leaving the function empty for --function"),
+		)
+	}
+	return timeseriesQueryDef{
+		Name:     strings.TrimSpace(name),
+		Filter:   rest[:i],
+		Function: strings.TrimSpace(rest[i+1:]),
+	}, nil
+}
+
+// readTimeseriesQueriesFile reads the queries of a --queries-file. JSON is
+// read as YAML, of which it is a subset.
+func readTimeseriesQueriesFile(file string) ([]timeseriesQueryDef, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.NewValidationError("cannot read "+file, err)
 	}
 
-	switch timeseriesQueryOutput {
-	case "json":
-		outputJSON(result, false)
-	case "json-pretty":
-		outputJSON(result, true)
-	default:
-		outputNumericCSV(values)
+	var doc timeseriesQueriesFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, errors.NewValidationError("invalid queries file "+file, err)
+	}
+	if len(doc.Queries) == 0 {
+		return nil, errors.NewValidationError("no queries in "+file,
+			fmt.Errorf("list them under \"queries\", each with a name, filter and function"))
+	}
+	return doc.Queries, nil
+}
+
+// withTimeseriesFlags fills in the fields q leaves empty from the command's
+// flags.
+func withTimeseriesFlags(q timeseriesQueryDef) timeseriesQueryDef {
+	if q.Function == "" {
+		q.Function = timeseriesQueryFunction
+	}
+	if q.Start == "" {
+		q.Start = timeseriesQueryStartTime
+	}
+	if q.End == "" {
+		q.End = timeseriesQueryEndTime
+	}
+	if q.Buckets == 0 {
+		q.Buckets = timeseriesQueryBuckets
+	}
+	return q
+}
+
+// namedTimeseriesResult is a result of the JSON document written for named
+// queries without --long.
+type namedTimeseriesResult struct {
+	Name   string    `json:"name"`
+	Values []float64 `json:"values"`
+}
+
+// namedTimeseriesDocument is the API response of named queries, each result
+// labelled with the name of its query.
+func namedTimeseriesDocument(status string, series []bucketSeries) map[string]any {
+	results := make([]namedTimeseriesResult, len(series))
+	for i, s := range series {
+		values := s.values
+		if values == nil {
+			values = []float64{}
+		}
+		results[i] = namedTimeseriesResult{Name: s.name, Values: values}
 	}
+	return map[string]any{"status": status, "results": results}
 }
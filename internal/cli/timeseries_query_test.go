@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeseriesQueries(t *testing.T) {
+	defer func() {
+		timeseriesQueryFunction, timeseriesQueryStartTime, timeseriesQueryEndTime, timeseriesQueryBuckets = "", "", "", 1
+	}()
+	timeseriesQueryFunction, timeseriesQueryStartTime, timeseriesQueryEndTime, timeseriesQueryBuckets = "count", "1h", "", 12
+
+	queries, err := parseTimeseriesQueries("error", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, []timeseriesQueryDef{{Filter: "error", Function: "count", Start: "1h", Buckets: 12}}, queries)
+
+	queries, err = parseTimeseriesQueries("", []string{
+		"errors:severity >= 5:",
+		`latency:$uri == "http://x":mean(latency)`,
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []timeseriesQueryDef{
+		{Name: "errors", Filter: "severity >= 5", Function: "count", Start: "1h", Buckets: 12},
+		{Name: "latency", Filter: `$uri == "http://x"`, Function: "mean(latency)", Start: "1h", Buckets: 12},
+	}, queries, "the function follows the last colon, and an empty one is --function")
+
+	for _, bad := range [][]string{{"no-colons"}, {"name:filter"}, {":filter:count"}, {"bad name:x:count"}, {"a:x:count", "a:y:count"}} {
+		_, err := parseTimeseriesQueries("", bad, "")
+		assert.Error(t, err, "%v", bad)
+	}
+
+	_, err = parseTimeseriesQueries("error", []string{"a:x:count"}, "")
+	assert.Error(t, err, "a positional filter and --query are exclusive")
+}
+
+func TestParseTimeseriesQueries_File(t *testing.T) {
+	defer func() {
+		timeseriesQueryFunction, timeseriesQueryStartTime, timeseriesQueryBuckets = "", "", 1
+	}()
+	timeseriesQueryFunction, timeseriesQueryStartTime, timeseriesQueryBuckets = "count", "1h", 12
+	dir := t.TempDir()
+
+	yamlFile := filepath.Join(dir, "dashboard.yaml")
+	require.NoError(t, os.WriteFile(yamlFile, []byte(`queries:
+  - name: errors
+    filter: severity >= 5
+  - name: latency
+    filter: $serverHost == "web-01"
+    function: p99(latency)
+    start: 7d
+    buckets: 7
+`), 0644))
+	queries, err := parseTimeseriesQueries("", []string{"extra::rate"}, yamlFile)
+	require.NoError(t, err)
+	assert.Equal(t, []timeseriesQueryDef{
+		{Name: "errors", Filter: "severity >= 5", Function: "count", Start: "1h", Buckets: 12},
+		{Name: "latency", Filter: `$serverHost == "web-01"`, Function: "p99(latency)", Start: "7d", Buckets: 7},
+		{Name: "extra", Function: "rate", Start: "1h", Buckets: 12},
+	}, queries, "file queries come first, then --query, each completed from the flags")
+
+	jsonFile := filepath.Join(dir, "dashboard.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`{"queries": [{"name": "errors", "filter": "severity >= 5"}]}`), 0644))
+	queries, err = parseTimeseriesQueries("", nil, jsonFile)
+	require.NoError(t, err)
+	assert.Equal(t, []timeseriesQueryDef{{Name: "errors", Filter: "severity >= 5", Function: "count", Start: "1h", Buckets: 12}}, queries)
+
+	for name, content := range map[string]string{
+		"unknown.yaml": "queries:\n  - name: a\n    fliter: x\n",
+		"empty.yaml":   "queries: []\n",
+		"noname.yaml":  "queries:\n  - filter: x\n",
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		_, err := parseTimeseriesQueries("", nil, path)
+		assert.Error(t, err, name)
+	}
+
+	_, err = parseTimeseriesQueries("", nil, filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
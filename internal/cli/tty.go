@@ -1,6 +1,11 @@
 package cli
 
-import "os"
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
 
 // IsTTY reports whether stdout is a terminal. It is a package-level variable for testability.
 var IsTTY = func() bool {
@@ -10,3 +15,41 @@ var IsTTY = func() bool {
 	}
 	return fi.Mode()&os.ModeCharDevice != 0
 }
+
+// isStdinTTY reports whether stdin is a terminal, so prompting is possible.
+var isStdinTTY = func() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// isStderrTTY reports whether stderr is a terminal, so progress can be drawn.
+var isStderrTTY = func() bool {
+	fi, err := os.Stderr.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth returns the width table output is fitted to: $COLUMNS when
+// set, the terminal's width when stdout (or the --pager's) is one, and 0
+// (unlimited) otherwise.
+func terminalWidth() int {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	stdout := os.Stdout
+	if activePager != nil {
+		stdout = activePager.originalStdout
+	} else if !IsTTY() {
+		return 0
+	}
+	width, _, err := term.GetSize(int(stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
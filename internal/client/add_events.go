@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+const (
+	// MaxRequestBytes is the largest request body the addEvents and
+	// uploadLogs endpoints accept.
+	MaxRequestBytes = 6 * 1024 * 1024
+
+	// addEventsHeadroom is reserved in every addEvents batch for the token
+	// and the JSON framing that is not accounted per event.
+	addEventsHeadroom = 1024
+)
+
+// Event is a single event sent with addEvents. Within a session timestamps
+// must be strictly increasing; EventSession takes care of that.
+type Event struct {
+	Thread string `json:"thread,omitempty"`
+	// Timestamp is in nanoseconds since the epoch.
+	Timestamp int64                  `json:"ts,string"`
+	Severity  int                    `json:"sev"`
+	Attrs     map[string]interface{} `json:"attrs"`
+	// SequenceID and SequenceNumber let the server discard events it has
+	// already received when a request is retried.
+	SequenceID     string `json:"si,omitempty"`
+	SequenceNumber int64  `json:"sn,omitempty"`
+}
+
+// Thread names a thread ID referenced by events, e.g. one per input file.
+type Thread struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type AddEventsParams struct {
+	Session     string
+	SessionInfo map[string]interface{}
+	Threads     []Thread
+	Events      []Event
+}
+
+type AddEventsResponse struct {
+	Status       string  `json:"status"`
+	Message      string  `json:"message,omitempty"`
+	BytesCharged float64 `json:"bytesCharged,omitempty"`
+}
+
+// AddEvents sends one addEvents request. Network failures, retryable HTTP
+// statuses and transient "error/server/..." statuses, such as backoff, are
+// retried by a single loop, so a batch is sent at most MaxRetries+1 times.
+func (c *Client) AddEvents(ctx context.Context, params AddEventsParams) (*AddEventsResponse, error) {
+	requestParams := map[string]interface{}{
+		"session": params.Session,
+		"events":  params.Events,
+	}
+	if len(params.SessionInfo) > 0 {
+		requestParams["sessionInfo"] = params.SessionInfo
+	}
+	if len(params.Threads) > 0 {
+		requestParams["threads"] = params.Threads
+	}
+
+	var result AddEventsResponse
+	err := c.retryOperation(ctx, func() error {
+		resp, err := c.makeSingleRequest(ctx, "addEvents", requestParams)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		result = AddEventsResponse{}
+		if err := c.decodeResponse(resp.Body, &result); err != nil {
+			return err
+		}
+
+		if result.Status != "success" {
+			return apiStatusError(result.Status, result.Message)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// IngestStats summarises what a write session has sent so far.
+type IngestStats struct {
+	Events   int   `json:"events"`
+	Requests int   `json:"requests"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// EventSession batches events into addEvents requests that share a session
+// ID, sessionInfo and thread list. Batches are flushed before they would
+// exceed MaxRequestBytes. Every event gets a sequence number so retried
+// requests are not ingested twice. An EventSession is not safe for
+// concurrent use.
+type EventSession struct {
+	client   *Client
+	id       string
+	info     map[string]interface{}
+	threads  []Thread
+	overhead int
+
+	sequenceID string
+	sequence   int64
+	lastTS     int64
+
+	pending      []Event
+	pendingBytes int
+	stats        IngestStats
+}
+
+// NewEventSession starts a session with a fresh random ID. info becomes the
+// session's sessionInfo, typically serverHost plus any server attributes.
+func (c *Client) NewEventSession(info map[string]interface{}, threads []Thread) *EventSession {
+	s := &EventSession{
+		client:     c,
+		id:         newSessionID(),
+		info:       info,
+		threads:    threads,
+		sequenceID: newSessionID(),
+	}
+
+	framing, _ := json.Marshal(map[string]interface{}{
+		"session":     s.id,
+		"sessionInfo": info,
+		"threads":     threads,
+		"events":      []Event{},
+	})
+	s.overhead = len(framing) + len(c.token) + addEventsHeadroom
+	return s
+}
+
+// ID returns the session ID sent with every request.
+func (s *EventSession) ID() string {
+	return s.id
+}
+
+// Stats reports the events, requests and bytes sent so far.
+func (s *EventSession) Stats() IngestStats {
+	return s.stats
+}
+
+// Add queues an event, flushing the pending batch first when the event
+// would not fit. A zero Timestamp is set to the current time. When Add
+// returns an error, such as a cancelled flush, the event was not queued and
+// the session is unchanged, so the event can be added again.
+func (s *EventSession) Add(ctx context.Context, event Event) error {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixNano()
+	}
+	if event.Timestamp <= s.lastTS {
+		event.Timestamp = s.lastTS + 1
+	}
+
+	event.SequenceID = s.sequenceID
+	event.SequenceNumber = s.sequence + 1
+
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return errors.NewParseError("failed to encode event", err)
+	}
+	size := len(encoded) + 1
+	if s.overhead+size > MaxRequestBytes {
+		return errors.NewValidationError(
+			fmt.Sprintf("event of %d bytes exceeds the %d byte addEvents request limit", len(encoded), MaxRequestBytes),
+			nil,
+		)
+	}
+
+	if s.overhead+s.pendingBytes+size > MaxRequestBytes {
+		if err := s.Flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	s.lastTS = event.Timestamp
+	s.sequence = event.SequenceNumber
+	s.pending = append(s.pending, event)
+	s.pendingBytes += size
+	return nil
+}
+
+// Flush sends any pending events.
+func (s *EventSession) Flush(ctx context.Context) error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	_, err := s.client.AddEvents(ctx, AddEventsParams{
+		Session:     s.id,
+		SessionInfo: s.info,
+		Threads:     s.threads,
+		Events:      s.pending,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.stats.Events += len(s.pending)
+	s.stats.Requests++
+	s.stats.Bytes += int64(s.overhead + s.pendingBytes - addEventsHeadroom)
+	s.pending = nil
+	s.pendingBytes = 0
+	return nil
+}
+
+// newSessionID returns a random version 4 UUID.
+func newSessionID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
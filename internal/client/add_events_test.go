@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_AddEvents(t *testing.T) {
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/addEvents", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &request))
+		w.Write([]byte(`{"status":"success","bytesCharged":42}`))
+	}))
+	defer server.Close()
+
+	client := New("write-token", server.URL, false)
+	resp, err := client.AddEvents(context.Background(), AddEventsParams{
+		Session:     "session-1",
+		SessionInfo: map[string]interface{}{"serverHost": "web-1"},
+		Threads:     []Thread{{ID: "1", Name: "app.log"}},
+		Events: []Event{{
+			Thread:    "1",
+			Timestamp: 1700000000000000000,
+			Severity:  3,
+			Attrs:     map[string]interface{}{"message": "hello"},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), resp.BytesCharged)
+
+	assert.Equal(t, "write-token", request["token"])
+	assert.Equal(t, "session-1", request["session"])
+	assert.Equal(t, map[string]interface{}{"serverHost": "web-1"}, request["sessionInfo"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": "1", "name": "app.log"}}, request["threads"])
+
+	events := request["events"].([]interface{})
+	require.Len(t, events, 1)
+	event := events[0].(map[string]interface{})
+	assert.Equal(t, "1700000000000000000", event["ts"], "timestamps are sent as strings")
+	assert.Equal(t, "1", event["thread"])
+	assert.Equal(t, map[string]interface{}{"message": "hello"}, event["attrs"])
+}
+
+func TestClient_AddEvents_RetriesBackoff(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte(`{"status":"error/server/backoff","message":"slow down"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	client := New("write-token", server.URL, false)
+	client.SetRetryPolicy(fastRetryPolicy(2))
+	_, err := client.AddEvents(context.Background(), AddEventsParams{Session: "s"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestClient_AddEvents_RetriesOnce(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New("write-token", server.URL, false)
+	client.SetRetryPolicy(fastRetryPolicy(2))
+	_, err := client.AddEvents(context.Background(), AddEventsParams{Session: "s"})
+	require.Error(t, err)
+	assert.Equal(t, 3, calls, "HTTP retries are not multiplied by the operation retries")
+}
+
+func TestClient_AddEvents_BadParamIsNotRetried(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"status":"error/client/badParam","message":"bad session"}`))
+	}))
+	defer server.Close()
+
+	client := New("write-token", server.URL, false)
+	client.SetRetryPolicy(fastRetryPolicy(2))
+	_, err := client.AddEvents(context.Background(), AddEventsParams{Session: "s"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad session")
+	assert.Equal(t, 1, calls)
+}
+
+func TestEventSession_BatchesUnderRequestLimit(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.LessOrEqual(t, len(body), MaxRequestBytes)
+		var reqData map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &reqData))
+		requests = append(requests, reqData)
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	client := New("write-token", server.URL, false)
+	session := client.NewEventSession(map[string]interface{}{"serverHost": "web-1"}, nil)
+
+	line := strings.Repeat("x", 1024*1024)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, session.Add(context.Background(), Event{Timestamp: 100, Severity: 3, Attrs: map[string]interface{}{"message": line}}))
+	}
+	require.NoError(t, session.Flush(context.Background()))
+
+	require.Len(t, requests, 2, "ten 1MB events need two 6MB requests")
+	assert.Equal(t, 10, session.Stats().Events)
+	assert.Equal(t, 2, session.Stats().Requests)
+
+	var lastTS, lastSN float64
+	var sequenceID string
+	for _, req := range requests {
+		assert.Equal(t, session.ID(), req["session"], "every request shares the session")
+		for _, e := range req["events"].([]interface{}) {
+			event := e.(map[string]interface{})
+			ts, err := json.Number(event["ts"].(string)).Float64()
+			require.NoError(t, err)
+			assert.Greater(t, ts, lastTS, "timestamps are strictly increasing")
+			lastTS = ts
+
+			assert.Equal(t, lastSN+1, event["sn"], "sequence numbers are consecutive")
+			lastSN = event["sn"].(float64)
+			if sequenceID == "" {
+				sequenceID = event["si"].(string)
+			}
+			assert.Equal(t, sequenceID, event["si"])
+		}
+	}
+}
+
+func TestEventSession_RejectsOversizedEvent(t *testing.T) {
+	client := New("write-token", "http://127.0.0.1:1", false)
+	session := client.NewEventSession(nil, nil)
+
+	err := session.Add(context.Background(), Event{Attrs: map[string]interface{}{"message": strings.Repeat("x", MaxRequestBytes)}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds")
+}
+
+func TestClient_UploadLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/uploadLogs", r.URL.Path)
+		assert.Equal(t, "write-token", r.URL.Query().Get("token"))
+		assert.Equal(t, "web-1", r.Header.Get("server-host"))
+		assert.Equal(t, "/var/log/app.log", r.Header.Get("logfile"))
+		assert.Equal(t, "accessLog", r.Header.Get("parser"))
+		assert.Equal(t, "prod", r.Header.Get("server-env"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "line one\nline two\n", string(body))
+		w.Write([]byte(`{"status":"success"}`))
+	}))
+	defer server.Close()
+
+	client := New("write-token", server.URL, false)
+	err := client.UploadLogs(context.Background(), UploadLogsParams{
+		ServerHost: "web-1",
+		LogFile:    "/var/log/app.log",
+		Parser:     "accessLog",
+		Attributes: map[string]string{"env": "prod"},
+	}, []byte("line one\nline two\n"))
+	require.NoError(t, err)
+}
+
+func TestClient_UploadLogs_TooLarge(t *testing.T) {
+	client := New("write-token", "http://127.0.0.1:1", false)
+	err := client.UploadLogs(context.Background(), UploadLogsParams{}, make([]byte, MaxRequestBytes+1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds")
+}
+
+func TestNewSessionID(t *testing.T) {
+	id := newSessionID()
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, id)
+	assert.NotEqual(t, id, newSessionID())
+}
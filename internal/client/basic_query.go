@@ -1,15 +1,95 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"iter"
 
 	"github.com/andreagrandi/logbasset/internal/errors"
 	"github.com/andreagrandi/logbasset/internal/logging"
 )
 
 func (c *Client) Query(ctx context.Context, params QueryParams) (*QueryResponse, error) {
+	matches := make([]LogEvent, 0)
+	result, err := c.streamQuery(ctx, params, func(event LogEvent) bool {
+		matches = append(matches, event)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Matches = matches
+	return result, nil
+}
+
+// QueryIter runs a log query and yields each matching event as soon as it is
+// decoded from the response body, so a large page never has to be held in
+// memory at once. A request, parse or API error is yielded once as the final
+// element. Stopping the iteration early closes the response without reading
+// the rest of it.
+func (c *Client) QueryIter(ctx context.Context, params QueryParams) iter.Seq2[LogEvent, error] {
+	return func(yield func(LogEvent, error) bool) {
+		stopped := false
+		_, err := c.streamQuery(ctx, params, func(event LogEvent) bool {
+			if !yield(event, nil) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if err != nil && !stopped {
+			yield(LogEvent{}, err)
+		}
+	}
+}
+
+// streamQuery issues a single query request and passes every element of
+// "matches" to fn while the body is still being decoded. When fn returns
+// false decoding stops and the partially read response is returned with a nil
+// error. The returned response carries the other top-level fields; Matches is
+// left empty because events are only ever handed to fn.
+func (c *Client) streamQuery(ctx context.Context, params QueryParams, fn func(LogEvent) bool) (*QueryResponse, error) {
+	resp, err := c.makeRequest(ctx, "query", queryRequestParams(params))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body := &readErrorTracker{r: resp.Body}
+	var reader io.Reader = body
+	var logged bytes.Buffer
+	if c.verbose {
+		reader = io.TeeReader(body, &logged)
+	}
+
+	var result QueryResponse
+	stopped, err := decodeQueryResponse(reader, &result, fn)
+
+	if c.verbose {
+		logging.WithField("response_body", logged.String()).Debug("API response received")
+	}
+
+	if body.err != nil {
+		return nil, errors.NewNetworkError("failed to read response body", body.err)
+	}
+	if err != nil {
+		return nil, errors.NewParseError("failed to parse response", err)
+	}
+	if stopped {
+		return &result, nil
+	}
+
+	if result.Status != "success" {
+		return nil, apiStatusError(result.Status, result.Message)
+	}
+
+	return &result, nil
+}
+
+func queryRequestParams(params QueryParams) map[string]interface{} {
 	requestParams := map[string]interface{}{
 		"queryType": "log",
 	}
@@ -35,30 +115,131 @@ func (c *Client) Query(ctx context.Context, params QueryParams) (*QueryResponse,
 	if params.Priority != "" {
 		requestParams["priority"] = params.Priority
 	}
+	if params.ContinuationToken != "" {
+		requestParams["continuationToken"] = params.ContinuationToken
+	}
 
-	resp, err := c.makeRequest(ctx, "query", requestParams)
+	return requestParams
+}
+
+// decodeQueryResponse walks a query response with json.Decoder tokens. Each
+// element of the "matches" array is decoded on its own and handed to fn;
+// every other top-level field is stored in result. It reports whether fn
+// asked to stop before the end of the body.
+func decodeQueryResponse(r io.Reader, result *QueryResponse, fn func(LogEvent) bool) (bool, error) {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return false, err
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		key, _ := tok.(string)
+
+		switch key {
+		case "status":
+			err = dec.Decode(&result.Status)
+		case "message":
+			err = dec.Decode(&result.Message)
+		case "continuationToken":
+			err = dec.Decode(&result.ContinuationToken)
+		case "matches":
+			var stopped bool
+			stopped, err = decodeMatches(dec, fn)
+			if err == nil && stopped {
+				return true, nil
+			}
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return false, expectDelim(dec, '}')
+}
+
+func decodeMatches(dec *json.Decoder, fn func(LogEvent) bool) (bool, error) {
+	tok, err := dec.Token()
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	defer resp.Body.Close()
+	if tok == nil {
+		return false, nil
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return false, fmt.Errorf("expected matches to be an array, got %v", tok)
+	}
+
+	for dec.More() {
+		var event LogEvent
+		if err := dec.Decode(&event); err != nil {
+			return false, err
+		}
+		if !fn(event) {
+			return true, nil
+		}
+	}
+
+	return false, expectDelim(dec, ']')
+}
 
-	body, err := io.ReadAll(resp.Body)
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
 	if err != nil {
-		return nil, errors.NewNetworkError("failed to read response body", err)
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
 	}
+	return nil
+}
 
-	if c.verbose {
-		logging.WithField("response_body", string(body)).Debug("API response received")
+// readErrorTracker remembers the first non-EOF error returned by the
+// underlying reader, so a dropped connection is reported as a network error
+// rather than as malformed JSON.
+type readErrorTracker struct {
+	r   io.Reader
+	err error
+}
+
+func (t *readErrorTracker) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && err != io.EOF && t.err == nil {
+		t.err = err
 	}
+	return n, err
+}
 
-	var result QueryResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, errors.NewParseError("failed to parse response", err)
+// decodeResponse decodes a response body straight into result with
+// json.Decoder instead of reading it into memory first. Like streamQuery it
+// reports a failed read as a network error and malformed JSON as a parse
+// error, and in verbose mode logs the body once it has been decoded.
+func (c *Client) decodeResponse(r io.Reader, result interface{}) error {
+	body := &readErrorTracker{r: r}
+	var reader io.Reader = body
+	var logged bytes.Buffer
+	if c.verbose {
+		reader = io.TeeReader(body, &logged)
 	}
 
-	if result.Status != "success" {
-		return nil, errors.NewAPIError(result.Message, nil)
+	err := json.NewDecoder(reader).Decode(result)
+
+	if c.verbose {
+		logging.WithField("response_body", logged.String()).Debug("API response received")
 	}
 
-	return &result, nil
+	if body.err != nil {
+		return errors.NewNetworkError("failed to read response body", body.err)
+	}
+	if err != nil {
+		return errors.NewParseError("failed to parse response", err)
+	}
+	return nil
 }
@@ -97,6 +97,18 @@ func drainAndClose(body io.ReadCloser) {
 }
 
 func (c *Client) makeRequest(ctx context.Context, endpoint string, params map[string]interface{}) (*http.Response, error) {
+	return c.sendRequest(ctx, endpoint, params, c.retryPolicy)
+}
+
+// makeSingleRequest is makeRequest without its retries, for operations such
+// as addEvents that retryOperation re-runs as a whole, so the attempts of the
+// two are not multiplied.
+func (c *Client) makeSingleRequest(ctx context.Context, endpoint string, params map[string]interface{}) (*http.Response, error) {
+	return c.sendRequest(ctx, endpoint, params, RetryPolicy{})
+}
+
+// sendRequest posts params to an API endpoint, retrying according to policy.
+func (c *Client) sendRequest(ctx context.Context, endpoint string, params map[string]interface{}, policy RetryPolicy) (*http.Response, error) {
 	if c.token == "" {
 		return nil, errors.NewAuthError("API token is required", nil)
 	}
@@ -126,7 +138,21 @@ func (c *Client) makeRequest(ctx context.Context, endpoint string, params map[st
 		}
 	}
 
-	policy := c.retryPolicy
+	return c.doWithRetry(ctx, policy, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+}
+
+// doWithRetry sends the request built by newRequest, retrying transient
+// network failures and retryable HTTP statuses according to policy, usually
+// the client's RetryPolicy. newRequest is called once per attempt so the body
+// can be re-read.
+func (c *Client) doWithRetry(ctx context.Context, policy RetryPolicy, newRequest func() (*http.Request, error)) (*http.Response, error) {
 	if policy.MaxRetries < 0 {
 		policy.MaxRetries = 0
 	}
@@ -163,11 +189,10 @@ func (c *Client) makeRequest(ctx context.Context, endpoint string, params map[st
 			}
 		}
 
-		req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewReader(jsonData))
+		req, err := newRequest()
 		if err != nil {
 			return nil, errors.NewNetworkError("failed to create request", err)
 		}
-		req.Header.Set("Content-Type", "application/json")
 
 		resp, execErr = c.httpClient.Do(req)
 		if execErr != nil {
@@ -406,6 +406,40 @@ func TestClient_TimeseriesQuery(t *testing.T) {
 	assert.Equal(t, []float64{1.0, 2.0, 3.0}, result.Results[0].Values)
 }
 
+func TestClient_TimeseriesQueries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var reqData map[string]interface{}
+		json.Unmarshal(body, &reqData)
+
+		queries, ok := reqData["queries"].([]interface{})
+		require.True(t, ok, "queries should be an array")
+		require.Len(t, queries, 2, "every query goes in the one request")
+		first := queries[0].(map[string]interface{})
+		second := queries[1].(map[string]interface{})
+		assert.Equal(t, "severity >= 5", first["filter"])
+		assert.Equal(t, "count", first["function"])
+		assert.Equal(t, "mean(latency)", second["function"])
+		assert.Equal(t, "7d", second["startTime"])
+		assert.EqualValues(t, 7, second["buckets"])
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status": "success", "results": [{"values": [1, 2]}, {"values": [3]}]}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	result, err := client.TimeseriesQueries(context.Background(), []TimeseriesQueryParams{
+		{Filter: "severity >= 5", Function: "count", StartTime: "1h", Buckets: 2},
+		{Function: "mean(latency)", StartTime: "7d", Buckets: 7},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, result.Results, 2)
+	assert.Equal(t, []float64{1, 2}, result.Results[0].Values)
+	assert.Equal(t, []float64{3}, result.Results[1].Values)
+}
+
 func TestClient_Tail(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "POST", r.Method)
@@ -798,3 +832,207 @@ func TestClient_Tail_ContextCancellation(t *testing.T) {
 	// Should have made at least one request
 	assert.GreaterOrEqual(t, callCount, 1)
 }
+
+func TestTail_ReconnectsAfterTransientFailures(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		switch requestCount {
+		case 1:
+			w.Write([]byte(`{"status":"success","matches":[{"timestamp":"1","message":"First"}],"continuationToken":"token-1"}`))
+		case 2, 3, 4, 5:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 6:
+			w.Write([]byte(`{"status":"error/server/backoff","message":"slow down"}`))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			var reqData map[string]interface{}
+			json.Unmarshal(body, &reqData)
+			assert.Equal(t, "token-1", reqData["continuationToken"], "the reconnect resumes from the last token")
+			w.Write([]byte(`{"status":"success","matches":[{"timestamp":"2","message":"Second"}],"continuationToken":"token-2"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	client.SetRetryPolicy(fastRetryPolicy(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	outputChan := make(chan LogEvent, 10)
+
+	var messages []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range outputChan {
+			messages = append(messages, event.Message)
+			if len(messages) == 2 {
+				cancel()
+			}
+		}
+	}()
+
+	err := client.Tail(ctx, TailParams{Lines: 10, Interval: time.Millisecond}, outputChan)
+	<-done
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{"First", "Second"}, messages)
+}
+
+func TestTail_StopsOnClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"error/client/badParam","message":"bad filter"}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	err := client.Tail(context.Background(), TailParams{Lines: 10}, make(chan LogEvent, 10))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad filter")
+}
+
+func TestTail_RequiresContinuationToken(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"status":"success","matches":[{"timestamp":"1","message":"a"}]}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	outputChan := make(chan LogEvent, 10)
+	err := client.Tail(context.Background(), TailParams{Lines: 10, Interval: time.Millisecond}, outputChan)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no continuationToken")
+	assert.Equal(t, 1, requests, "the tail is not restarted without a token")
+	assert.Empty(t, outputChan, "events that could never be followed up are not sent")
+}
+
+func TestTail_ResumeAndCheckpoints(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var reqData map[string]interface{}
+		json.Unmarshal(body, &reqData)
+		requests = append(requests, reqData)
+		w.Write([]byte(`{"status":"success","matches":[` +
+			`{"timestamp":"1700000000000000001","message":"a"},{"timestamp":"1700000000000000002","message":"b"}` +
+			`],"continuationToken":"token-next"}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	outputChan := make(chan LogEvent, 10)
+	go func() {
+		for range outputChan {
+		}
+	}()
+
+	var checkpoints []TailCheckpoint
+	err := client.Tail(ctx, TailParams{
+		Lines:    10,
+		Interval: time.Millisecond,
+		Resume:   &TailCheckpoint{ContinuationToken: "token-saved", LastTimestamp: "1700000000000000000"},
+		OnCheckpoint: func(cp TailCheckpoint) {
+			checkpoints = append(checkpoints, cp)
+			cancel()
+		},
+	}, outputChan)
+	assert.Equal(t, context.Canceled, err)
+
+	require.Len(t, requests, 1)
+	assert.Equal(t, "token-saved", requests[0]["continuationToken"], "a resumed tail does not re-fetch the last lines")
+	assert.Equal(t, float64(1000), requests[0]["maxCount"])
+	assert.Equal(t, []TailCheckpoint{{ContinuationToken: "token-next", LastTimestamp: "1700000000000000002"}}, checkpoints)
+}
+
+func TestNextTailWait(t *testing.T) {
+	const interval, maxInterval = 2 * time.Second, 10 * time.Second
+
+	tests := []struct {
+		name        string
+		prev        time.Duration
+		events      int
+		full        bool
+		maxInterval time.Duration
+		want        time.Duration
+	}{
+		{name: "fixed polling ignores full pages", prev: interval, events: 1000, full: true, want: interval},
+		{name: "fixed polling ignores idle pages", prev: interval, want: interval},
+		{name: "full page polls again at once", prev: interval, events: 1000, full: true, maxInterval: maxInterval, want: 0},
+		{name: "partial page resets the interval", prev: 8 * time.Second, events: 3, maxInterval: maxInterval, want: interval},
+		{name: "idle page after catching up", prev: 0, maxInterval: maxInterval, want: interval},
+		{name: "idle page backs off", prev: 4 * time.Second, maxInterval: maxInterval, want: 8 * time.Second},
+		{name: "backoff is capped", prev: 8 * time.Second, maxInterval: maxInterval, want: maxInterval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextTailWait(tt.prev, tt.events, tt.full, interval, tt.maxInterval))
+		})
+	}
+}
+
+func TestTail_PageSize(t *testing.T) {
+	var maxCounts []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var reqData map[string]interface{}
+		json.Unmarshal(body, &reqData)
+		maxCounts = append(maxCounts, reqData["maxCount"])
+		w.Write([]byte(`{"status":"success","matches":[{"message":"a"},{"message":"b"}],"continuationToken":"token"}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	outputChan := make(chan LogEvent, 10)
+	go func() {
+		for range outputChan {
+		}
+	}()
+
+	// Every page is full, so adaptive polling never waits for the hour-long
+	// interval.
+	pages := 0
+	err := client.Tail(ctx, TailParams{
+		Lines:       2,
+		PageSize:    2,
+		Interval:    time.Hour,
+		MaxInterval: 2 * time.Hour,
+		OnCheckpoint: func(TailCheckpoint) {
+			if pages++; pages == 3 {
+				cancel()
+			}
+		},
+	}, outputChan)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []interface{}{float64(2), float64(2), float64(2)}, maxCounts)
+}
+
+func TestClient_decodeResponse_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "truncated") {
+			// Promise more than is sent, so the connection drops mid-body
+			w.Header().Set("Content-Length", "1000")
+			w.Write([]byte(`{"status":"success","values":[1,`))
+			return
+		}
+		w.Write([]byte(`{"status":"success","values":[1,}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	client.SetRetryPolicy(RetryPolicy{})
+
+	_, err := client.NumericQuery(context.Background(), NumericQueryParams{Filter: "truncated", StartTime: "1h"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read response body", "a dropped connection is a network error")
+
+	_, err = client.NumericQuery(context.Background(), NumericQueryParams{Filter: "malformed", StartTime: "1h"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse response")
+}
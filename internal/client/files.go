@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+)
+
+const (
+	statusNoSuchFile      = "success/noSuchFile"
+	statusVersionMismatch = "error/client/versionMismatch"
+)
+
+// statusResponse is the body of API calls that only report a status.
+type statusResponse struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+type ListFilesResponse struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message,omitempty"`
+	Paths   []string `json:"paths"`
+}
+
+// ConfigFile is a Scalyr configuration file (parser, dashboard, alert,
+// lookup table, ...) as returned by getFile.
+type ConfigFile struct {
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	Path    string `json:"path"`
+	Version int64  `json:"version"`
+	// CreateDate and ModDate are in milliseconds since the epoch.
+	CreateDate int64  `json:"createDate,omitempty"`
+	ModDate    int64  `json:"modDate,omitempty"`
+	Content    string `json:"content"`
+}
+
+type PutFileParams struct {
+	Path    string
+	Content string
+	// ExpectedVersion makes the write fail with a version mismatch when the
+	// file has changed since that version was read. Zero skips the check.
+	ExpectedVersion int64
+	// Delete removes the file instead of writing Content.
+	Delete bool
+}
+
+// IsNoSuchFile reports whether err is GetFile's error for a missing file.
+func IsNoSuchFile(err error) bool {
+	return hasAPIStatus(err, statusNoSuchFile)
+}
+
+// IsVersionMismatch reports whether err is PutFile's error for a file that
+// changed since ExpectedVersion.
+func IsVersionMismatch(err error) bool {
+	return hasAPIStatus(err, statusVersionMismatch)
+}
+
+func hasAPIStatus(err error, status string) bool {
+	var se *statusError
+	return stderrors.As(err, &se) && strings.EqualFold(se.status, status)
+}
+
+// ListFiles returns the paths of every configuration file in the account.
+func (c *Client) ListFiles(ctx context.Context) (*ListFilesResponse, error) {
+	var result ListFilesResponse
+	if err := c.filesRequest(ctx, "listFiles", map[string]interface{}{}, &result); err != nil {
+		return nil, err
+	}
+	if result.Status != "success" {
+		return nil, apiStatusError(result.Status, result.Message)
+	}
+	return &result, nil
+}
+
+// GetFile fetches a configuration file. A missing file is reported as an
+// error for which IsNoSuchFile is true.
+func (c *Client) GetFile(ctx context.Context, path string) (*ConfigFile, error) {
+	var result ConfigFile
+	if err := c.filesRequest(ctx, "getFile", map[string]interface{}{"path": path}, &result); err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(result.Status, statusNoSuchFile) {
+		return nil, apiStatusError(result.Status, "no such file: "+path)
+	}
+	if result.Status != "success" {
+		return nil, apiStatusError(result.Status, result.Message)
+	}
+	if result.Path == "" {
+		result.Path = path
+	}
+	return &result, nil
+}
+
+// PutFile creates, replaces or deletes a configuration file. When
+// ExpectedVersion is set and the file has changed since, the returned error
+// satisfies IsVersionMismatch.
+func (c *Client) PutFile(ctx context.Context, params PutFileParams) error {
+	requestParams := map[string]interface{}{
+		"path": params.Path,
+	}
+	if params.Delete {
+		requestParams["deleteFile"] = true
+	} else {
+		requestParams["content"] = params.Content
+	}
+	if params.ExpectedVersion > 0 {
+		requestParams["expectedVersion"] = params.ExpectedVersion
+	}
+
+	var result statusResponse
+	if err := c.filesRequest(ctx, "putFile", requestParams, &result); err != nil {
+		return err
+	}
+	if result.Status != "success" {
+		message := result.Message
+		if strings.EqualFold(result.Status, statusVersionMismatch) && message == "" {
+			message = "file " + params.Path + " was changed by someone else"
+		}
+		return apiStatusError(result.Status, message)
+	}
+	return nil
+}
+
+func (c *Client) filesRequest(ctx context.Context, endpoint string, params map[string]interface{}, result interface{}) error {
+	resp, err := c.makeRequest(ctx, endpoint, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return c.decodeResponse(resp.Body, result)
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filesServer replies to each request with the response registered for its
+// endpoint, recording the decoded request bodies.
+func filesServer(t *testing.T, responses map[string]string, requests *[]map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var reqData map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &reqData))
+		reqData["endpoint"] = r.URL.Path
+		*requests = append(*requests, reqData)
+		w.Write([]byte(responses[r.URL.Path]))
+	}))
+}
+
+func TestClient_ListFiles(t *testing.T) {
+	var requests []map[string]interface{}
+	server := filesServer(t, map[string]string{
+		"/api/listFiles": `{"status":"success","paths":["/scalyr/parsers/app","/dashboards/errors"]}`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("config-token", server.URL, false)
+	resp, err := client.ListFiles(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/scalyr/parsers/app", "/dashboards/errors"}, resp.Paths)
+	assert.Equal(t, "config-token", requests[0]["token"])
+}
+
+func TestClient_GetFile(t *testing.T) {
+	var requests []map[string]interface{}
+	server := filesServer(t, map[string]string{
+		"/api/getFile": `{"status":"success","path":"/scalyr/parsers/app","version":7,"createDate":1700000000000,"modDate":1700000001000,"content":"{ patterns: {} }"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("config-token", server.URL, false)
+	file, err := client.GetFile(context.Background(), "/scalyr/parsers/app")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), file.Version)
+	assert.Equal(t, "{ patterns: {} }", file.Content)
+	assert.Equal(t, int64(1700000001000), file.ModDate)
+	assert.Equal(t, "/scalyr/parsers/app", requests[0]["path"])
+}
+
+func TestClient_GetFile_NoSuchFile(t *testing.T) {
+	var requests []map[string]interface{}
+	server := filesServer(t, map[string]string{
+		"/api/getFile": `{"status":"success/noSuchFile"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("config-token", server.URL, false)
+	_, err := client.GetFile(context.Background(), "/missing")
+	require.Error(t, err)
+	assert.True(t, IsNoSuchFile(err))
+	assert.False(t, IsVersionMismatch(err))
+}
+
+func TestClient_PutFile(t *testing.T) {
+	var requests []map[string]interface{}
+	server := filesServer(t, map[string]string{
+		"/api/putFile": `{"status":"success"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("config-token", server.URL, false)
+	require.NoError(t, client.PutFile(context.Background(), PutFileParams{Path: "/a", Content: "x", ExpectedVersion: 3}))
+	require.NoError(t, client.PutFile(context.Background(), PutFileParams{Path: "/b", Content: "y"}))
+	require.NoError(t, client.PutFile(context.Background(), PutFileParams{Path: "/c", Delete: true}))
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, "x", requests[0]["content"])
+	assert.Equal(t, float64(3), requests[0]["expectedVersion"])
+	assert.NotContains(t, requests[1], "expectedVersion", "zero skips the version check")
+	assert.Equal(t, true, requests[2]["deleteFile"])
+	assert.NotContains(t, requests[2], "content")
+}
+
+func TestClient_PutFile_VersionMismatch(t *testing.T) {
+	var requests []map[string]interface{}
+	server := filesServer(t, map[string]string{
+		"/api/putFile": `{"status":"error/client/versionMismatch"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("config-token", server.URL, false)
+	err := client.PutFile(context.Background(), PutFileParams{Path: "/a", Content: "x", ExpectedVersion: 3})
+	require.Error(t, err)
+	assert.True(t, IsVersionMismatch(err))
+	assert.Contains(t, err.Error(), "changed by someone else")
+}
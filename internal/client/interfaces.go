@@ -2,7 +2,10 @@ package client
 
 import (
 	"context"
+	"iter"
 	"net/http"
+
+	"github.com/andreagrandi/logbasset/internal/timerange"
 )
 
 // HTTPClient interface for HTTP operations to enable testing with mocks
@@ -13,11 +16,28 @@ type HTTPClient interface {
 // ClientInterface defines the contract for the API client
 type ClientInterface interface {
 	Query(ctx context.Context, params QueryParams) (*QueryResponse, error)
+	QueryIter(ctx context.Context, params QueryParams) iter.Seq2[LogEvent, error]
+	QueryAll(ctx context.Context, params QueryParams, limit int) iter.Seq2[LogEvent, error]
+	QueryWindows(ctx context.Context, params QueryParams, windows []timerange.Window, limit int, opts ShardOptions) iter.Seq2[LogEvent, error]
 	PowerQuery(ctx context.Context, params PowerQueryParams) (*PowerQueryResponse, error)
+	PowerQueryWindows(ctx context.Context, params PowerQueryParams, windows []timerange.Window, opts ShardOptions) (*PowerQueryResponse, error)
 	NumericQuery(ctx context.Context, params NumericQueryParams) (*NumericQueryResponse, error)
 	FacetQuery(ctx context.Context, params FacetQueryParams) (*FacetQueryResponse, error)
 	TimeseriesQuery(ctx context.Context, params TimeseriesQueryParams) (*TimeseriesQueryResponse, error)
+	TimeseriesQueries(ctx context.Context, params []TimeseriesQueryParams) (*TimeseriesQueryResponse, error)
 	Tail(ctx context.Context, params TailParams, outputChan chan<- LogEvent) error
+	AddEvents(ctx context.Context, params AddEventsParams) (*AddEventsResponse, error)
+	NewEventSession(info map[string]interface{}, threads []Thread) *EventSession
+	UploadLogs(ctx context.Context, params UploadLogsParams, body []byte) error
+	ListFiles(ctx context.Context) (*ListFilesResponse, error)
+	GetFile(ctx context.Context, path string) (*ConfigFile, error)
+	PutFile(ctx context.Context, params PutFileParams) error
+	LaunchQuery(ctx context.Context, req LRQRequest) (*LRQResponse, error)
+	PollQuery(ctx context.Context, handle LRQHandle, lastStepSeen int) (*LRQResponse, error)
+	CancelQuery(ctx context.Context, handle LRQHandle) error
+	RunQuery(ctx context.Context, req LRQRequest, progress func(completed, total int)) (*LRQResponse, error)
+	QueryLRQ(ctx context.Context, params QueryParams, progress func(completed, total int)) (*QueryResponse, error)
+	PowerQueryLRQ(ctx context.Context, params PowerQueryParams, progress func(completed, total int)) (*PowerQueryResponse, error)
 	SetToken(token string)
 }
 
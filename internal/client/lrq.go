@@ -0,0 +1,370 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/logging"
+)
+
+const (
+	// lrqPath is the long-running query endpoint of the v2 API.
+	lrqPath = "/api/v2/query"
+
+	// forwardTagHeader is returned when a query is launched and must be sent
+	// back on every ping and cancel so they reach the node running it.
+	forwardTagHeader = "X-Dataset-Query-Forward-Tag"
+
+	// lrqCancelTimeout bounds the DELETE sent after the caller's context is
+	// already done.
+	lrqCancelTimeout = 5 * time.Second
+)
+
+// LRQ query types.
+const (
+	LRQTypeLog        = "LOG"
+	LRQTypePowerQuery = "PQ"
+)
+
+// lrqPollInterval and lrqMaxPollInterval bound the delay between pings;
+// the delay doubles while the query makes no progress. They are variables so
+// tests can shorten them.
+var (
+	lrqPollInterval    = 250 * time.Millisecond
+	lrqMaxPollInterval = 2 * time.Second
+)
+
+// LRQRequest is the body of a long-running query launch. Exactly one of Log
+// and PowerQuery is set, matching QueryType.
+type LRQRequest struct {
+	QueryType     string                `json:"queryType"`
+	StartTime     string                `json:"startTime,omitempty"`
+	EndTime       string                `json:"endTime,omitempty"`
+	QueryPriority string                `json:"queryPriority,omitempty"`
+	Log           *LRQLogOptions        `json:"log,omitempty"`
+	PowerQuery    *LRQPowerQueryOptions `json:"pq,omitempty"`
+}
+
+type LRQLogOptions struct {
+	Filter string `json:"filter"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+type LRQPowerQueryOptions struct {
+	Query      string `json:"query"`
+	ResultType string `json:"resultType,omitempty"`
+}
+
+// LRQHandle identifies a launched query for PollQuery and CancelQuery.
+type LRQHandle struct {
+	ID         string
+	ForwardTag string
+}
+
+// LRQError is the error reported for a failed query. The API sends either a
+// string or an object with a message.
+type LRQError struct {
+	Message string `json:"message"`
+}
+
+func (e *LRQError) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		e.Message = message
+		return nil
+	}
+	type plain LRQError
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+// LRQResponse reports a long-running query's progress. Data holds the result
+// once StepsCompleted reaches StepsTotal.
+type LRQResponse struct {
+	ID             string          `json:"id"`
+	StepsCompleted int             `json:"stepsCompleted"`
+	StepsTotal     int             `json:"stepsTotal"`
+	Error          *LRQError       `json:"error,omitempty"`
+	Data           json.RawMessage `json:"data,omitempty"`
+
+	forwardTag string
+}
+
+// Done reports whether the query has finished and Data holds the result.
+func (r *LRQResponse) Done() bool {
+	return r.StepsTotal > 0 && r.StepsCompleted >= r.StepsTotal
+}
+
+// Handle returns the handle used to poll or cancel the query.
+func (r *LRQResponse) Handle() LRQHandle {
+	return LRQHandle{ID: r.ID, ForwardTag: r.forwardTag}
+}
+
+// LaunchQuery starts a long-running query on the v2 API.
+func (c *Client) LaunchQuery(ctx context.Context, req LRQRequest) (*LRQResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.NewParseError("failed to marshal request data", err)
+	}
+	result, err := c.lrqRequest(ctx, "POST", lrqPath, body, "")
+	if err != nil {
+		return nil, err
+	}
+	if result.ID == "" {
+		return nil, errors.NewParseError("long-running query response has no id", nil)
+	}
+	return result, nil
+}
+
+// PollQuery reports the progress of a launched query. lastStepSeen lets the
+// server reply only once further steps have completed.
+func (c *Client) PollQuery(ctx context.Context, handle LRQHandle, lastStepSeen int) (*LRQResponse, error) {
+	path := fmt.Sprintf("%s/%s?lastStepSeen=%d", lrqPath, url.PathEscape(handle.ID), lastStepSeen)
+	result, err := c.lrqRequest(ctx, "GET", path, nil, handle.ForwardTag)
+	if err != nil {
+		return nil, err
+	}
+	if result.forwardTag == "" {
+		result.forwardTag = handle.ForwardTag
+	}
+	if result.ID == "" {
+		result.ID = handle.ID
+	}
+	return result, nil
+}
+
+// CancelQuery stops a launched query on the server.
+func (c *Client) CancelQuery(ctx context.Context, handle LRQHandle) error {
+	path := fmt.Sprintf("%s/%s", lrqPath, url.PathEscape(handle.ID))
+	resp, err := c.lrqDo(ctx, "DELETE", path, nil, handle.ForwardTag)
+	if err != nil {
+		return err
+	}
+	drainAndClose(resp.Body)
+	return nil
+}
+
+// RunQuery launches req and polls it until it completes, calling progress
+// (if not nil) whenever the number of completed steps changes. However it
+// returns, the query is then deleted on the server, which stops it when ctx
+// was cancelled or polling failed and frees its result when it completed.
+func (c *Client) RunQuery(ctx context.Context, req LRQRequest, progress func(completed, total int)) (*LRQResponse, error) {
+	result, err := c.LaunchQuery(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	handle := result.Handle()
+	defer c.releaseQuery(handle)
+
+	interval := lrqPollInterval
+	lastStep := -1
+	for {
+		if result.Error != nil {
+			return nil, errors.NewAPIError(result.Error.Message, nil)
+		}
+		if result.StepsCompleted != lastStep {
+			lastStep = result.StepsCompleted
+			interval = lrqPollInterval
+			if progress != nil {
+				progress(result.StepsCompleted, result.StepsTotal)
+			}
+		} else if interval *= 2; interval > lrqMaxPollInterval {
+			interval = lrqMaxPollInterval
+		}
+		if result.Done() {
+			return result, nil
+		}
+
+		if err := sleepWithContext(ctx, interval); err != nil {
+			return nil, errors.NewContextError("query was cancelled or timed out", err)
+		}
+
+		result, err = c.PollQuery(ctx, handle, lastStep)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// releaseQuery deletes a launched query under its own short timeout, since
+// the caller's context may already be cancelled, so the query does not keep
+// consuming account resources.
+func (c *Client) releaseQuery(handle LRQHandle) {
+	ctx, cancel := context.WithTimeout(context.Background(), lrqCancelTimeout)
+	defer cancel()
+	if err := c.CancelQuery(ctx, handle); err != nil && c.verbose {
+		logging.WithField("error", err.Error()).Debug("Failed to cancel long-running query")
+	}
+}
+
+// QueryLRQ runs a log query through the long-running query API.
+func (c *Client) QueryLRQ(ctx context.Context, params QueryParams, progress func(completed, total int)) (*QueryResponse, error) {
+	result, err := c.RunQuery(ctx, LRQRequest{
+		QueryType:     LRQTypeLog,
+		StartTime:     params.StartTime,
+		EndTime:       params.EndTime,
+		QueryPriority: strings.ToUpper(params.Priority),
+		Log:           &LRQLogOptions{Filter: params.Filter, Limit: params.Count},
+	}, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Matches []lrqLogMatch `json:"matches"`
+	}
+	if len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, &data); err != nil {
+			return nil, errors.NewParseError("failed to parse long-running query result", err)
+		}
+	}
+
+	matches := make([]LogEvent, len(data.Matches))
+	for i, m := range data.Matches {
+		matches[i] = m.event()
+	}
+	return &QueryResponse{Status: "success", Matches: matches}, nil
+}
+
+// PowerQueryLRQ runs a PowerQuery through the long-running query API.
+func (c *Client) PowerQueryLRQ(ctx context.Context, params PowerQueryParams, progress func(completed, total int)) (*PowerQueryResponse, error) {
+	result, err := c.RunQuery(ctx, LRQRequest{
+		QueryType:     LRQTypePowerQuery,
+		StartTime:     params.StartTime,
+		EndTime:       params.EndTime,
+		QueryPriority: strings.ToUpper(params.Priority),
+		PowerQuery:    &LRQPowerQueryOptions{Query: params.Query, ResultType: "TABLE"},
+	}, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &PowerQueryResponse{Status: "success"}
+	if len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, response); err != nil {
+			return nil, errors.NewParseError("failed to parse long-running query result", err)
+		}
+	}
+	response.Status = "success"
+	if response.Columns == nil {
+		response.Columns = []PowerQueryColumn{}
+	}
+	return response, nil
+}
+
+// lrqLogMatch is an event in a LOG query result. Timestamps are numbers and
+// the server's attributes are reported separately from the event's.
+type lrqLogMatch struct {
+	Timestamp  json.Number            `json:"timestamp"`
+	Severity   int                    `json:"severity"`
+	Message    string                 `json:"message"`
+	Thread     string                 `json:"thread,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	ServerInfo map[string]interface{} `json:"serverInfo,omitempty"`
+}
+
+func (m lrqLogMatch) event() LogEvent {
+	attrs := m.Attributes
+	if len(m.ServerInfo) > 0 {
+		attrs = make(map[string]interface{}, len(m.Attributes)+len(m.ServerInfo))
+		for k, v := range m.ServerInfo {
+			attrs[k] = v
+		}
+		for k, v := range m.Attributes {
+			attrs[k] = v
+		}
+	}
+	return LogEvent{
+		Timestamp:  m.Timestamp.String(),
+		Severity:   m.Severity,
+		Message:    m.Message,
+		Thread:     m.Thread,
+		Attributes: attrs,
+	}
+}
+
+func (c *Client) lrqRequest(ctx context.Context, method, path string, body []byte, forwardTag string) (*LRQResponse, error) {
+	resp, err := c.lrqDo(ctx, method, path, body, forwardTag)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result LRQResponse
+	if err := c.decodeResponse(resp.Body, &result); err != nil {
+		return nil, err
+	}
+	result.forwardTag = resp.Header.Get(forwardTagHeader)
+	return &result, nil
+}
+
+// lrqDo sends a v2 API request, which authenticates with a bearer token
+// rather than a token in the body. Non-2xx responses become errors.
+func (c *Client) lrqDo(ctx context.Context, method, path string, body []byte, forwardTag string) (*http.Response, error) {
+	if c.token == "" {
+		return nil, errors.NewAuthError("API token is required", nil)
+	}
+
+	requestURL := c.server + path
+	if c.verbose {
+		fields := map[string]any{"url": requestURL, "method": method}
+		if body != nil {
+			fields["request_data"] = string(body)
+		}
+		logging.WithFields(fields).Debug("Making HTTP request")
+	}
+
+	resp, err := c.doWithRetry(ctx, c.retryPolicy, func() (*http.Request, error) {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if forwardTag != "" {
+			req.Header.Set(forwardTagHeader, forwardTag)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer drainAndClose(resp.Body)
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	message := strings.TrimSpace(string(snippet))
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(snippet, &apiErr) == nil && apiErr.Message != "" {
+		message = apiErr.Message
+	}
+	if message == "" {
+		message = "server returned status " + strconv.Itoa(resp.StatusCode)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, errors.NewAuthError(message, nil)
+	default:
+		return nil, errors.NewAPIError(message, nil)
+	}
+}
@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lrqServer is a fake v2 query endpoint that completes a query after steps
+// pings and records every request it receives.
+type lrqServer struct {
+	t     *testing.T
+	steps int
+	data  string
+
+	mu       sync.Mutex
+	pings    int
+	launch   map[string]interface{}
+	requests []*http.Request
+}
+
+func (s *lrqServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+
+	assert.Equal(s.t, "Bearer read-token", r.Header.Get("Authorization"))
+	switch r.Method {
+	case "POST":
+		assert.Equal(s.t, "/api/v2/query", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(s.t, json.Unmarshal(body, &s.launch))
+		w.Header().Set(forwardTagHeader, "node-7")
+		w.Write([]byte(`{"id":"q1","stepsCompleted":0,"stepsTotal":` + strconv.Itoa(s.steps) + `}`))
+	case "GET":
+		assert.Equal(s.t, "/api/v2/query/q1", r.URL.Path)
+		assert.Equal(s.t, "node-7", r.Header.Get(forwardTagHeader))
+		s.pings++
+		if s.pings >= s.steps {
+			w.Write([]byte(`{"id":"q1","stepsCompleted":` + strconv.Itoa(s.steps) + `,"stepsTotal":` + strconv.Itoa(s.steps) + `,"data":` + s.data + `}`))
+			return
+		}
+		w.Write([]byte(`{"id":"q1","stepsCompleted":` + strconv.Itoa(s.pings) + `,"stepsTotal":` + strconv.Itoa(s.steps) + `}`))
+	case "DELETE":
+		assert.Equal(s.t, "/api/v2/query/q1", r.URL.Path)
+		assert.Equal(s.t, "node-7", r.Header.Get(forwardTagHeader))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *lrqServer) methods() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var methods []string
+	for _, r := range s.requests {
+		methods = append(methods, r.Method)
+	}
+	return methods
+}
+
+func fastLRQPolling(t *testing.T) {
+	t.Helper()
+	interval, maxInterval := lrqPollInterval, lrqMaxPollInterval
+	lrqPollInterval, lrqMaxPollInterval = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { lrqPollInterval, lrqMaxPollInterval = interval, maxInterval })
+}
+
+func TestClient_PowerQueryLRQ(t *testing.T) {
+	fastLRQPolling(t)
+	fake := &lrqServer{t: t, steps: 3, data: `{"columns":[{"name":"status"},{"name":"count"}],"values":[["200",12],["500",3]],"warnings":["partial"]}`}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var progress [][2]int
+	client := New("read-token", server.URL, false)
+	resp, err := client.PowerQueryLRQ(context.Background(), PowerQueryParams{
+		Query:     "dataset = 'accesslog' | group count() by status",
+		StartTime: "1h",
+		Priority:  "low",
+	}, func(completed, total int) {
+		progress = append(progress, [2]int{completed, total})
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "success", resp.Status)
+	assert.Equal(t, []PowerQueryColumn{{Name: "status"}, {Name: "count"}}, resp.Columns)
+	assert.Len(t, resp.Values, 2)
+	assert.Equal(t, []string{"partial"}, resp.Warnings)
+	assert.Equal(t, [][2]int{{0, 3}, {1, 3}, {2, 3}, {3, 3}}, progress)
+
+	assert.Equal(t, "PQ", fake.launch["queryType"])
+	assert.Equal(t, "1h", fake.launch["startTime"])
+	assert.Equal(t, "LOW", fake.launch["queryPriority"])
+	pq := fake.launch["pq"].(map[string]interface{})
+	assert.Equal(t, "dataset = 'accesslog' | group count() by status", pq["query"])
+	assert.Equal(t, "TABLE", pq["resultType"])
+
+	methods := fake.methods()
+	assert.Equal(t, "DELETE", methods[len(methods)-1], "the completed query is released on the server")
+}
+
+func TestClient_QueryLRQ(t *testing.T) {
+	fastLRQPolling(t)
+	fake := &lrqServer{t: t, steps: 1, data: `{"matches":[{"timestamp":1700000000000000000,"severity":3,"message":"hello","attributes":{"app":"web"},"serverInfo":{"serverHost":"h1"}}]}`}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := New("read-token", server.URL, false)
+	resp, err := client.QueryLRQ(context.Background(), QueryParams{Filter: "error", StartTime: "24h", Count: 50}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, resp.Matches, 1)
+	event := resp.Matches[0]
+	assert.Equal(t, "1700000000000000000", event.Timestamp)
+	assert.Equal(t, "hello", event.Message)
+	assert.Equal(t, map[string]interface{}{"app": "web", "serverHost": "h1"}, event.Attributes)
+
+	log := fake.launch["log"].(map[string]interface{})
+	assert.Equal(t, "error", log["filter"])
+	assert.Equal(t, float64(50), log["limit"])
+}
+
+func TestClient_RunQuery_CancelsOnContextDone(t *testing.T) {
+	fastLRQPolling(t)
+	fake := &lrqServer{t: t, steps: 1000}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := New("read-token", server.URL, false)
+	_, err := client.RunQuery(ctx, LRQRequest{QueryType: LRQTypeLog}, func(completed, total int) {
+		if completed == 2 {
+			cancel()
+		}
+	})
+	require.Error(t, err)
+
+	methods := fake.methods()
+	assert.Equal(t, "DELETE", methods[len(methods)-1], "the server-side query is cancelled")
+}
+
+func TestClient_RunQuery_QueryError(t *testing.T) {
+	fastLRQPolling(t)
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		switch r.Method {
+		case "POST":
+			w.Write([]byte(`{"id":"q1","stepsCompleted":0,"stepsTotal":2}`))
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"id":"q1","stepsCompleted":1,"stepsTotal":2,"error":{"message":"bad query syntax"}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := New("read-token", server.URL, false)
+	_, err := client.RunQuery(context.Background(), LRQRequest{QueryType: LRQTypePowerQuery}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad query syntax")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"POST", "GET", "DELETE"}, methods, "the failed query is released on the server")
+}
+
+func TestClient_RunQuery_ReleasesAfterPollFailure(t *testing.T) {
+	fastLRQPolling(t)
+	var (
+		mu      sync.Mutex
+		methods []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		switch r.Method {
+		case "POST":
+			w.Write([]byte(`{"id":"q1","stepsCompleted":0,"stepsTotal":2}`))
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"unknown query"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := New("read-token", server.URL, false)
+	client.SetRetryPolicy(RetryPolicy{})
+	_, err := client.RunQuery(context.Background(), LRQRequest{QueryType: LRQTypeLog}, nil)
+	require.Error(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "DELETE", methods[len(methods)-1], "a query whose polling failed is released on the server")
+}
+
+func TestClient_LaunchQuery_HTTPErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantMsg string
+	}{
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"message":"invalid token"}`, wantMsg: "invalid token"},
+		{name: "bad request", status: http.StatusBadRequest, body: `filter is required`, wantMsg: "filter is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := New("read-token", server.URL, false)
+			_, err := client.LaunchQuery(context.Background(), LRQRequest{QueryType: LRQTypeLog})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+}
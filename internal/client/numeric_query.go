@@ -1,13 +1,6 @@
 package client
 
-import (
-	"context"
-	"encoding/json"
-	"io"
-
-	"github.com/andreagrandi/logbasset/internal/errors"
-	"github.com/andreagrandi/logbasset/internal/logging"
-)
+import "context"
 
 func (c *Client) NumericQuery(ctx context.Context, params NumericQueryParams) (*NumericQueryResponse, error) {
 	requestParams := map[string]interface{}{
@@ -37,22 +30,13 @@ func (c *Client) NumericQuery(ctx context.Context, params NumericQueryParams) (*
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.NewNetworkError("failed to read response body", err)
-	}
-
-	if c.verbose {
-		logging.WithField("response_body", string(body)).Debug("API response received")
-	}
-
 	var result NumericQueryResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, errors.NewParseError("failed to parse response", err)
+	if err := c.decodeResponse(resp.Body, &result); err != nil {
+		return nil, err
 	}
 
 	if result.Status != "success" {
-		return nil, errors.NewAPIError(result.Message, nil)
+		return nil, apiStatusError(result.Status, result.Message)
 	}
 
 	return &result, nil
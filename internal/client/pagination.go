@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"iter"
+)
+
+// MaxPageSize is the largest maxCount the Scalyr query API accepts for a
+// single request.
+const MaxPageSize = 5000
+
+// QueryAll runs a log query and follows continuationToken across requests,
+// yielding events as they are decoded from each page. Paging stops once limit
+// events have been yielded (limit <= 0 means no limit), the server stops
+// returning a continuation token or matches, or the caller stops iterating.
+// A request, parse or API error is yielded once as the final element.
+// params.PageTimeout, when set, applies to each page request separately.
+//
+// Per the Scalyr API docs every follow-up request repeats the original
+// filter, startTime, endTime and pageMode alongside the continuation token.
+func (c *Client) QueryAll(ctx context.Context, params QueryParams, limit int) iter.Seq2[LogEvent, error] {
+	return func(yield func(LogEvent, error) bool) {
+		delivered := 0
+
+		for {
+			pageParams := params
+			pageParams.Count = MaxPageSize
+			if limit > 0 && limit-delivered < pageParams.Count {
+				pageParams.Count = limit - delivered
+			}
+
+			done := false
+			pageEvents := 0
+			pageCtx, cancel := ctx, context.CancelFunc(func() {})
+			if params.PageTimeout > 0 {
+				pageCtx, cancel = context.WithTimeout(ctx, params.PageTimeout)
+			}
+			page, err := c.streamQuery(pageCtx, pageParams, func(event LogEvent) bool {
+				if !yield(event, nil) {
+					done = true
+					return false
+				}
+				pageEvents++
+				delivered++
+				if limit > 0 && delivered >= limit {
+					done = true
+					return false
+				}
+				return true
+			})
+			cancel()
+			if done {
+				return
+			}
+			if err != nil {
+				yield(LogEvent{}, err)
+				return
+			}
+
+			if page.ContinuationToken == "" || pageEvents == 0 {
+				return
+			}
+
+			params.ContinuationToken = page.ContinuationToken
+		}
+	}
+}
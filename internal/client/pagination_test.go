@@ -0,0 +1,270 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedServer replies to successive query requests with the given pages,
+// recording each decoded request body.
+func pagedServer(t *testing.T, pages []string, requests *[]map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var reqData map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &reqData))
+		*requests = append(*requests, reqData)
+
+		w.Header().Set("Content-Type", "application/json")
+		idx := len(*requests) - 1
+		if !assert.Less(t, idx, len(pages), "unexpected extra page request") {
+			w.Write([]byte(`{"status":"error","message":"no more pages"}`))
+			return
+		}
+		w.Write([]byte(pages[idx]))
+	}))
+}
+
+func page(token string, messages ...string) string {
+	matches := make([]LogEvent, len(messages))
+	for i, m := range messages {
+		matches[i] = LogEvent{Timestamp: fmt.Sprintf("%d", i), Severity: 3, Message: m}
+	}
+	data, _ := json.Marshal(QueryResponse{Status: "success", Matches: matches, ContinuationToken: token})
+	return string(data)
+}
+
+// collectMessages drains an event iterator, returning the messages seen before
+// the first error.
+func collectMessages(seq iter.Seq2[LogEvent, error]) ([]string, error) {
+	var messages []string
+	for event, err := range seq {
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, event.Message)
+	}
+	return messages, nil
+}
+
+func TestClient_QueryAll_FollowsContinuationToken(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{
+		page("tok-1", "a", "b"),
+		page("tok-2", "c"),
+		page("", "d"),
+	}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	messages, err := collectMessages(client.QueryAll(context.Background(), QueryParams{
+		Filter:    "error",
+		StartTime: "24h",
+		EndTime:   "NOW",
+		Mode:      "head",
+		Priority:  "low",
+	}, 0))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, messages)
+	require.Len(t, requests, 3)
+
+	assert.NotContains(t, requests[0], "continuationToken")
+	assert.Equal(t, "tok-1", requests[1]["continuationToken"])
+	assert.Equal(t, "tok-2", requests[2]["continuationToken"])
+
+	for _, req := range requests {
+		assert.Equal(t, "error", req["filter"], "filter must be repeated on every page")
+		assert.Equal(t, "24h", req["startTime"], "startTime must be repeated on every page")
+		assert.Equal(t, "NOW", req["endTime"], "endTime must be repeated on every page")
+		assert.Equal(t, "head", req["pageMode"], "pageMode must be repeated on every page")
+		assert.Equal(t, "low", req["priority"])
+		assert.Equal(t, float64(MaxPageSize), req["maxCount"])
+	}
+}
+
+func TestClient_QueryAll_RespectsLimit(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{
+		page("tok-1", "a", "b", "c"),
+		page("tok-2", "d", "e", "f"),
+	}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	messages, err := collectMessages(client.QueryAll(context.Background(), QueryParams{Filter: "x"}, 5))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, messages)
+	require.Len(t, requests, 2)
+	assert.Equal(t, float64(5), requests[0]["maxCount"])
+	assert.Equal(t, float64(2), requests[1]["maxCount"], "last page should only ask for the remainder")
+}
+
+func TestClient_QueryAll_StopsOnEmptyPage(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{
+		page("tok-1", "a"),
+		page("tok-2"),
+	}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	messages, err := collectMessages(client.QueryAll(context.Background(), QueryParams{}, 0))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, messages)
+	assert.Len(t, requests, 2)
+}
+
+func TestClient_QueryAll_BreakStopsPaging(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{
+		page("tok-1", "a", "b"),
+		page("", "c"),
+	}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	var messages []string
+	for event, err := range client.QueryAll(context.Background(), QueryParams{}, 0) {
+		require.NoError(t, err)
+		messages = append(messages, event.Message)
+		break
+	}
+
+	assert.Equal(t, []string{"a"}, messages)
+	assert.Len(t, requests, 1)
+}
+
+func TestClient_QueryAll_APIError(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{
+		page("tok-1", "a"),
+		`{"status":"error","message":"continuation token expired"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	messages, err := collectMessages(client.QueryAll(context.Background(), QueryParams{}, 0))
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"a"}, messages)
+	assert.Contains(t, err.Error(), "continuation token expired")
+}
+
+func TestClient_QueryIter_StreamsEvents(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{
+		// status after matches and an unknown key, to exercise token-level decoding
+		`{"matches":[{"timestamp":"1","severity":3,"message":"a","attributes":{"host":"web-01"}},` +
+			`{"timestamp":"2","severity":4,"message":"b"}],"extra":{"nested":[1,2]},"status":"success"}`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	var events []LogEvent
+	for event, err := range client.QueryIter(context.Background(), QueryParams{Filter: "x"}) {
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "a", events[0].Message)
+	assert.Equal(t, "web-01", events[0].Attributes["host"])
+	assert.Equal(t, 4, events[1].Severity)
+	assert.Equal(t, "x", requests[0]["filter"])
+}
+
+func TestClient_QueryIter_APIError(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{`{"status":"error","message":"bad filter"}`}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	messages, err := collectMessages(client.QueryIter(context.Background(), QueryParams{}))
+	require.Error(t, err)
+	assert.Empty(t, messages)
+	assert.Contains(t, err.Error(), "bad filter")
+}
+
+func TestClient_QueryIter_TruncatedBody(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{
+		`{"status":"success","matches":[{"timestamp":"1","severity":3,"message":"a"},{"timest`,
+	}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	messages, err := collectMessages(client.QueryIter(context.Background(), QueryParams{}))
+	require.Error(t, err)
+	assert.Equal(t, []string{"a"}, messages, "events decoded before the error are still delivered")
+	assert.Contains(t, err.Error(), "failed to parse response")
+}
+
+func TestClient_Query_EmptyMatchesIsEmptySlice(t *testing.T) {
+	var requests []map[string]interface{}
+	server := pagedServer(t, []string{`{"status":"success","matches":[]}`}, &requests)
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	result, err := client.Query(context.Background(), QueryParams{})
+	require.NoError(t, err)
+	assert.NotNil(t, result.Matches)
+	assert.Empty(t, result.Matches)
+}
+
+func TestClient_QueryAll_PageTimeoutIsPerPage(t *testing.T) {
+	pages := []string{page("tok-1", "a"), page("tok-2", "b"), page("", "c")}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		time.Sleep(60 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(pages[requests]))
+		requests++
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	started := time.Now()
+	messages, err := collectMessages(client.QueryAll(context.Background(), QueryParams{Filter: "x", PageTimeout: 150 * time.Millisecond}, 0))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, messages)
+	assert.Greater(t, time.Since(started), 150*time.Millisecond,
+		"the pages together took longer than PageTimeout, which only bounds each page")
+}
+
+func TestClient_QueryAll_PageTimeoutExpires(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	client.SetRetryPolicy(RetryPolicy{})
+	_, err := collectMessages(client.QueryAll(context.Background(), QueryParams{Filter: "x", PageTimeout: 50 * time.Millisecond}, 0))
+	assert.Error(t, err, "a page slower than PageTimeout fails")
+}
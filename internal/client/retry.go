@@ -2,10 +2,15 @@ package client
 
 import (
 	"context"
+	stderrors "errors"
 	"math/rand"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/logging"
 )
 
 const (
@@ -87,3 +92,76 @@ func sleepWithContext(ctx context.Context, d time.Duration) error {
 		return nil
 	}
 }
+
+// statusError records the status string of an API response that did not
+// succeed, e.g. "error/server/backoff" or "error/client/badParam".
+type statusError struct {
+	status string
+}
+
+func (e *statusError) Error() string {
+	return "API status " + e.status
+}
+
+// apiStatusError builds the error returned for a non-success API response,
+// keeping the raw status as the cause so retry logic can tell transient
+// server-side failures apart from bad requests.
+func apiStatusError(status, message string) error {
+	var cause error
+	if status != "" {
+		cause = &statusError{status: status}
+	}
+	return errors.NewAPIError(message, cause)
+}
+
+// isRetryableError reports whether a whole operation is worth running again:
+// network failures that outlived makeRequest's own retries, and API errors
+// whose status the server marks as transient ("error/server/...").
+func isRetryableError(err error) bool {
+	var lbErr *errors.LogBassetError
+	if !stderrors.As(err, &lbErr) {
+		return false
+	}
+	switch lbErr.Type {
+	case errors.NetworkError:
+		return true
+	case errors.APIError:
+		var se *statusError
+		return stderrors.As(lbErr.Cause, &se) && strings.HasPrefix(se.status, "error/server")
+	default:
+		return false
+	}
+}
+
+// retryOperation runs fn, re-running it with the client's RetryPolicy backoff
+// while it fails with a retryable error.
+func (c *Client) retryOperation(ctx context.Context, fn func() error) error {
+	policy := c.retryPolicy
+	if policy.MaxRetries < 0 {
+		policy.MaxRetries = 0
+	}
+
+	var err error
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := policy.backoffDelay(attempt - 1)
+			if c.verbose {
+				logging.WithFields(map[string]any{
+					"attempt":     attempt,
+					"delay":       delay.String(),
+					"max_retries": policy.MaxRetries,
+					"error":       err.Error(),
+				}).Debug("Retrying operation after transient failure")
+			}
+			if sleepErr := sleepWithContext(ctx, delay); sleepErr != nil {
+				return errors.NewContextError("request was cancelled or timed out", sleepErr)
+			}
+		}
+
+		err = fn()
+		if err == nil || ctx.Err() != nil || !isRetryableError(err) {
+			return err
+		}
+	}
+	return err
+}
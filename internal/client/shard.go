@@ -0,0 +1,228 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"iter"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/timerange"
+)
+
+// ShardOptions controls how a query split into time windows fans out.
+type ShardOptions struct {
+	// Parallel is the maximum number of windows queried at once.
+	Parallel int
+	// Timeout bounds each attempt at a single window; zero means no limit
+	// beyond the parent context.
+	Timeout time.Duration
+}
+
+type shardTask struct {
+	done chan struct{}
+	err  error
+}
+
+// startShards runs fn for shards 0..n-1 on at most opts.Parallel goroutines,
+// dispatching them in order. Each shard attempt gets its own opts.Timeout and
+// transient failures, including an attempt that runs out of that timeout, are
+// re-run according to the client's RetryPolicy. Every
+// task's done channel is closed once its shard has finished, so callers can
+// consume results in shard order while later shards are still running.
+func (c *Client) startShards(ctx context.Context, n int, opts ShardOptions, fn func(ctx context.Context, shard int) error) []*shardTask {
+	tasks := make([]*shardTask, n)
+	for i := range tasks {
+		tasks[i] = &shardTask{done: make(chan struct{})}
+	}
+
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	sem := make(chan struct{}, parallel)
+
+	go func() {
+		for i := range tasks {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				for _, task := range tasks[i:] {
+					task.err = errors.NewContextError("request was cancelled or timed out", ctx.Err())
+					close(task.done)
+				}
+				return
+			}
+
+			go func(i int) {
+				defer func() { <-sem }()
+				tasks[i].err = c.retryOperation(ctx, func() error {
+					shardCtx := ctx
+					if opts.Timeout > 0 {
+						var cancel context.CancelFunc
+						shardCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+						defer cancel()
+					}
+					err := fn(shardCtx, i)
+					if err != nil && ctx.Err() == nil && shardCtx.Err() == context.DeadlineExceeded {
+						// Only this window's own timeout expired, so it is
+						// worth another attempt like any transient failure
+						return errors.NewNetworkError("window timed out after "+opts.Timeout.String(), err)
+					}
+					return err
+				})
+				close(tasks[i].done)
+			}(i)
+		}
+	}()
+
+	return tasks
+}
+
+// QueryWindows runs the log query once per window with bounded parallelism
+// and yields the merged events in timestamp order, up to limit events in
+// total (limit <= 0 yields every event). Each window fetches at most limit
+// events, following continuation tokens, and once limit events have been
+// yielded the windows still running are cancelled. Windows must be ordered
+// and non-overlapping: a window's events are held until every earlier window
+// has been yielded, then sorted by timestamp. A failed window is yielded as an
+// error once the windows before it are done, and stops the iteration.
+func (c *Client) QueryWindows(ctx context.Context, params QueryParams, windows []timerange.Window, limit int, opts ShardOptions) iter.Seq2[LogEvent, error] {
+	return func(yield func(LogEvent, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		results := make([][]LogEvent, len(windows))
+		tasks := c.startShards(ctx, len(windows), opts, func(ctx context.Context, shard int) error {
+			shardParams := params
+			shardParams.StartTime = formatAPITime(windows[shard].Start)
+			shardParams.EndTime = formatAPITime(windows[shard].End)
+
+			var events []LogEvent
+			for event, err := range c.QueryAll(ctx, shardParams, limit) {
+				if err != nil {
+					return err
+				}
+				events = append(events, event)
+			}
+			results[shard] = events
+			return nil
+		})
+
+		yielded := 0
+		for i, task := range tasks {
+			<-task.done
+			if task.err != nil {
+				yield(LogEvent{}, task.err)
+				return
+			}
+
+			events := results[i]
+			results[i] = nil
+			sortEventsByTime(events)
+			for _, event := range events {
+				if !yield(event, nil) {
+					return
+				}
+				yielded++
+				if limit > 0 && yielded >= limit {
+					return
+				}
+			}
+		}
+	}
+}
+
+// PowerQueryWindows runs the PowerQuery once per window with bounded
+// parallelism and concatenates the resulting rows in window order. When the
+// result has a "timestamp" column the rows are additionally sorted by it.
+// Aggregates are not recombined, so a "group by" yields one set of groups per
+// window. Every window must return the same columns.
+func (c *Client) PowerQueryWindows(ctx context.Context, params PowerQueryParams, windows []timerange.Window, opts ShardOptions) (*PowerQueryResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*PowerQueryResponse, len(windows))
+	tasks := c.startShards(ctx, len(windows), opts, func(ctx context.Context, shard int) error {
+		shardParams := params
+		shardParams.StartTime = formatAPITime(windows[shard].Start)
+		shardParams.EndTime = formatAPITime(windows[shard].End)
+
+		result, err := c.PowerQuery(ctx, shardParams)
+		if err != nil {
+			return err
+		}
+		results[shard] = result
+		return nil
+	})
+
+	merged := &PowerQueryResponse{Status: "success"}
+	for i, task := range tasks {
+		<-task.done
+		if task.err != nil {
+			return nil, task.err
+		}
+
+		result := results[i]
+		if merged.Columns == nil {
+			merged.Columns = result.Columns
+		} else if len(result.Columns) > 0 && !slices.Equal(merged.Columns, result.Columns) {
+			return nil, errors.NewParseError("time windows returned different PowerQuery columns", nil)
+		}
+		merged.MatchingEvents += result.MatchingEvents
+		merged.OmittedEvents += result.OmittedEvents
+		merged.Values = append(merged.Values, result.Values...)
+		merged.Warnings = append(merged.Warnings, result.Warnings...)
+	}
+
+	for i, col := range merged.Columns {
+		if col.Name == "timestamp" {
+			slices.SortStableFunc(merged.Values, func(a, b []interface{}) int {
+				return cmp.Compare(cellTimestamp(a, i), cellTimestamp(b, i))
+			})
+			break
+		}
+	}
+
+	return merged, nil
+}
+
+// formatAPITime renders an absolute time as nanoseconds since the epoch,
+// which the Scalyr API accepts for startTime and endTime.
+func formatAPITime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func sortEventsByTime(events []LogEvent) {
+	slices.SortStableFunc(events, func(a, b LogEvent) int {
+		return cmp.Compare(parseEventTimestamp(a.Timestamp), parseEventTimestamp(b.Timestamp))
+	})
+}
+
+// parseEventTimestamp converts a Scalyr timestamp (nanoseconds since the
+// epoch, or RFC 3339) to nanoseconds. Unparseable values sort first.
+func parseEventTimestamp(ts string) int64 {
+	if nanos, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		return nanos
+	}
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		return t.UnixNano()
+	}
+	return 0
+}
+
+func cellTimestamp(row []interface{}, col int) int64 {
+	if col >= len(row) {
+		return 0
+	}
+	switch v := row[col].(type) {
+	case float64:
+		return int64(v)
+	case string:
+		return parseEventTimestamp(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,286 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/timerange"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var shardBase = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+func hourlyWindows(n int) []timerange.Window {
+	return timerange.Split(timerange.Window{
+		Start: shardBase,
+		End:   shardBase.Add(time.Duration(n) * time.Hour),
+	}, time.Hour)
+}
+
+// windowIndex maps a request's startTime back to the hourly window it covers.
+func windowIndex(t *testing.T, req map[string]interface{}) int {
+	nanos, err := strconv.ParseInt(req["startTime"].(string), 10, 64)
+	require.NoError(t, err)
+	return int(time.Unix(0, nanos).Sub(shardBase) / time.Hour)
+}
+
+func decodeRequest(t *testing.T, r *http.Request) map[string]interface{} {
+	body, _ := io.ReadAll(r.Body)
+	var req map[string]interface{}
+	assert.NoError(t, json.Unmarshal(body, &req))
+	return req
+}
+
+func TestClient_QueryWindows_MergesInTimestampOrder(t *testing.T) {
+	var (
+		inFlight    atomic.Int32
+		maxInFlight atomic.Int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cur := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			prev := maxInFlight.Load()
+			if cur <= prev || maxInFlight.CompareAndSwap(prev, cur) {
+				break
+			}
+		}
+
+		req := decodeRequest(t, r)
+		idx := windowIndex(t, req)
+		assert.Equal(t, "error", req["filter"])
+
+		// Later windows answer faster, so completion order is reversed.
+		time.Sleep(time.Duration(4-idx) * 5 * time.Millisecond)
+
+		start := shardBase.Add(time.Duration(idx) * time.Hour)
+		// Events within a window are returned newest first to check the sort.
+		resp := QueryResponse{Status: "success", Matches: []LogEvent{
+			{Timestamp: strconv.FormatInt(start.Add(2*time.Minute).UnixNano(), 10), Message: fmt.Sprintf("w%d-b", idx)},
+			{Timestamp: strconv.FormatInt(start.Add(time.Minute).UnixNano(), 10), Message: fmt.Sprintf("w%d-a", idx)},
+		}}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	messages, err := collectMessages(client.QueryWindows(context.Background(),
+		QueryParams{Filter: "error"}, hourlyWindows(4), 0, ShardOptions{Parallel: 2}))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"w0-a", "w0-b", "w1-a", "w1-b", "w2-a", "w2-b", "w3-a", "w3-b"}, messages)
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(2), "parallelism must be bounded")
+}
+
+func TestClient_QueryWindows_LimitSpansWindows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idx := windowIndex(t, decodeRequest(t, r))
+		if idx >= 2 {
+			// Later windows are not needed, so they must be cancelled
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+
+		start := shardBase.Add(time.Duration(idx) * time.Hour)
+		resp := QueryResponse{Status: "success", Matches: []LogEvent{
+			{Timestamp: strconv.FormatInt(start.Add(time.Minute).UnixNano(), 10), Message: fmt.Sprintf("w%d-a", idx)},
+			{Timestamp: strconv.FormatInt(start.Add(2*time.Minute).UnixNano(), 10), Message: fmt.Sprintf("w%d-b", idx)},
+		}}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	client.SetRetryPolicy(RetryPolicy{})
+
+	started := time.Now()
+	messages, err := collectMessages(client.QueryWindows(context.Background(),
+		QueryParams{Filter: "error"}, hourlyWindows(4), 3, ShardOptions{Parallel: 4}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"w0-a", "w0-b", "w1-a"}, messages, "the limit applies to all windows together")
+	assert.Less(t, time.Since(started), 2*time.Second, "windows still running are cancelled at the limit")
+}
+
+func TestClient_QueryWindows_SendsWindowBounds(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := decodeRequest(t, r)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		w.Write([]byte(`{"status":"success","matches":[]}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	windows := hourlyWindows(3)
+
+	_, err := collectMessages(client.QueryWindows(context.Background(), QueryParams{}, windows, 100, ShardOptions{Parallel: 3}))
+	require.NoError(t, err)
+	require.Len(t, requests, 3)
+
+	seen := map[int]bool{}
+	for _, req := range requests {
+		idx := windowIndex(t, req)
+		seen[idx] = true
+		assert.Equal(t, formatAPITime(windows[idx].End), req["endTime"])
+		assert.Equal(t, float64(100), req["maxCount"], "limit applies to each window")
+	}
+	assert.Len(t, seen, 3)
+}
+
+func TestClient_QueryWindows_RetriesTransientShardFailure(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Write([]byte(`{"status":"error/server/backoff","message":"too busy"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success","matches":[{"timestamp":"1","message":"ok"}]}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	client.SetRetryPolicy(fastRetryPolicy(2))
+
+	messages, err := collectMessages(client.QueryWindows(context.Background(), QueryParams{}, hourlyWindows(1), 0, ShardOptions{Parallel: 1}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ok"}, messages)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_QueryWindows_RetriesShardTimeout(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if calls.Add(1) == 1 {
+			// The first attempt outlives the per-window timeout
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"status":"success","matches":[{"timestamp":"1","message":"ok"}]}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	client.SetRetryPolicy(fastRetryPolicy(2))
+
+	messages, err := collectMessages(client.QueryWindows(context.Background(), QueryParams{}, hourlyWindows(1), 0,
+		ShardOptions{Parallel: 1, Timeout: 50 * time.Millisecond}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ok"}, messages)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_QueryWindows_ClientErrorNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"status":"error/client/badParam","message":"bad filter"}`))
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+	client.SetRetryPolicy(fastRetryPolicy(3))
+
+	_, err := collectMessages(client.QueryWindows(context.Background(), QueryParams{}, hourlyWindows(1), 0, ShardOptions{Parallel: 1}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad filter")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_PowerQueryWindows_MergesRows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := decodeRequest(t, r)
+		idx := windowIndex(t, req)
+		assert.Equal(t, "/api/powerQuery", r.URL.Path)
+
+		resp := PowerQueryResponse{
+			Status:         "success",
+			MatchingEvents: 10,
+			Columns:        []PowerQueryColumn{{Name: "uriPath"}, {Name: "count"}},
+			Values:         [][]interface{}{{fmt.Sprintf("/w%d", idx), float64(idx)}},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	result, err := client.PowerQueryWindows(context.Background(), PowerQueryParams{Query: "x"}, hourlyWindows(3), ShardOptions{Parallel: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "success", result.Status)
+	assert.Equal(t, float64(30), result.MatchingEvents)
+	assert.Equal(t, []PowerQueryColumn{{Name: "uriPath"}, {Name: "count"}}, result.Columns)
+	assert.Equal(t, [][]interface{}{{"/w0", float64(0)}, {"/w1", float64(1)}, {"/w2", float64(2)}}, result.Values)
+}
+
+func TestClient_PowerQueryWindows_SortsByTimestampColumn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idx := windowIndex(t, decodeRequest(t, r))
+		start := shardBase.Add(time.Duration(idx) * time.Hour)
+		resp := PowerQueryResponse{
+			Status:  "success",
+			Columns: []PowerQueryColumn{{Name: "timestamp"}, {Name: "message"}},
+			Values: [][]interface{}{
+				{float64(start.Add(time.Minute).UnixNano()), "late"},
+				{float64(start.UnixNano()), "early"},
+			},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	result, err := client.PowerQueryWindows(context.Background(), PowerQueryParams{Query: "x"}, hourlyWindows(2), ShardOptions{Parallel: 2})
+	require.NoError(t, err)
+	require.Len(t, result.Values, 4)
+	assert.Equal(t, []interface{}{"early", "late", "early", "late"},
+		[]interface{}{result.Values[0][1], result.Values[1][1], result.Values[2][1], result.Values[3][1]})
+}
+
+func TestClient_PowerQueryWindows_ColumnMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idx := windowIndex(t, decodeRequest(t, r))
+		resp := PowerQueryResponse{
+			Status:  "success",
+			Columns: []PowerQueryColumn{{Name: fmt.Sprintf("col%d", idx)}},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := New("test-token", server.URL, false)
+
+	_, err := client.PowerQueryWindows(context.Background(), PowerQueryParams{Query: "x"}, hourlyWindows(2), ShardOptions{Parallel: 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "different PowerQuery columns")
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, isRetryableError(apiStatusError("error/server/backoff", "busy")))
+	assert.False(t, isRetryableError(apiStatusError("error/client/badParam", "bad")))
+	assert.False(t, isRetryableError(apiStatusError("", "unknown")))
+	assert.False(t, isRetryableError(fmt.Errorf("plain error")))
+}
@@ -2,23 +2,50 @@ package client
 
 import (
 	"context"
-	"encoding/json"
-	"io"
 	"time"
 
 	"github.com/andreagrandi/logbasset/internal/errors"
 	"github.com/andreagrandi/logbasset/internal/logging"
 )
 
+// Defaults for TailParams fields left at zero.
+const (
+	DefaultTailInterval = 2 * time.Second
+	DefaultTailPageSize = 1000
+)
+
+// TailCheckpoint records how far a tail has got. Passing it back as
+// TailParams.Resume continues from the next event, so nothing is skipped or
+// repeated across restarts.
+type TailCheckpoint struct {
+	ContinuationToken string `json:"continuationToken"`
+	LastTimestamp     string `json:"lastTimestamp,omitempty"`
+}
+
+// Tail sends matching events to outputChan until ctx is done, starting with
+// the last params.Lines events or from params.Resume. Transient failures are
+// retried indefinitely with backoff, so a long-running tail survives network
+// outages and server restarts; other errors end it, as does a first response
+// without a continuationToken to poll on from.
 func (c *Client) Tail(ctx context.Context, params TailParams, outputChan chan<- LogEvent) error {
 	defer close(outputChan)
 
+	interval := params.Interval
+	if interval <= 0 {
+		interval = DefaultTailInterval
+	}
+	pageSize := params.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultTailPageSize
+	}
+
+	// According to Scalyr docs: repeat the same filter, pageMode, startTime,
+	// endTime when using continuationToken
+	maxCount := params.Lines
 	requestParams := map[string]interface{}{
 		"queryType": "log",
 		"pageMode":  "tail",
-		"maxCount":  params.Lines,
 	}
-
 	if params.Filter != "" {
 		requestParams["filter"] = params.Filter
 	}
@@ -26,81 +53,100 @@ func (c *Client) Tail(ctx context.Context, params TailParams, outputChan chan<-
 		requestParams["priority"] = params.Priority
 	}
 
-	resp, err := c.makeRequest(ctx, "query", requestParams)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return errors.NewNetworkError("failed to read response body", err)
-	}
-
-	if c.verbose {
-		logging.WithField("response_body", string(body)).Debug("API response received")
-	}
-
-	var result QueryResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return errors.NewParseError("failed to parse response", err)
-	}
-
-	if result.Status != "success" {
-		return errors.NewAPIError(result.Message, nil)
-	}
-
-	for _, event := range result.Matches {
-		outputChan <- event
+	var checkpoint TailCheckpoint
+	if params.Resume != nil && params.Resume.ContinuationToken != "" {
+		checkpoint = *params.Resume
+		requestParams["continuationToken"] = checkpoint.ContinuationToken
+		maxCount = pageSize
 	}
 
+	var wait time.Duration
+	failures := 0
 	for {
-		select {
-		case <-ctx.Done():
+		if err := sleepWithContext(ctx, wait); err != nil || ctx.Err() != nil {
 			return ctx.Err()
-		case <-time.After(2 * time.Second):
-			// According to Scalyr docs: repeat the same filter, pageMode, startTime, endTime
-			// when using continuationToken
-			continuationParams := map[string]interface{}{
-				"queryType":         "log",
-				"pageMode":          "tail",
-				"continuationToken": result.ContinuationToken,
-				"maxCount":          1000,
-			}
-
-			// Repeat filter if it was specified in original request
-			if params.Filter != "" {
-				continuationParams["filter"] = params.Filter
-			}
+		}
 
-			// Include priority if it was specified
-			if params.Priority != "" {
-				continuationParams["priority"] = params.Priority
+		requestParams["maxCount"] = maxCount
+		result, err := c.tailPage(ctx, requestParams)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-
-			resp, err := c.makeRequest(ctx, "query", continuationParams)
-			if err != nil {
+			if !isRetryableError(err) {
 				return err
 			}
-
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				resp.Body.Close()
-				return errors.NewNetworkError("failed to read response body", err)
-			}
-			resp.Body.Close()
-
-			if err := json.Unmarshal(body, &result); err != nil {
-				return errors.NewParseError("failed to parse response", err)
+			wait = interval + c.retryPolicy.backoffDelay(failures)
+			failures++
+			logging.WithFields(map[string]any{
+				"attempt": failures,
+				"delay":   wait.String(),
+				"error":   err.Error(),
+			}).Warn("Tail request failed, reconnecting")
+			continue
+		}
+		failures = 0
+		if result.ContinuationToken == "" && checkpoint.ContinuationToken == "" {
+			// Without a token the next poll would start the tail over,
+			// repeating these events forever
+			return errors.NewAPIError("tail response has no continuationToken", nil)
+		}
+		full := len(result.Matches) > 0 && len(result.Matches) >= maxCount
+		wait = nextTailWait(wait, len(result.Matches), full, interval, params.MaxInterval)
+
+		for _, event := range result.Matches {
+			select {
+			case outputChan <- event:
+			case <-ctx.Done():
+				return ctx.Err()
 			}
+			checkpoint.LastTimestamp = event.Timestamp
+		}
 
-			if result.Status != "success" {
-				return errors.NewAPIError(result.Message, nil)
-			}
+		if result.ContinuationToken != "" {
+			checkpoint.ContinuationToken = result.ContinuationToken
+			requestParams["continuationToken"] = result.ContinuationToken
+		}
+		maxCount = pageSize
 
-			for _, event := range result.Matches {
-				outputChan <- event
-			}
+		if params.OnCheckpoint != nil {
+			params.OnCheckpoint(checkpoint)
 		}
 	}
 }
+
+// nextTailWait returns the delay before the next poll. Polling is fixed at
+// interval unless maxInterval is above it; then a full page is followed
+// immediately by the next one to catch up on a burst, and each empty page
+// doubles the delay up to maxInterval.
+func nextTailWait(prev time.Duration, events int, full bool, interval, maxInterval time.Duration) time.Duration {
+	if maxInterval <= interval {
+		return interval
+	}
+	switch {
+	case full:
+		return 0
+	case events > 0 || prev < interval:
+		return interval
+	default:
+		return min(prev*2, maxInterval)
+	}
+}
+
+func (c *Client) tailPage(ctx context.Context, requestParams map[string]interface{}) (*QueryResponse, error) {
+	resp, err := c.makeRequest(ctx, "query", requestParams)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result QueryResponse
+	if err := c.decodeResponse(resp.Body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Status != "success" {
+		return nil, apiStatusError(result.Status, result.Message)
+	}
+	return &result, nil
+}
@@ -1,15 +1,44 @@
 package client
 
-import (
-	"context"
-	"encoding/json"
-	"io"
-
-	"github.com/andreagrandi/logbasset/internal/errors"
-	"github.com/andreagrandi/logbasset/internal/logging"
-)
+import "context"
 
 func (c *Client) TimeseriesQuery(ctx context.Context, params TimeseriesQueryParams) (*TimeseriesQueryResponse, error) {
+	return c.TimeseriesQueries(ctx, []TimeseriesQueryParams{params})
+}
+
+// TimeseriesQueries runs several timeseries queries in one request. The
+// response holds a result for each query, in the same order.
+func (c *Client) TimeseriesQueries(ctx context.Context, params []TimeseriesQueryParams) (*TimeseriesQueryResponse, error) {
+	queries := make([]map[string]interface{}, len(params))
+	for i, p := range params {
+		queries[i] = timeseriesQuery(p)
+	}
+
+	// Wrap queries in queries array per Scalyr API spec
+	requestParams := map[string]interface{}{
+		"queries": queries,
+	}
+
+	resp, err := c.makeRequest(ctx, "timeseriesQuery", requestParams)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result TimeseriesQueryResponse
+	if err := c.decodeResponse(resp.Body, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Status != "success" {
+		return nil, apiStatusError(result.Status, result.Message)
+	}
+
+	return &result, nil
+}
+
+// timeseriesQuery builds the query object of one timeseries query.
+func timeseriesQuery(params TimeseriesQueryParams) map[string]interface{} {
 	// Build the inner query object per Scalyr API spec
 	query := map[string]interface{}{
 		"queryType": "numeric",
@@ -39,34 +68,5 @@ func (c *Client) TimeseriesQuery(ctx context.Context, params TimeseriesQueryPara
 		query["createSummaries"] = false
 	}
 
-	// Wrap query in queries array per Scalyr API spec
-	requestParams := map[string]interface{}{
-		"queries": []map[string]interface{}{query},
-	}
-
-	resp, err := c.makeRequest(ctx, "timeseriesQuery", requestParams)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.NewNetworkError("failed to read response body", err)
-	}
-
-	if c.verbose {
-		logging.WithField("response_body", string(body)).Debug("API response received")
-	}
-
-	var result TimeseriesQueryResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, errors.NewParseError("failed to parse response", err)
-	}
-
-	if result.Status != "success" {
-		return nil, errors.NewAPIError(result.Message, nil)
-	}
-
-	return &result, nil
+	return query
 }
@@ -1,13 +1,20 @@
 package client
 
+import "time"
+
 type QueryParams struct {
-	Filter    string
-	StartTime string
-	EndTime   string
-	Count     int
-	Mode      string
-	Columns   string
-	Priority  string
+	Filter            string
+	StartTime         string
+	EndTime           string
+	Count             int
+	Mode              string
+	Columns           string
+	Priority          string
+	ContinuationToken string
+	// PageTimeout bounds each page request of QueryAll, so a long paged
+	// fetch is not limited by one overall deadline; zero means no limit
+	// beyond the context.
+	PageTimeout time.Duration
 }
 
 type PowerQueryParams struct {
@@ -50,6 +57,19 @@ type TailParams struct {
 	Filter   string
 	Lines    int
 	Priority string
+	// Interval is the delay between polls, DefaultTailInterval when zero.
+	Interval time.Duration
+	// MaxInterval enables adaptive polling when above Interval: full pages
+	// are fetched back to back, and idle polls back off up to MaxInterval.
+	MaxInterval time.Duration
+	// PageSize is the maxCount of each poll, DefaultTailPageSize when zero.
+	PageSize int
+	// Resume continues a previous tail from its checkpoint instead of
+	// starting with the last Lines events.
+	Resume *TailCheckpoint
+	// OnCheckpoint, when set, is called after each poll, once the page's
+	// events have been sent on the output channel.
+	OnCheckpoint func(TailCheckpoint)
 }
 
 type LogEvent struct {
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/logging"
+)
+
+type UploadLogsParams struct {
+	// ServerHost and LogFile become the serverHost and logfile attributes
+	// of the uploaded events.
+	ServerHost string
+	LogFile    string
+	// Parser names the parser applied to each line.
+	Parser string
+	// Attributes are sent as server-<name> headers and set on every event.
+	Attributes map[string]string
+}
+
+// UploadLogs sends raw log text to the uploadLogs endpoint, which splits it
+// into one event per line. body must not exceed MaxRequestBytes. Failures are
+// retried as for AddEvents.
+func (c *Client) UploadLogs(ctx context.Context, params UploadLogsParams, body []byte) error {
+	if c.token == "" {
+		return errors.NewAuthError("API token is required", nil)
+	}
+	if len(body) > MaxRequestBytes {
+		return errors.NewValidationError(
+			fmt.Sprintf("upload of %d bytes exceeds the %d byte uploadLogs request limit", len(body), MaxRequestBytes),
+			nil,
+		)
+	}
+
+	requestURL := fmt.Sprintf("%s/api/uploadLogs", c.server)
+	if c.verbose {
+		logging.WithFields(map[string]any{
+			"url":        requestURL,
+			"endpoint":   "uploadLogs",
+			"bytes":      len(body),
+			"serverHost": params.ServerHost,
+			"logfile":    params.LogFile,
+			"parser":     params.Parser,
+		}).Debug("Making HTTP request")
+	}
+
+	query := url.Values{"token": {c.token}}
+	return c.retryOperation(ctx, func() error {
+		resp, err := c.doWithRetry(ctx, RetryPolicy{}, func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "POST", requestURL+"?"+query.Encode(), bytes.NewReader(body))
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Type", "text/plain")
+			if params.ServerHost != "" {
+				req.Header.Set("server-host", params.ServerHost)
+			}
+			if params.LogFile != "" {
+				req.Header.Set("logfile", params.LogFile)
+			}
+			if params.Parser != "" {
+				req.Header.Set("parser", params.Parser)
+			}
+			for name, value := range params.Attributes {
+				req.Header.Set("server-"+name, value)
+			}
+			return req, nil
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var result statusResponse
+		if err := c.decodeResponse(resp.Body, &result); err != nil {
+			return err
+		}
+		if result.Status != "success" {
+			return apiStatusError(result.Status, result.Message)
+		}
+		return nil
+	})
+}
@@ -1,26 +1,96 @@
 package config
 
 import (
+	stderrors "errors"
+	"fmt"
 	"net/url"
-	"os"
-	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/andreagrandi/logbasset/internal/client"
 	"github.com/andreagrandi/logbasset/internal/errors"
 	"github.com/andreagrandi/logbasset/internal/logging"
+	"github.com/andreagrandi/logbasset/internal/timerange"
 	"github.com/spf13/viper"
 )
 
+// DefaultTimeout bounds a command's API requests when no timeout is configured.
+const DefaultTimeout = 30 * time.Second
+
 type Config struct {
-	Server   string `mapstructure:"server"`
-	Token    string `mapstructure:"token"`
-	Verbose  bool   `mapstructure:"verbose"`
-	Priority string `mapstructure:"priority"`
-	LogLevel string `mapstructure:"log_level"`
+	Server   string        `mapstructure:"server"`
+	Token    string        `mapstructure:"token"`
+	Verbose  bool          `mapstructure:"verbose"`
+	Priority string        `mapstructure:"priority"`
+	LogLevel string        `mapstructure:"log_level"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+
+	// WriteToken authorises the write API (addEvents, uploadLogs). It is
+	// kept apart from Token so read-only setups never hold write access.
+	WriteToken string `mapstructure:"write_token"`
+	// ConfigReadToken and ConfigWriteToken authorise the configuration file
+	// API (listFiles, getFile, putFile).
+	ConfigReadToken  string `mapstructure:"config_read_token"`
+	ConfigWriteToken string `mapstructure:"config_write_token"`
+
+	// TokenCommand is run through the shell to obtain the token when none
+	// is set directly; its trimmed stdout is used as the token.
+	TokenCommand string `mapstructure:"token_command"`
+	// CredentialStore names the store consulted for the token when neither
+	// token nor token_command yields one (see CredentialStores).
+	CredentialStore string `mapstructure:"credential_store"`
+
+	// Color is the default for --color: auto, always or never.
+	Color string `mapstructure:"color"`
+	// Theme overrides the colours of terminal output, keyed by
+	// output.ThemeKeys, e.g. `error: bold red`.
+	Theme map[string]string `mapstructure:"theme"`
+	// TimeFormat and TZ are the defaults for --time-format and --tz.
+	TimeFormat string `mapstructure:"time_format"`
+	TZ         string `mapstructure:"tz"`
+
+	// Profile is the name of the active profile, if any.
+	Profile  string             `mapstructure:"profile"`
+	Profiles map[string]Profile `mapstructure:"profiles"`
+
+	// File is the config file that was loaded, empty when none was found.
+	File string `mapstructure:"-"`
+	// Sources records where each effective setting in Settings came from.
+	Sources map[string]Source `mapstructure:"-"`
+
+	readErr error
+}
+
+// Profile is a named set of connection settings under `profiles:` in the
+// config file. Fields left empty fall back to the top-level file values.
+type Profile struct {
+	Server       string `mapstructure:"server"`
+	Token        string `mapstructure:"token"`
+	TokenCommand string `mapstructure:"token_command"`
+	WriteToken   string `mapstructure:"write_token"`
+	// ConfigReadToken and ConfigWriteToken authorise the files commands.
+	ConfigReadToken  string `mapstructure:"config_read_token"`
+	ConfigWriteToken string `mapstructure:"config_write_token"`
+	// Verbose is nil when the profile does not set it, so an explicit false
+	// can still override a top-level true.
+	Verbose  *bool         `mapstructure:"verbose"`
+	Priority string        `mapstructure:"priority"`
+	LogLevel string        `mapstructure:"log_level"`
+	Timeout  time.Duration `mapstructure:"timeout"`
 }
 
 func NewWithoutValidation() (*Config, error) {
+	return LoadProfile("")
+}
+
+// LoadProfile loads the configuration without validating it. When name is
+// empty the profile is taken from the scalyr_profile env var, then from the
+// file's top-level `profile:` key; with neither set no profile is applied.
+// A profile's values replace the top-level file values, so flags and
+// environment variables still take precedence over them.
+func LoadProfile(name string) (*Config, error) {
 	v := viper.New()
 
 	setDefaults(v)
@@ -29,14 +99,126 @@ func NewWithoutValidation() (*Config, error) {
 		return nil, errors.NewConfigError("failed to setup configuration", err)
 	}
 
+	// A config file that exists but cannot be parsed is reported by
+	// Validate, so the config subcommands can still inspect and repair it
+	var readErr error
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !stderrors.As(err, &notFound) {
+			readErr = errors.NewConfigError("failed to read config file "+v.ConfigFileUsed(), err)
+		}
+	}
+
 	config := &Config{}
 	if err := v.Unmarshal(config); err != nil {
 		return nil, errors.NewConfigError("failed to unmarshal configuration", err)
 	}
 
+	if name == "" {
+		name = config.Profile
+	}
+	if name == "" {
+		config.finishLoad(v, nil, readErr)
+		return config, nil
+	}
+
+	// Viper lowercases map keys, so profile names are case-insensitive
+	name = strings.ToLower(name)
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, errors.NewConfigError(
+			fmt.Sprintf("profile %q not found", name),
+			fmt.Errorf("available profiles: %s", strings.Join(config.ProfileNames(), ", ")),
+		)
+	}
+
+	settings := profileSettings(profile)
+	if err := v.MergeConfigMap(settings); err != nil {
+		return nil, errors.NewConfigError("failed to apply profile "+name, err)
+	}
+
+	config = &Config{}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, errors.NewConfigError("failed to unmarshal configuration", err)
+	}
+	config.Profile = name
+	config.finishLoad(v, settings, readErr)
+
 	return config, nil
 }
 
+// finishLoad records the loaded file and the source of every setting.
+// profileSettings holds the values the active profile contributed.
+func (c *Config) finishLoad(v *viper.Viper, profileSettings map[string]interface{}, readErr error) {
+	c.File = v.ConfigFileUsed()
+	c.readErr = readErr
+	c.Sources = make(map[string]Source, len(Settings))
+	for _, s := range Settings {
+		_, fromProfile := profileSettings[s.Key]
+		switch {
+		case s.envSet():
+			c.Sources[s.Key] = SourceEnv
+		case fromProfile:
+			c.Sources[s.Key] = SourceProfile
+		case v.InConfig(s.Key):
+			c.Sources[s.Key] = SourceFile
+		default:
+			c.Sources[s.Key] = SourceDefault
+		}
+	}
+}
+
+// profileSettings returns the values a profile sets, keyed like the
+// top-level config file.
+func profileSettings(p Profile) map[string]interface{} {
+	settings := make(map[string]interface{})
+	if p.Server != "" {
+		settings["server"] = p.Server
+	}
+	if p.Token != "" {
+		settings["token"] = p.Token
+	}
+	if p.TokenCommand != "" {
+		settings["token_command"] = p.TokenCommand
+		// A profile's token_command must win over a top-level token
+		if p.Token == "" {
+			settings["token"] = ""
+		}
+	}
+	if p.WriteToken != "" {
+		settings["write_token"] = p.WriteToken
+	}
+	if p.ConfigReadToken != "" {
+		settings["config_read_token"] = p.ConfigReadToken
+	}
+	if p.ConfigWriteToken != "" {
+		settings["config_write_token"] = p.ConfigWriteToken
+	}
+	if p.Verbose != nil {
+		settings["verbose"] = *p.Verbose
+	}
+	if p.Priority != "" {
+		settings["priority"] = p.Priority
+	}
+	if p.LogLevel != "" {
+		settings["log_level"] = p.LogLevel
+	}
+	if p.Timeout > 0 {
+		settings["timeout"] = p.Timeout
+	}
+	return settings
+}
+
+// ProfileNames returns the configured profile names in sorted order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func New() (*Config, error) {
 	config, err := NewWithoutValidation()
 	if err != nil {
@@ -55,82 +237,133 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("verbose", false)
 	v.SetDefault("priority", "high")
 	v.SetDefault("log_level", "info")
+	v.SetDefault("timeout", DefaultTimeout)
+	v.SetDefault("color", "auto")
 }
 
 func setupViper(v *viper.Viper) error {
 	v.SetConfigName("logbasset")
 	v.SetConfigType("yaml")
 
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		v.AddConfigPath(filepath.Join(homeDir, ".config", "logbasset"))
-		v.AddConfigPath(filepath.Join(homeDir, ".logbasset"))
+	for _, dir := range SearchDirs() {
+		v.AddConfigPath(dir)
 	}
 
-	v.AddConfigPath(".")
-
 	v.SetEnvPrefix("scalyr")
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
-	v.BindEnv("token", "scalyr_readlog_token")
-	v.BindEnv("server", "scalyr_server")
-	v.BindEnv("log_level", "scalyr_log_level")
-
-	v.ReadInConfig()
+	for _, s := range Settings {
+		v.BindEnv(s.Key, s.Env)
+	}
 
 	return nil
 }
 
 func validateConfig(config *Config) error {
+	if config.readErr != nil {
+		return config.readErr
+	}
+
 	if config.Token == "" {
 		return errors.NewAuthError("API token is required", nil)
 	}
 
-	if config.Server != "" {
-		parsedURL, err := url.Parse(config.Server)
-		if err != nil {
-			return errors.NewConfigError("invalid server URL", err)
-		}
+	return validateConnection(config)
+}
 
-		if !strings.HasPrefix(config.Server, "http://") && !strings.HasPrefix(config.Server, "https://") {
-			return errors.NewConfigError("server URL must start with http:// or https://", nil)
-		}
+// validateConnection checks the settings shared by read and write commands.
+func validateConnection(config *Config) error {
+	if err := validateServer(config.Server); err != nil {
+		return err
+	}
 
-		if parsedURL.User != nil {
-			return errors.NewConfigError("server URL must not contain embedded credentials", nil)
-		}
+	if err := validatePriority(config.Priority); err != nil {
+		return err
+	}
 
-		if parsedURL.RawQuery != "" {
-			return errors.NewConfigError("server URL must not contain query parameters", nil)
-		}
+	if config.Timeout < 0 {
+		return errors.NewValidationError("timeout must not be negative", nil)
+	}
 
-		if parsedURL.Fragment != "" {
-			return errors.NewConfigError("server URL must not contain a fragment", nil)
-		}
+	if err := validateColor(config.Color); err != nil {
+		return err
 	}
 
-	if config.Priority != "" && config.Priority != "high" && config.Priority != "low" {
+	if err := validateTZ(config.TZ); err != nil {
+		return err
+	}
+
+	return validateLogLevel(config.LogLevel)
+}
+
+func validateServer(server string) error {
+	if server == "" {
+		return nil
+	}
+
+	parsedURL, err := url.Parse(server)
+	if err != nil {
+		return errors.NewConfigError("invalid server URL", err)
+	}
+
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		return errors.NewConfigError("server URL must start with http:// or https://", nil)
+	}
+
+	if parsedURL.User != nil {
+		return errors.NewConfigError("server URL must not contain embedded credentials", nil)
+	}
+
+	if parsedURL.RawQuery != "" {
+		return errors.NewConfigError("server URL must not contain query parameters", nil)
+	}
+
+	if parsedURL.Fragment != "" {
+		return errors.NewConfigError("server URL must not contain a fragment", nil)
+	}
+
+	return nil
+}
+
+func validatePriority(priority string) error {
+	if priority != "" && priority != "high" && priority != "low" {
 		return errors.NewValidationError("priority must be 'high' or 'low'", nil)
 	}
+	return nil
+}
 
-	if config.LogLevel != "" {
-		validLevels := []string{"debug", "info", "warn", "error"}
-		valid := false
-		for _, level := range validLevels {
-			if strings.ToLower(config.LogLevel) == level {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			return errors.NewValidationError("log level must be one of: debug, info, warn, error", nil)
-		}
+// ColorModes are the values of --color and the color key.
+var ColorModes = []string{"auto", "always", "never"}
+
+func validateColor(mode string) error {
+	if mode == "" || slices.Contains(ColorModes, mode) {
+		return nil
 	}
+	return errors.NewValidationError("color must be one of: "+strings.Join(ColorModes, ", "), nil)
+}
 
+func validateTZ(tz string) error {
+	if _, err := timerange.LoadLocation(tz); err != nil {
+		return errors.NewValidationError("tz must be local, UTC or an IANA time zone name", err)
+	}
 	return nil
 }
 
+func validateLogLevel(logLevel string) error {
+	if logLevel == "" {
+		return nil
+	}
+
+	validLevels := []string{"debug", "info", "warn", "error"}
+	for _, level := range validLevels {
+		if strings.ToLower(logLevel) == level {
+			return nil
+		}
+	}
+	return errors.NewValidationError("log level must be one of: debug, info, warn, error", nil)
+}
+
 func (c *Config) GetClient() *client.Client {
 	return client.New(c.Token, c.Server, c.Verbose)
 }
@@ -142,22 +375,64 @@ func (c *Config) ApplyLogging() error {
 	return nil
 }
 
+// SetFromFlags applies the flags a command was run with. Empty strings and a
+// false verbose mean the flag was not given, so the file, profile and env
+// values are kept.
 func (c *Config) SetFromFlags(token, server string, verbose bool, priority, logLevel string) {
 	if token != "" {
 		c.Token = token
+		c.SetSource("token", SourceFlag)
 	}
 	if server != "" {
 		c.Server = server
+		c.SetSource("server", SourceFlag)
+	}
+	if verbose {
+		c.Verbose = true
+		c.SetSource("verbose", SourceFlag)
 	}
-	c.Verbose = verbose
 	if priority != "" {
 		c.Priority = priority
+		c.SetSource("priority", SourceFlag)
 	}
 	if logLevel != "" {
 		c.LogLevel = logLevel
+		c.SetSource("log_level", SourceFlag)
 	}
 }
 
+// SetSource records where the effective value of key came from.
+func (c *Config) SetSource(key string, source Source) {
+	if c.Sources == nil {
+		c.Sources = make(map[string]Source)
+	}
+	c.Sources[key] = source
+}
+
 func (c *Config) Validate() error {
 	return validateConfig(c)
 }
+
+// ValidateSettings validates the configuration without resolving the token.
+// A token_command or credential_store stands in for the token, since they
+// are only consulted when a command needs one.
+func (c *Config) ValidateSettings() error {
+	if c.readErr != nil {
+		return c.readErr
+	}
+
+	if c.Token == "" && c.TokenCommand == "" && c.CredentialStore == "" {
+		return errors.NewAuthError(
+			"API token is required",
+			fmt.Errorf("set token, token_command or credential_store"),
+		)
+	}
+
+	if c.Token == "" && c.TokenCommand == "" {
+		if _, err := OpenCredentialStore(c.CredentialStore); err != nil {
+			return err
+		}
+	}
+
+	return validateConnection(c)
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -214,6 +216,46 @@ func TestValidateConfig(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "invalid color",
+			config: &Config{
+				Token:    "test-token",
+				Server:   "https://www.scalyr.com",
+				Priority: "high",
+				Color:    "sometimes",
+			},
+			expectError: true,
+		},
+		{
+			name: "valid color",
+			config: &Config{
+				Token:    "test-token",
+				Server:   "https://www.scalyr.com",
+				Priority: "high",
+				Color:    "never",
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid tz",
+			config: &Config{
+				Token:    "test-token",
+				Server:   "https://www.scalyr.com",
+				Priority: "high",
+				TZ:       "Mars/Olympus_Mons",
+			},
+			expectError: true,
+		},
+		{
+			name: "valid tz",
+			config: &Config{
+				Token:    "test-token",
+				Server:   "https://www.scalyr.com",
+				Priority: "high",
+				TZ:       "Europe/London",
+			},
+			expectError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -288,9 +330,178 @@ func TestFlagOverrideEnv(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+const profilesYAML = `token: default-token
+server: https://www.scalyr.com
+priority: high
+profiles:
+  eu:
+    server: https://eu.scalyr.com
+    token: eu-token
+  staging:
+    server: https://staging.example.com
+    token: staging-token
+    priority: low
+    timeout: 2m
+`
+
+// writeConfigFile points the config search paths at a fresh directory
+// containing a logbasset.yaml with the given contents.
+func writeConfigFile(t *testing.T, contents string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Chdir(dir)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logbasset.yaml"), []byte(contents), 0600))
+}
+
+func TestLoadProfile(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, profilesYAML)
+
+	config, err := LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "", config.Profile)
+	assert.Equal(t, "default-token", config.Token)
+	assert.Equal(t, []string{"eu", "staging"}, config.ProfileNames())
+
+	config, err = LoadProfile("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", config.Profile)
+	assert.Equal(t, "staging-token", config.Token)
+	assert.Equal(t, "https://staging.example.com", config.Server)
+	assert.Equal(t, "low", config.Priority)
+	assert.Equal(t, 2*time.Minute, config.Timeout)
+
+	config, err = LoadProfile("EU")
+	require.NoError(t, err)
+	assert.Equal(t, "eu", config.Profile)
+	assert.Equal(t, "https://eu.scalyr.com", config.Server)
+	assert.Equal(t, "high", config.Priority, "unset profile fields fall back to the top-level value")
+
+	_, err = LoadProfile("missing")
+	assert.Error(t, err)
+}
+
+func TestLoadProfileSelection(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "profile: eu\n"+profilesYAML)
+
+	config, err := LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "eu", config.Profile, "file's profile key selects the default profile")
+
+	os.Setenv("scalyr_profile", "staging")
+	config, err = LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", config.Profile, "scalyr_profile overrides the file")
+
+	config, err = LoadProfile("eu")
+	require.NoError(t, err)
+	assert.Equal(t, "eu", config.Profile, "explicit name overrides scalyr_profile")
+}
+
+func TestLoadProfileEnvOverridesProfile(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, profilesYAML)
+	os.Setenv("scalyr_readlog_token", "env-token")
+
+	config, err := LoadProfile("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "env-token", config.Token)
+	assert.Equal(t, "https://staging.example.com", config.Server)
+
+	config.SetFromFlags("flag-token", "", false, "", "")
+	assert.Equal(t, "flag-token", config.Token)
+}
+
+func TestLoadProfileVerboseWithoutFlag(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, profilesYAML+"  loud:\n    verbose: true\n")
+
+	config, err := LoadProfile("loud")
+	require.NoError(t, err)
+	assert.True(t, config.Verbose)
+
+	config.SetFromFlags("", "", false, "", "")
+	assert.True(t, config.Verbose, "an absent --verbose keeps the profile's value")
+	assert.Equal(t, SourceProfile, config.Sources["verbose"])
+}
+
+func TestLoadProfileVerboseFalseOverridesFile(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "verbose: true\n"+profilesYAML+"  quiet:\n    verbose: false\n")
+
+	config, err := LoadProfile("quiet")
+	require.NoError(t, err)
+	assert.False(t, config.Verbose, "a profile's verbose: false wins over the top-level value")
+	assert.Equal(t, SourceProfile, config.Sources["verbose"])
+
+	config, err = LoadProfile("eu")
+	require.NoError(t, err)
+	assert.True(t, config.Verbose, "a profile without verbose keeps the top-level value")
+}
+
 func clearEnv() {
+	os.Unsetenv("scalyr_writelog_token")
+	os.Unsetenv("scalyr_readconfig_token")
+	os.Unsetenv("scalyr_writeconfig_token")
+	os.Unsetenv("scalyr_token_command")
+	os.Unsetenv("scalyr_credential_store")
+	os.Unsetenv("scalyr_profile")
 	os.Unsetenv("scalyr_readlog_token")
 	os.Unsetenv("scalyr_server")
 	os.Unsetenv("scalyr_verbose")
 	os.Unsetenv("scalyr_priority")
 }
+
+func TestValidateSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      *Config
+		expectError bool
+	}{
+		{
+			name:   "token",
+			config: &Config{Token: "test-token", Priority: "high"},
+		},
+		{
+			name:   "token command instead of a token",
+			config: &Config{TokenCommand: "echo test-token", Priority: "high"},
+		},
+		{
+			name:   "credential store instead of a token",
+			config: &Config{CredentialStore: "file", Priority: "high"},
+		},
+		{
+			name:        "unknown credential store",
+			config:      &Config{CredentialStore: "vault", Priority: "high"},
+			expectError: true,
+		},
+		{
+			name:        "no token source",
+			config:      &Config{Priority: "high"},
+			expectError: true,
+		},
+		{
+			name:        "token command with invalid priority",
+			config:      &Config{TokenCommand: "echo test-token", Priority: "urgent"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.ValidateSettings()
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
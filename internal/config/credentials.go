@@ -0,0 +1,330 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+// DefaultCredentialName is the credential used when no profile is active.
+const DefaultCredentialName = "default"
+
+// tokenCommandTimeout bounds how long token_command may run, leaving time
+// for helpers such as `op` that prompt for unlocking.
+const tokenCommandTimeout = 2 * time.Minute
+
+// ErrCredentialNotFound is returned by CredentialStore.Get for unknown names.
+var ErrCredentialNotFound = stderrors.New("credential not found")
+
+// CredentialStore keeps API tokens outside the config file, keyed by profile
+// name (DefaultCredentialName without a profile).
+type CredentialStore interface {
+	Get(name string) (string, error)
+	Set(name, token string) error
+	Delete(name string) error
+}
+
+// CredentialStores maps the values accepted by the credential_store key to
+// constructors. Additional backends register themselves here.
+var CredentialStores = map[string]func() (CredentialStore, error){
+	"file": func() (CredentialStore, error) {
+		path, err := DefaultCredentialsPath()
+		if err != nil {
+			return nil, err
+		}
+		return NewEncryptedFileStore(path, Passphrase), nil
+	},
+}
+
+// Passphrase supplies the passphrase for the encrypted file store. It reads
+// the scalyr_credentials_passphrase env var; the CLI replaces it to prompt
+// on a terminal.
+var Passphrase = func() (string, error) {
+	if p := os.Getenv("scalyr_credentials_passphrase"); p != "" {
+		return p, nil
+	}
+	return "", errors.NewAuthError(
+		"credential store passphrase is required",
+		fmt.Errorf("set the scalyr_credentials_passphrase env var"),
+	)
+}
+
+func credentialStoreNames() []string {
+	names := make([]string, 0, len(CredentialStores))
+	for name := range CredentialStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenCredentialStore returns the named store, defaulting to "file".
+func OpenCredentialStore(name string) (CredentialStore, error) {
+	if name == "" {
+		name = "file"
+	}
+	open, ok := CredentialStores[name]
+	if !ok {
+		return nil, errors.NewConfigError(
+			fmt.Sprintf("unknown credential store: %s", name),
+			fmt.Errorf("valid stores are: %s", strings.Join(credentialStoreNames(), ", ")),
+		)
+	}
+	return open()
+}
+
+// CredentialName is the name the active profile's token is stored under.
+func (c *Config) CredentialName() string {
+	if c.Profile != "" {
+		return c.Profile
+	}
+	return DefaultCredentialName
+}
+
+// ResolveToken fills in the token when flags, the environment and the config
+// file did not provide one: first from token_command, then from the
+// configured credential store. Call it after SetFromFlags.
+func (c *Config) ResolveToken(ctx context.Context) error {
+	if c.Token != "" {
+		return nil
+	}
+
+	if c.TokenCommand != "" {
+		token, err := runTokenCommand(ctx, c.TokenCommand)
+		if err != nil {
+			return err
+		}
+		c.Token = token
+		c.SetSource("token", SourceCommand)
+		return nil
+	}
+
+	if c.CredentialStore != "" {
+		store, err := OpenCredentialStore(c.CredentialStore)
+		if err != nil {
+			return err
+		}
+		token, err := store.Get(c.CredentialName())
+		if stderrors.Is(err, ErrCredentialNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		c.Token = token
+		c.SetSource("token", SourceStore)
+	}
+
+	return nil
+}
+
+// runTokenCommand runs command through the shell and returns its trimmed
+// stdout. Stdin and stderr are passed through so helpers can prompt.
+func runTokenCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, tokenCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.NewAuthError("token_command failed", err)
+	}
+
+	token := strings.TrimSpace(stdout.String())
+	if token == "" {
+		return "", errors.NewAuthError("token_command printed no token", nil)
+	}
+	return token, nil
+}
+
+// DefaultCredentialsPath is where the encrypted file store keeps tokens.
+func DefaultCredentialsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.NewConfigError("cannot determine home directory", err)
+	}
+	return filepath.Join(homeDir, ".config", "logbasset", "credentials.enc"), nil
+}
+
+// pbkdf2Iterations follows the OWASP recommendation for PBKDF2-HMAC-SHA256.
+const pbkdf2Iterations = 600000
+
+// EncryptedFileStore keeps tokens in a single file encrypted with AES-256-GCM
+// under a key derived from a passphrase, for hosts without an OS keyring.
+type EncryptedFileStore struct {
+	path       string
+	passphrase func() (string, error)
+}
+
+func NewEncryptedFileStore(path string, passphrase func() (string, error)) *EncryptedFileStore {
+	return &EncryptedFileStore{path: path, passphrase: passphrase}
+}
+
+// encryptedFile is the on-disk format. A fresh salt and nonce are generated
+// on every write.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func (s *EncryptedFileStore) Get(name string) (string, error) {
+	tokens, _, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	token, ok := tokens[name]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return token, nil
+}
+
+func (s *EncryptedFileStore) Set(name, token string) error {
+	tokens, passphrase, err := s.load()
+	if err != nil {
+		return err
+	}
+	tokens[name] = token
+	return s.save(tokens, passphrase)
+}
+
+func (s *EncryptedFileStore) Delete(name string) error {
+	tokens, passphrase, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[name]; !ok {
+		return ErrCredentialNotFound
+	}
+	delete(tokens, name)
+	return s.save(tokens, passphrase)
+}
+
+// load decrypts the store, returning an empty set when the file does not
+// exist yet. The passphrase is returned so a following save reuses it.
+func (s *EncryptedFileStore) load() (map[string]string, string, error) {
+	passphrase, err := s.passphrase()
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return make(map[string]string), passphrase, nil
+	}
+	if err != nil {
+		return nil, "", errors.NewConfigError("failed to read credential store "+s.path, err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != 1 {
+		return nil, "", errors.NewConfigError("credential store "+s.path+" is corrupt or has an unsupported format", err)
+	}
+
+	gcm, err := newGCM(passphrase, file.Salt)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, "", errors.NewAuthError("failed to decrypt credential store; wrong passphrase?", err)
+	}
+
+	tokens := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, "", errors.NewConfigError("credential store "+s.path+" is corrupt", err)
+	}
+	return tokens, passphrase, nil
+}
+
+func (s *EncryptedFileStore) save(tokens map[string]string, passphrase string) error {
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return errors.NewConfigError("failed to encode credentials", err)
+	}
+
+	file := encryptedFile{Version: 1, Salt: make([]byte, 16)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return errors.NewConfigError("failed to generate salt", err)
+	}
+	gcm, err := newGCM(passphrase, file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return errors.NewConfigError("failed to generate nonce", err)
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return errors.NewConfigError("failed to encode credential store", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.NewConfigError("failed to create directory "+dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".credentials-*")
+	if err != nil {
+		return errors.NewConfigError("failed to write credential store "+s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.NewConfigError("failed to write credential store "+s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.NewConfigError("failed to write credential store "+s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errors.NewConfigError("failed to write credential store "+s.path, err)
+	}
+	return nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, errors.NewConfigError("failed to derive credential store key", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.NewConfigError("failed to initialise cipher", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.NewConfigError("failed to initialise cipher", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixedPassphrase(p string) func() (string, error) {
+	return func() (string, error) { return p, nil }
+}
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	store := NewEncryptedFileStore(path, fixedPassphrase("correct horse"))
+
+	_, err := store.Get("default")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+
+	require.NoError(t, store.Set("default", "default-token"))
+	require.NoError(t, store.Set("eu", "eu-token"))
+
+	token, err := store.Get("eu")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-token", token)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "eu-token", "tokens must not be stored in plaintext")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	require.NoError(t, store.Delete("eu"))
+	_, err = store.Get("eu")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+	assert.ErrorIs(t, store.Delete("eu"), ErrCredentialNotFound)
+
+	token, err = store.Get("default")
+	require.NoError(t, err)
+	assert.Equal(t, "default-token", token)
+}
+
+func TestEncryptedFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	require.NoError(t, NewEncryptedFileStore(path, fixedPassphrase("right")).Set("default", "secret"))
+
+	_, err := NewEncryptedFileStore(path, fixedPassphrase("wrong")).Get("default")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCredentialNotFound)
+}
+
+func TestResolveTokenFromCommand(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "token_command: echo '  command-token  '\n"+
+		"profiles:\n  eu:\n    token_command: echo eu-token\n")
+
+	config, err := LoadProfile("")
+	require.NoError(t, err)
+	require.NoError(t, config.ResolveToken(context.Background()))
+	assert.Equal(t, "command-token", config.Token)
+	assert.Equal(t, SourceCommand, config.Sources["token"])
+
+	config, err = LoadProfile("eu")
+	require.NoError(t, err)
+	require.NoError(t, config.ResolveToken(context.Background()))
+	assert.Equal(t, "eu-token", config.Token)
+
+	os.Setenv("scalyr_readlog_token", "env-token")
+	config, err = LoadProfile("")
+	require.NoError(t, err)
+	require.NoError(t, config.ResolveToken(context.Background()))
+	assert.Equal(t, "env-token", config.Token, "token_command only runs when no token is set")
+}
+
+func TestResolveTokenCommandFailure(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+
+	config := &Config{TokenCommand: "exit 3"}
+	assert.Error(t, config.ResolveToken(context.Background()))
+
+	config = &Config{TokenCommand: "true"}
+	assert.Error(t, config.ResolveToken(context.Background()), "empty output is an error")
+}
+
+func TestProfileTokenCommandOverridesTopLevelToken(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "token: plain-token\nprofiles:\n  eu:\n    token_command: echo eu-token\n")
+
+	config, err := LoadProfile("eu")
+	require.NoError(t, err)
+	require.NoError(t, config.ResolveToken(context.Background()))
+	assert.Equal(t, "eu-token", config.Token)
+}
+
+func TestResolveTokenFromStore(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "credential_store: file\nprofiles:\n  eu:\n    server: https://eu.scalyr.com\n")
+
+	original := Passphrase
+	Passphrase = fixedPassphrase("test passphrase")
+	defer func() { Passphrase = original }()
+
+	store, err := OpenCredentialStore("file")
+	require.NoError(t, err)
+	require.NoError(t, store.Set("eu", "stored-eu-token"))
+
+	config, err := LoadProfile("eu")
+	require.NoError(t, err)
+	require.NoError(t, config.ResolveToken(context.Background()))
+	assert.Equal(t, "stored-eu-token", config.Token)
+	assert.Equal(t, SourceStore, config.Sources["token"])
+
+	config, err = LoadProfile("")
+	require.NoError(t, err)
+	require.NoError(t, config.ResolveToken(context.Background()))
+	assert.Equal(t, "", config.Token, "a missing credential leaves the token unset for Validate to report")
+}
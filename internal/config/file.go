@@ -0,0 +1,209 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"go.yaml.in/yaml/v3"
+)
+
+// SetInFile writes key (a top-level key or "profiles.<name>.<key>") to the
+// YAML config file at path, creating the file and any missing profile if
+// needed. Comments and unrelated keys are preserved. The value is validated
+// first.
+func SetInFile(path, key, value string) error {
+	if err := ValidateSetting(key, value); err != nil {
+		return err
+	}
+
+	doc, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(strings.ToLower(key), ".")
+	for _, part := range parts[:len(parts)-1] {
+		child := mappingValue(node, part)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, scalarNode(part, "!!str"), child)
+		} else if child.Kind != yaml.MappingNode {
+			return errors.NewConfigError(fmt.Sprintf("cannot set %s: %s is not a mapping in %s", key, part, path), nil)
+		}
+		node = child
+	}
+
+	tag := "!!str"
+	if parts[len(parts)-1] == "verbose" {
+		tag = "!!bool"
+		value = strings.ToLower(value)
+	}
+
+	last := parts[len(parts)-1]
+	if existing := mappingValue(node, last); existing != nil {
+		// Update in place so comments attached to the value survive
+		existing.Kind, existing.Tag, existing.Value, existing.Style = yaml.ScalarNode, tag, value, 0
+		existing.Content = nil
+	} else {
+		node.Content = append(node.Content, scalarNode(last, "!!str"), scalarNode(value, tag))
+	}
+
+	return writeDocument(path, doc)
+}
+
+// UnsetInFile removes key from the config file at path. It reports whether
+// the key was present.
+func UnsetInFile(path, key string) (bool, error) {
+	if _, _, err := LookupSetting(key); err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	doc, err := readDocument(path)
+	if err != nil {
+		return false, err
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(strings.ToLower(key), ".")
+	for _, part := range parts[:len(parts)-1] {
+		node = mappingValue(node, part)
+		if node == nil || node.Kind != yaml.MappingNode {
+			return false, nil
+		}
+	}
+
+	last := parts[len(parts)-1]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, last) {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return true, writeDocument(path, doc)
+		}
+	}
+	return false, nil
+}
+
+// CreateFile writes a new config file at path holding settings, in the order
+// of Settings. An existing file is only replaced when overwrite is set. The
+// file is created with 0600 permissions since it may hold a token.
+func CreateFile(path string, settings map[string]string, overwrite bool) error {
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return errors.NewConfigError(
+			fmt.Sprintf("config file already exists: %s", path),
+			fmt.Errorf("use --force to overwrite it, or config set to change individual keys"),
+		)
+	}
+
+	mapping := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, s := range Settings {
+		value, ok := settings[s.Key]
+		if !ok || value == "" {
+			continue
+		}
+		if err := ValidateSetting(s.Key, value); err != nil {
+			return err
+		}
+		tag := "!!str"
+		if s.Key == "verbose" {
+			tag = "!!bool"
+		}
+		mapping.Content = append(mapping.Content, scalarNode(s.Key, "!!str"), scalarNode(value, tag))
+	}
+
+	return writeDocument(path, &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}})
+}
+
+func readDocument(path string) (*yaml.Node, error) {
+	empty := &yaml.Node{
+		Kind:    yaml.DocumentNode,
+		Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, errors.NewConfigError("failed to read config file "+path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.NewConfigError("failed to parse config file "+path, err)
+	}
+	if doc.Kind == 0 {
+		return empty, nil
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.NewConfigError("config file "+path+" is not a YAML mapping", nil)
+	}
+	return &doc, nil
+}
+
+// writeDocument replaces the file at path atomically, keeping the existing
+// file's permissions or using 0600 for a new one.
+func writeDocument(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return errors.NewConfigError("failed to encode config file", err)
+	}
+	enc.Close()
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.NewConfigError("failed to create config directory "+dir, err)
+	}
+
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, ".logbasset-*.yaml")
+	if err != nil {
+		return errors.NewConfigError("failed to write config file "+path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return errors.NewConfigError("failed to write config file "+path, err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return errors.NewConfigError("failed to write config file "+path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.NewConfigError("failed to write config file "+path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.NewConfigError("failed to write config file "+path, err)
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key in a YAML mapping, matching
+// case-insensitively like viper does.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarNode(value, tag string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetInFilePreservesComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logbasset.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("# my settings\nserver: https://www.scalyr.com # US\ntoken: old\n"), 0640))
+
+	require.NoError(t, SetInFile(path, "server", "https://eu.scalyr.com"))
+	require.NoError(t, SetInFile(path, "verbose", "TRUE"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# my settings\nserver: https://eu.scalyr.com # US\ntoken: old\nverbose: true\n", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm(), "existing permissions are kept")
+}
+
+func TestSetInFileCreatesProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "logbasset.yaml")
+
+	require.NoError(t, SetInFile(path, "profiles.EU.server", "https://eu.scalyr.com"))
+	require.NoError(t, SetInFile(path, "profiles.eu.token", "eu-token"))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "profiles:\n  eu:\n    server: https://eu.scalyr.com\n    token: eu-token\n", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestSetInFileRejectsInvalidValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logbasset.yaml")
+
+	assert.Error(t, SetInFile(path, "priority", "medium"))
+	assert.Error(t, SetInFile(path, "server", "ftp://example.com"))
+	assert.Error(t, SetInFile(path, "timeout", "soon"))
+	assert.Error(t, SetInFile(path, "verbose", "maybe"))
+	assert.Error(t, SetInFile(path, "token", ""))
+	assert.Error(t, SetInFile(path, "colour", "red"))
+	assert.Error(t, SetInFile(path, "profiles.eu.profile", "us"), "profiles cannot select other profiles")
+
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "nothing is written for invalid values")
+}
+
+func TestUnsetInFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logbasset.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("token: t\nserver: https://eu.scalyr.com\nprofiles:\n  eu:\n    token: e\n"), 0600))
+
+	removed, err := UnsetInFile(path, "server")
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	removed, err = UnsetInFile(path, "profiles.eu.token")
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	removed, err = UnsetInFile(path, "priority")
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "token: t\nprofiles:\n  eu: {}\n", string(data))
+
+	removed, err = UnsetInFile(filepath.Join(t.TempDir(), "missing.yaml"), "token")
+	require.NoError(t, err)
+	assert.False(t, removed)
+}
+
+func TestCreateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logbasset.yaml")
+
+	require.NoError(t, CreateFile(path, map[string]string{
+		"priority": "low",
+		"token":    "secret",
+		"server":   "https://eu.scalyr.com",
+	}, false))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "server: https://eu.scalyr.com\ntoken: secret\npriority: low\n", string(data))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Error(t, CreateFile(path, map[string]string{"token": "other"}, false), "existing file is not overwritten")
+	require.NoError(t, CreateFile(path, map[string]string{"token": "other"}, true))
+}
+
+func TestSources(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, profilesYAML)
+	os.Setenv("scalyr_server", "https://env.scalyr.com")
+
+	config, err := LoadProfile("staging")
+	require.NoError(t, err)
+	assert.Equal(t, SourceEnv, config.Sources["server"])
+	assert.Equal(t, SourceProfile, config.Sources["token"])
+	assert.Equal(t, SourceDefault, config.Sources["log_level"])
+	assert.NotEmpty(t, config.File)
+
+	config.SetFromFlags("flag-token", "", false, "", "")
+	assert.Equal(t, SourceFlag, config.Sources["token"])
+
+	value, err := config.Value("profiles.eu.server")
+	require.NoError(t, err)
+	assert.Equal(t, "https://eu.scalyr.com", value)
+
+	value, err = config.Value("timeout")
+	require.NoError(t, err)
+	assert.Equal(t, "2m0s", value)
+}
+
+func TestMalformedFileFailsValidation(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "token: [unterminated\n")
+	os.Setenv("scalyr_readlog_token", "env-token")
+
+	config, err := LoadProfile("")
+	require.NoError(t, err, "loading succeeds so the file can still be inspected")
+	assert.Error(t, config.Validate())
+}
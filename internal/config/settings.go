@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+// Source identifies where the effective value of a setting came from.
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "env"
+	SourceProfile Source = "profile"
+	SourceFile    Source = "file"
+	SourceDefault Source = "default"
+	// SourceCommand and SourceStore only apply to the token.
+	SourceCommand Source = "token_command"
+	SourceStore   Source = "credential_store"
+)
+
+// Setting describes a top-level configuration key.
+type Setting struct {
+	Key string
+	// Env is the environment variable that overrides the key.
+	Env string
+	// Secret settings are masked when listed.
+	Secret bool
+	// InProfile reports whether the key may also be set inside a profile.
+	InProfile bool
+}
+
+// Settings lists every top-level key of logbasset.yaml in display order.
+var Settings = []Setting{
+	{Key: "server", Env: "scalyr_server", InProfile: true},
+	{Key: "token", Env: "scalyr_readlog_token", Secret: true, InProfile: true},
+	{Key: "token_command", Env: "scalyr_token_command", InProfile: true},
+	{Key: "credential_store", Env: "scalyr_credential_store"},
+	{Key: "write_token", Env: "scalyr_writelog_token", Secret: true, InProfile: true},
+	{Key: "config_read_token", Env: "scalyr_readconfig_token", Secret: true, InProfile: true},
+	{Key: "config_write_token", Env: "scalyr_writeconfig_token", Secret: true, InProfile: true},
+	{Key: "verbose", Env: "scalyr_verbose", InProfile: true},
+	{Key: "priority", Env: "scalyr_priority", InProfile: true},
+	{Key: "log_level", Env: "scalyr_log_level", InProfile: true},
+	{Key: "timeout", Env: "scalyr_timeout", InProfile: true},
+	{Key: "color", Env: "scalyr_color"},
+	{Key: "time_format", Env: "scalyr_time_format"},
+	{Key: "tz", Env: "scalyr_tz"},
+	{Key: "profile", Env: "scalyr_profile"},
+}
+
+// envSet reports whether the setting is overridden from the environment,
+// either through its documented variable or viper's SCALYR_<KEY> fallback.
+func (s Setting) envSet() bool {
+	for _, name := range []string{s.Env, "SCALYR_" + strings.ToUpper(s.Key)} {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchDirs returns the directories searched for logbasset.yaml, in the
+// order they are tried.
+func SearchDirs() []string {
+	var dirs []string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs,
+			filepath.Join(homeDir, ".config", "logbasset"),
+			filepath.Join(homeDir, ".logbasset"),
+		)
+	}
+	return append(dirs, ".")
+}
+
+// DefaultPath is where a new config file is created when none exists yet.
+func DefaultPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.NewConfigError("cannot determine home directory", err)
+	}
+	return filepath.Join(homeDir, ".config", "logbasset", "logbasset.yaml"), nil
+}
+
+// LookupSetting resolves a key accepted by `config get/set/unset`: either a
+// top-level key such as "server", or "profiles.<name>.<key>" for a profile.
+// It returns the setting and the profile name (empty for top-level keys).
+func LookupSetting(key string) (Setting, string, error) {
+	parts := strings.Split(strings.ToLower(key), ".")
+
+	name := parts[len(parts)-1]
+	profile := ""
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 3 && parts[0] == "profiles" && parts[1] != "":
+		profile = parts[1]
+	default:
+		return Setting{}, "", unknownKeyError(key)
+	}
+
+	for _, s := range Settings {
+		if s.Key == name && (profile == "" || s.InProfile) {
+			return s, profile, nil
+		}
+	}
+	return Setting{}, "", unknownKeyError(key)
+}
+
+func unknownKeyError(key string) error {
+	keys := make([]string, 0, len(Settings))
+	for _, s := range Settings {
+		keys = append(keys, s.Key)
+	}
+	return errors.NewUsageError(
+		fmt.Sprintf("unknown config key: %s", key),
+		fmt.Errorf("valid keys are %s, or profiles.<name>.<key>", strings.Join(keys, ", ")),
+	)
+}
+
+// ValidateSetting checks that value is acceptable for key before it is
+// written to the config file.
+func ValidateSetting(key, value string) error {
+	s, _, err := LookupSetting(key)
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		return errors.NewValidationError(fmt.Sprintf("%s must not be empty", key), nil)
+	}
+
+	switch s.Key {
+	case "server":
+		return validateServer(value)
+	case "priority":
+		return validatePriority(value)
+	case "log_level":
+		return validateLogLevel(value)
+	case "color":
+		return validateColor(value)
+	case "tz":
+		return validateTZ(value)
+	case "verbose":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.NewValidationError("verbose must be true or false", err)
+		}
+	case "credential_store":
+		if _, ok := CredentialStores[value]; !ok {
+			return errors.NewValidationError(
+				fmt.Sprintf("unknown credential store: %s", value),
+				fmt.Errorf("valid stores are: %s", strings.Join(credentialStoreNames(), ", ")),
+			)
+		}
+	case "timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return errors.NewValidationError(
+				fmt.Sprintf("invalid timeout: %s", value),
+				fmt.Errorf("use a duration such as 30s or 2m"),
+			)
+		}
+	}
+
+	return nil
+}
+
+// Value returns the effective value of key as a string. Profile keys report
+// the value written in the file for that profile.
+func (c *Config) Value(key string) (string, error) {
+	s, profile, err := LookupSetting(key)
+	if err != nil {
+		return "", err
+	}
+
+	if profile != "" {
+		p := c.Profiles[profile]
+		return (&Config{
+			Server:           p.Server,
+			Token:            p.Token,
+			TokenCommand:     p.TokenCommand,
+			WriteToken:       p.WriteToken,
+			ConfigReadToken:  p.ConfigReadToken,
+			ConfigWriteToken: p.ConfigWriteToken,
+			Verbose:          p.Verbose != nil && *p.Verbose,
+			Priority:         p.Priority,
+			LogLevel:         p.LogLevel,
+			Timeout:          p.Timeout,
+		}).value(s.Key), nil
+	}
+	return c.value(s.Key), nil
+}
+
+func (c *Config) value(key string) string {
+	switch key {
+	case "server":
+		return c.Server
+	case "token":
+		return c.Token
+	case "token_command":
+		return c.TokenCommand
+	case "credential_store":
+		return c.CredentialStore
+	case "write_token":
+		return c.WriteToken
+	case "config_read_token":
+		return c.ConfigReadToken
+	case "config_write_token":
+		return c.ConfigWriteToken
+	case "verbose":
+		return strconv.FormatBool(c.Verbose)
+	case "priority":
+		return c.Priority
+	case "log_level":
+		return c.LogLevel
+	case "timeout":
+		if c.Timeout > 0 {
+			return c.Timeout.String()
+		}
+		return ""
+	case "color":
+		return c.Color
+	case "time_format":
+		return c.TimeFormat
+	case "tz":
+		return c.TZ
+	case "profile":
+		return c.Profile
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+// TokenKind selects which configured token a command authenticates with.
+// Scalyr issues separate API keys for reading logs, writing logs, and
+// reading or writing configuration files.
+type TokenKind string
+
+const (
+	TokenRead        TokenKind = "read"
+	TokenWrite       TokenKind = "write"
+	TokenConfigRead  TokenKind = "config-read"
+	TokenConfigWrite TokenKind = "config-write"
+)
+
+// tokenSettings maps each kind other than TokenRead to its config key.
+var tokenSettings = map[TokenKind]string{
+	TokenWrite:       "write_token",
+	TokenConfigRead:  "config_read_token",
+	TokenConfigWrite: "config_write_token",
+}
+
+// TokenFor returns the token used for kind. A config write token also grants
+// read access to configuration files, so it backs TokenConfigRead.
+func (c *Config) TokenFor(kind TokenKind) string {
+	switch kind {
+	case TokenWrite:
+		return c.WriteToken
+	case TokenConfigRead:
+		if c.ConfigReadToken != "" {
+			return c.ConfigReadToken
+		}
+		return c.ConfigWriteToken
+	case TokenConfigWrite:
+		return c.ConfigWriteToken
+	default:
+		return c.Token
+	}
+}
+
+// SetTokenFromFlag applies --token to the token used for kind.
+func (c *Config) SetTokenFromFlag(kind TokenKind, token string) {
+	if token == "" {
+		return
+	}
+	switch kind {
+	case TokenWrite:
+		c.WriteToken = token
+	case TokenConfigRead:
+		c.ConfigReadToken = token
+	case TokenConfigWrite:
+		c.ConfigWriteToken = token
+	default:
+		c.Token = token
+		c.SetSource("token", SourceFlag)
+		return
+	}
+	c.SetSource(tokenSettings[kind], SourceFlag)
+}
+
+// ValidateFor validates the configuration for commands that authenticate
+// with kind. TokenRead is the same as Validate.
+func (c *Config) ValidateFor(kind TokenKind) error {
+	key, ok := tokenSettings[kind]
+	if !ok {
+		return validateConfig(c)
+	}
+
+	if c.readErr != nil {
+		return c.readErr
+	}
+
+	if c.TokenFor(kind) == "" {
+		var env string
+		for _, s := range Settings {
+			if s.Key == key {
+				env = s.Env
+			}
+		}
+		return errors.NewAuthError(
+			fmt.Sprintf("%s API token is required", kind),
+			fmt.Errorf("set %s in the config file or the %s env var", key, env),
+		)
+	}
+
+	return validateConnection(c)
+}
+
+// ClientFor returns a client authorised with the token for kind.
+func (c *Config) ClientFor(kind TokenKind) *client.Client {
+	return client.New(c.TokenFor(kind), c.Server, c.Verbose)
+}
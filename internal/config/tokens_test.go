@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateForWriteToken(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "token: read-token\nprofiles:\n  ingest:\n    write_token: profile-write-token\n")
+
+	config, err := LoadProfile("")
+	require.NoError(t, err)
+	require.NoError(t, config.ValidateFor(TokenRead))
+	assert.Error(t, config.ValidateFor(TokenWrite), "the read token does not authorise writes")
+
+	os.Setenv("scalyr_writelog_token", "env-write-token")
+	config, err = LoadProfile("")
+	require.NoError(t, err)
+	require.NoError(t, config.ValidateFor(TokenWrite))
+	assert.Equal(t, "env-write-token", config.TokenFor(TokenWrite))
+	assert.Equal(t, SourceEnv, config.Sources["write_token"])
+
+	os.Unsetenv("scalyr_writelog_token")
+	config, err = LoadProfile("ingest")
+	require.NoError(t, err)
+	assert.Equal(t, "profile-write-token", config.TokenFor(TokenWrite))
+	assert.Equal(t, "read-token", config.TokenFor(TokenRead))
+}
+
+func TestTokenForConfigFiles(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "token: read-token\nconfig_write_token: cfg-write\n")
+
+	config, err := LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "cfg-write", config.TokenFor(TokenConfigRead), "a config write token can also read")
+	assert.Equal(t, "cfg-write", config.TokenFor(TokenConfigWrite))
+	require.NoError(t, config.ValidateFor(TokenConfigRead))
+
+	os.Setenv("scalyr_readconfig_token", "cfg-read")
+	config, err = LoadProfile("")
+	require.NoError(t, err)
+	assert.Equal(t, "cfg-read", config.TokenFor(TokenConfigRead))
+
+	config.SetTokenFromFlag(TokenConfigWrite, "flag-token")
+	assert.Equal(t, "flag-token", config.TokenFor(TokenConfigWrite))
+	assert.Equal(t, "read-token", config.Token, "--token only replaces the token of the command's kind")
+	assert.Equal(t, SourceFlag, config.Sources["config_write_token"])
+}
+
+func TestValidateForMissingConfigToken(t *testing.T) {
+	clearEnv()
+	defer clearEnv()
+	writeConfigFile(t, "token: read-token\n")
+
+	config, err := LoadProfile("")
+	require.NoError(t, err)
+	err = config.ValidateFor(TokenConfigWrite)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config-write API token is required")
+}
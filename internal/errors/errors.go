@@ -232,3 +232,10 @@ func HandleErrorAndExit(err error) {
 	runBeforeExit()
 	os.Exit(ExitGeneral)
 }
+
+// Exit runs BeforeExit and exits with code, for commands such as check whose
+// exit code reports a result rather than an error.
+func Exit(code int) {
+	runBeforeExit()
+	os.Exit(code)
+}
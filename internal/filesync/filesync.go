@@ -0,0 +1,182 @@
+// Package filesync maps Scalyr configuration files (parsers, dashboards,
+// alerts, lookup tables) onto a local directory tree so they can be kept in
+// version control, and records which remote version each local file was
+// pulled at.
+package filesync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+// ManifestName is the file in the synced directory that records the remote
+// version and content hash of every pulled or pushed file.
+const ManifestName = ".logbasset-files.json"
+
+// Entry records the state of one file as of the last pull or push.
+type Entry struct {
+	Version int64  `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+// Manifest maps remote paths such as "/scalyr/parsers/app" to their Entry.
+type Manifest struct {
+	Files map[string]Entry `json:"files"`
+}
+
+// LoadManifest reads the manifest of dir, returning an empty one when the
+// directory has not been synced yet.
+func LoadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]Entry)}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, errors.NewConfigError("failed to read "+ManifestName, err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.NewParseError("failed to parse "+filepath.Join(dir, ManifestName), err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]Entry)
+	}
+	return m, nil
+}
+
+// Save writes the manifest to dir with sorted keys, so it diffs cleanly.
+func (m *Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.NewParseError("failed to encode "+ManifestName, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.NewConfigError("failed to create directory "+dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), append(data, '\n'), 0644); err != nil {
+		return errors.NewConfigError("failed to write "+ManifestName, err)
+	}
+	return nil
+}
+
+// Hash returns the hex SHA-256 of content.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// CleanRemotePath normalises a remote path to the "/a/b" form and rejects
+// paths that would escape the synced directory.
+func CleanRemotePath(remote string) (string, error) {
+	if remote == "" {
+		return "", errors.NewValidationError("file path must not be empty", nil)
+	}
+	for _, part := range strings.Split(remote, "/") {
+		if part == ".." {
+			return "", errors.NewValidationError("file path must not contain '..': "+remote, nil)
+		}
+	}
+	cleaned := path.Clean("/" + remote)
+	if cleaned == "/" || path.Base(cleaned) == ManifestName {
+		return "", errors.NewValidationError("invalid file path: "+remote, nil)
+	}
+	return cleaned, nil
+}
+
+// LocalPath returns where the remote file is stored under dir.
+func LocalPath(dir, remote string) (string, error) {
+	cleaned, err := CleanRemotePath(remote)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(cleaned, "/"))), nil
+}
+
+// Walk returns the remote paths of the files stored under dir, sorted. The
+// manifest and hidden files and directories, such as .git, are skipped, so
+// a pulled file with a hidden path is only found through the manifest.
+func Walk(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, "/"+filepath.ToSlash(rel))
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.NewConfigError("failed to read directory "+dir, err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Hidden reports whether a remote path has a file or directory name starting
+// with ".", which Walk skips.
+func Hidden(remote string) bool {
+	for _, part := range strings.Split(remote, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadLocal returns the content of the remote file's local copy, and false
+// when there is none.
+func ReadLocal(dir, remote string) ([]byte, bool, error) {
+	local, err := LocalPath(dir, remote)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := os.ReadFile(local)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.NewConfigError("failed to read "+local, err)
+	}
+	return data, true, nil
+}
+
+// WriteLocal stores content as the remote file's local copy, creating parent
+// directories as needed.
+func WriteLocal(dir, remote string, content []byte) error {
+	local, err := LocalPath(dir, remote)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return errors.NewConfigError("failed to create directory "+filepath.Dir(local), err)
+	}
+	if err := os.WriteFile(local, content, 0644); err != nil {
+		return errors.NewConfigError("failed to write "+local, err)
+	}
+	return nil
+}
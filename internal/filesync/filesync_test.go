@@ -0,0 +1,90 @@
+package filesync
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanRemotePath(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "/scalyr/parsers/app", want: "/scalyr/parsers/app"},
+		{in: "dashboards/errors", want: "/dashboards/errors"},
+		{in: "/a//b/", want: "/a/b"},
+		{in: "", wantErr: true},
+		{in: "/", wantErr: true},
+		{in: "/a/../../etc/passwd", wantErr: true},
+		{in: "/" + ManifestName, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := CleanRemotePath(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWriteReadAndWalk(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, WriteLocal(dir, "/scalyr/parsers/app", []byte("parser")))
+	require.NoError(t, WriteLocal(dir, "/dashboards/errors", []byte("dashboard")))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0644))
+	require.NoError(t, (&Manifest{Files: map[string]Entry{}}).Save(dir))
+
+	paths, err := Walk(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/dashboards/errors", "/scalyr/parsers/app"}, paths, "hidden entries and the manifest are skipped")
+
+	data, ok, err := ReadLocal(dir, "/scalyr/parsers/app")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "parser", string(data))
+
+	_, ok, err = ReadLocal(dir, "/missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	paths, err = Walk(filepath.Join(dir, "does-not-exist"))
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+}
+
+func TestHidden(t *testing.T) {
+	assert.True(t, Hidden("/scalyr/.lookups"))
+	assert.True(t, Hidden("/.config/parser"))
+	assert.False(t, Hidden("/scalyr/parsers/app.v2"))
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Empty(t, m.Files)
+
+	m.Files["/a"] = Entry{Version: 3, SHA256: Hash([]byte("x"))}
+	require.NoError(t, m.Save(dir))
+
+	loaded, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, m.Files, loaded.Files)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestName), []byte("{"), 0644))
+	_, err = LoadManifest(dir)
+	assert.Error(t, err)
+}
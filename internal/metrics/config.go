@@ -0,0 +1,194 @@
+// Package metrics exports the results of timeseries and numeric queries as
+// Prometheus metrics, so signals derived from Scalyr logs can be scraped
+// like any other target.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/timerange"
+	"github.com/andreagrandi/logbasset/internal/validation"
+	"go.yaml.in/yaml/v3"
+)
+
+// DefaultCache is how long query results are reused across scrapes when the
+// config file does not set cache.
+const DefaultCache = time.Minute
+
+// Types are the Prometheus metric types a metric can be exported as.
+var Types = []string{"gauge", "counter", "untyped"}
+
+// Queries are the kinds of query a metric can be computed with.
+var Queries = []string{"timeseries", "numeric"}
+
+var (
+	metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Config is a metrics config file: the metrics to export and how long their
+// values are cached.
+type Config struct {
+	// Cache is how long query results are reused before a scrape runs the
+	// queries again.
+	Cache   time.Duration
+	Metrics []Metric
+}
+
+// Metric is one exported series: the value of a query over its time range,
+// with the name and labels it is exported under. Several metrics may share
+// a name when their labels differ.
+type Metric struct {
+	Name     string            `yaml:"name"`
+	Help     string            `yaml:"help"`
+	Type     string            `yaml:"type"`
+	Query    string            `yaml:"query"`
+	Filter   string            `yaml:"filter"`
+	Function string            `yaml:"function"`
+	Start    string            `yaml:"start"`
+	End      string            `yaml:"end"`
+	Labels   map[string]string `yaml:"labels"`
+}
+
+// file is the document a metrics config file holds. start is the default of
+// every metric that sets none.
+type file struct {
+	Cache   string   `yaml:"cache"`
+	Start   string   `yaml:"start"`
+	Metrics []Metric `yaml:"metrics"`
+}
+
+// Load reads and validates the metrics config file at path, filling in the
+// defaults of every metric.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.NewValidationError("cannot read metrics config "+path, err)
+	}
+
+	var doc file
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, errors.NewValidationError("invalid metrics config "+path, err)
+	}
+	return doc.config()
+}
+
+// config validates the file and returns its Config.
+func (f file) config() (*Config, error) {
+	c := &Config{Cache: DefaultCache, Metrics: f.Metrics}
+	if f.Cache != "" {
+		d, err := timerange.ParseDuration(f.Cache)
+		if err != nil || d <= 0 {
+			return nil, errors.NewValidationError(
+				fmt.Sprintf("invalid cache duration: %s", f.Cache),
+				fmt.Errorf("use a duration such as 30s, 5m or 1h"),
+			)
+		}
+		c.Cache = d
+	}
+	if len(c.Metrics) == 0 {
+		return nil, errors.NewValidationError("no metrics in the metrics config",
+			fmt.Errorf("list them under \"metrics\", each with a name, filter, function and start"))
+	}
+
+	families := make(map[string]Metric)
+	series := make(map[string]bool)
+	for i := range c.Metrics {
+		m := &c.Metrics[i]
+		if m.Type == "" {
+			m.Type = "gauge"
+		}
+		if m.Query == "" {
+			m.Query = "timeseries"
+		}
+		if m.Start == "" {
+			m.Start = f.Start
+		}
+		if err := m.validate(); err != nil {
+			return nil, err
+		}
+
+		// Series of one name form a family, described once
+		if first, ok := families[m.Name]; ok && (first.Help != m.Help || first.Type != m.Type) {
+			return nil, errors.NewValidationError(
+				fmt.Sprintf("metric %s is declared with different help or type", m.Name),
+				fmt.Errorf("metrics sharing a name must have the same help and type"),
+			)
+		} else if !ok {
+			families[m.Name] = *m
+		}
+		key := m.Name + "{" + m.labelString() + "}"
+		if series[key] {
+			return nil, errors.NewValidationError("duplicate metric "+key,
+				fmt.Errorf("metrics sharing a name need different labels"))
+		}
+		series[key] = true
+	}
+	return c, nil
+}
+
+func (m Metric) validate() error {
+	if !metricNameRegex.MatchString(m.Name) {
+		return errors.NewValidationError(
+			fmt.Sprintf("invalid metric name %q", m.Name),
+			fmt.Errorf("names match [a-zA-Z_:][a-zA-Z0-9_:]*"),
+		)
+	}
+	if !slices.Contains(Types, m.Type) {
+		return errors.NewValidationError(
+			fmt.Sprintf("invalid type %q for metric %s", m.Type, m.Name),
+			fmt.Errorf("valid types: %s", strings.Join(Types, ", ")),
+		)
+	}
+	if !slices.Contains(Queries, m.Query) {
+		return errors.NewValidationError(
+			fmt.Sprintf("invalid query %q for metric %s", m.Query, m.Name),
+			fmt.Errorf("valid queries: %s", strings.Join(Queries, ", ")),
+		)
+	}
+	for name := range m.Labels {
+		if !labelNameRegex.MatchString(name) || strings.HasPrefix(name, "__") {
+			return errors.NewValidationError(
+				fmt.Sprintf("invalid label name %q for metric %s", name, m.Name),
+				fmt.Errorf("label names match [a-zA-Z_][a-zA-Z0-9_]* and do not start with __"),
+			)
+		}
+	}
+	if m.Start == "" {
+		return errors.NewValidationError("metric "+m.Name+" has no start",
+			fmt.Errorf("set start on the metric, or at the top of the file for every metric"))
+	}
+	if err := validation.ValidateTimeFormat(m.Start); err != nil {
+		return err
+	}
+	if err := validation.ValidateTimeFormat(m.End); err != nil {
+		return err
+	}
+	return validation.ValidateQuerySyntax(m.Filter)
+}
+
+// labelString returns the labels as Prometheus writes them, sorted by name:
+// a="1",b="2".
+func (m Metric) labelString() string {
+	names := make([]string, 0, len(m.Labels))
+	for name := range m.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelEscaper.Replace(m.Labels[name]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
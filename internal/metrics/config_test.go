@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "metrics.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load(writeConfig(t, `cache: 5m
+start: 10m
+metrics:
+  - name: scalyr_errors
+    help: Errors in the last 10 minutes
+    filter: severity >= 5
+    function: count
+    labels:
+      service: api
+  - name: scalyr_errors
+    help: Errors in the last 10 minutes
+    filter: severity >= 5
+    function: count
+    labels:
+      service: web
+  - name: scalyr_bytes_total
+    type: counter
+    query: numeric
+    function: sum(bytes)
+    start: 1h
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, c.Cache)
+	assert.Equal(t, []Metric{
+		{Name: "scalyr_errors", Help: "Errors in the last 10 minutes", Type: "gauge", Query: "timeseries", Filter: "severity >= 5", Function: "count", Start: "10m", Labels: map[string]string{"service": "api"}},
+		{Name: "scalyr_errors", Help: "Errors in the last 10 minutes", Type: "gauge", Query: "timeseries", Filter: "severity >= 5", Function: "count", Start: "10m", Labels: map[string]string{"service": "web"}},
+		{Name: "scalyr_bytes_total", Type: "counter", Query: "numeric", Function: "sum(bytes)", Start: "1h"},
+	}, c.Metrics, "type, query and start default to gauge, timeseries and the top-level start")
+
+	c, err = Load(writeConfig(t, "metrics:\n  - name: up_errors\n    start: 5m\n"))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCache, c.Cache)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"no metrics":         "cache: 1m\n",
+		"unknown key":        "metrics:\n  - name: a\n    start: 5m\n    fliter: x\n",
+		"bad cache":          "cache: soon\nmetrics:\n  - name: a\n    start: 5m\n",
+		"bad name":           "metrics:\n  - name: 1errors\n    start: 5m\n",
+		"bad type":           "metrics:\n  - name: a\n    type: histogram\n    start: 5m\n",
+		"bad query":          "metrics:\n  - name: a\n    query: facet\n    start: 5m\n",
+		"bad label":          "metrics:\n  - name: a\n    start: 5m\n    labels:\n      __name__: x\n",
+		"no start":           "metrics:\n  - name: a\n",
+		"bad start":          "metrics:\n  - name: a\n    start: yesterday-ish\n",
+		"duplicate series":   "metrics:\n  - name: a\n    start: 5m\n  - name: a\n    start: 1h\n",
+		"conflicting family": "metrics:\n  - name: a\n    start: 5m\n    labels: {x: '1'}\n  - name: a\n    type: counter\n    start: 5m\n    labels: {x: '2'}\n",
+	} {
+		_, err := Load(writeConfig(t, content))
+		assert.Error(t, err, name)
+	}
+
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
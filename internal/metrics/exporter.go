@@ -0,0 +1,310 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/logging"
+)
+
+// numericParallel bounds the numeric queries a refresh runs at once.
+const numericParallel = 4
+
+// ContentType is the Prometheus text exposition format served on /metrics.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// Exporter serves the metrics of a Config. Run refreshes them every
+// Config.Cache, and a scrape finding them older than that starts a refresh
+// in the background itself, so Scalyr is queried about once per cache period
+// however often it is scraped. Only one refresh runs at a time; scrapes are
+// answered from the last values and wait only for the first refresh.
+//
+// A refresh computes every timeseries metric in one request, alongside the
+// numeric metrics, which need a request each and run up to
+// numericParallel at a time.
+type Exporter struct {
+	client   client.ClientInterface
+	config   *Config
+	priority string
+	timeout  time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	values    map[int]float64
+	refreshed time.Time
+	duration  time.Duration
+	up        bool
+	running   chan struct{}
+}
+
+// NewExporter returns an Exporter running the queries of config through c
+// at priority, each refresh limited to timeout.
+func NewExporter(c client.ClientInterface, config *Config, priority string, timeout time.Duration) *Exporter {
+	return &Exporter{
+		client:   c,
+		config:   config,
+		priority: priority,
+		timeout:  timeout,
+		now:      time.Now,
+		values:   make(map[int]float64),
+	}
+}
+
+// Run refreshes the metrics now and then every Config.Cache until ctx is
+// cancelled.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Cache)
+	defer ticker.Stop()
+
+	for {
+		done, _ := e.startRefresh(true)
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP writes the metrics. A scrape before the first refresh has ended
+// waits for it; the others are answered from the last values, including a
+// scrape that finds them stale and starts a refresh.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if done, wait := e.startRefresh(false); wait {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}
+
+	var buf bytes.Buffer
+	e.mu.Lock()
+	e.write(&buf)
+	e.mu.Unlock()
+
+	w.Header().Set("Content-Type", ContentType)
+	if _, err := buf.WriteTo(w); err != nil {
+		logging.Warnf("Writing metrics failed: %v", err)
+	}
+}
+
+// startRefresh starts a refresh unless one is running or, without force, the
+// values are younger than Config.Cache. It returns a channel closed when the
+// running refresh ends, nil when there is none, and whether a scrape should
+// wait for it, which is only while no values have been computed yet.
+func (e *Exporter) startRefresh(force bool) (<-chan struct{}, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.running != nil {
+		return e.running, e.refreshed.IsZero()
+	}
+	if !force && !e.refreshed.IsZero() && e.now().Sub(e.refreshed) < e.config.Cache {
+		return nil, false
+	}
+
+	done := make(chan struct{})
+	e.running = done
+	wait := e.refreshed.IsZero()
+	go func() {
+		defer close(done)
+		if err := e.refresh(); err != nil {
+			logging.Warnf("Refreshing metrics failed, serving the last values: %v", err)
+		}
+	}()
+	return done, wait
+}
+
+// refresh runs every query, keeping the previous value of a metric whose
+// query fails. A query that returns no values removes its metric until it
+// returns one again. The queries run without holding e.mu, and the new values
+// replace the old ones when they have all ended.
+func (e *Exporter) refresh() error {
+	// A refresh outlives the scrape that started it, so it is bounded by its
+	// own timeout rather than the request
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	e.mu.Lock()
+	values := make(map[int]float64, len(e.values))
+	for i, v := range e.values {
+		values[i] = v
+	}
+	e.mu.Unlock()
+
+	started := e.now()
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		firstErr   error
+		timeseries []int
+		params     []client.TimeseriesQueryParams
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	slots := make(chan struct{}, numericParallel)
+
+	for i, m := range e.config.Metrics {
+		if m.Query == "timeseries" {
+			timeseries = append(timeseries, i)
+			params = append(params, client.TimeseriesQueryParams{
+				Filter:    m.Filter,
+				Function:  m.Function,
+				StartTime: m.Start,
+				EndTime:   m.End,
+				Buckets:   1,
+				Priority:  e.priority,
+			})
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result, err := e.client.NumericQuery(ctx, client.NumericQueryParams{
+				Filter:    m.Filter,
+				Function:  m.Function,
+				StartTime: m.Start,
+				EndTime:   m.End,
+				Buckets:   1,
+				Priority:  e.priority,
+			})
+			if err != nil {
+				fail(err)
+				return
+			}
+			mu.Lock()
+			setValue(values, i, result.Values)
+			mu.Unlock()
+		}()
+	}
+
+	if len(params) > 0 {
+		result, err := e.client.TimeseriesQueries(ctx, params)
+		if err != nil {
+			fail(err)
+		} else {
+			mu.Lock()
+			for n, i := range timeseries {
+				var v []float64
+				if n < len(result.Results) {
+					v = result.Results[n].Values
+				}
+				setValue(values, i, v)
+			}
+			mu.Unlock()
+		}
+	}
+	wg.Wait()
+
+	refreshed := e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.values = values
+	e.refreshed = refreshed
+	e.duration = refreshed.Sub(started)
+	e.up = firstErr == nil
+	e.running = nil
+	return firstErr
+}
+
+// setValue records the value of metric i in values: the last of bucketValues,
+// which has one per bucket.
+func setValue(values map[int]float64, i int, bucketValues []float64) {
+	if len(bucketValues) == 0 {
+		delete(values, i)
+		return
+	}
+	values[i] = bucketValues[len(bucketValues)-1]
+}
+
+// write writes the metrics in the Prometheus text format, each family
+// described once before its series, followed by the exporter's own metrics.
+func (e *Exporter) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	described := make(map[string]bool)
+	for i, m := range e.config.Metrics {
+		value, ok := e.values[i]
+		if !ok {
+			continue
+		}
+		if !described[m.Name] {
+			described[m.Name] = true
+			writeDescription(bw, m.Name, m.Help, m.Type)
+		}
+		writeSample(bw, m.Name, m.labelString(), value)
+	}
+
+	up := 0.0
+	if e.up {
+		up = 1
+	}
+	writeDescription(bw, "logbasset_up", "Whether every query of the last refresh succeeded.", "gauge")
+	writeSample(bw, "logbasset_up", "", up)
+	if !e.refreshed.IsZero() {
+		writeDescription(bw, "logbasset_last_refresh_timestamp_seconds", "When the queries were last run, as a Unix time.", "gauge")
+		writeSample(bw, "logbasset_last_refresh_timestamp_seconds", "", float64(e.refreshed.UnixMilli())/1000)
+		writeDescription(bw, "logbasset_refresh_duration_seconds", "How long the last refresh took.", "gauge")
+		writeSample(bw, "logbasset_refresh_duration_seconds", "", e.duration.Seconds())
+	}
+	return bw.Flush()
+}
+
+func writeDescription(w *bufio.Writer, name, help, typ string) {
+	if help != "" {
+		w.WriteString("# HELP " + name + " " + helpEscaper.Replace(help) + "\n")
+	}
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatSample(value) + "\n")
+}
+
+// formatSample renders a value as Prometheus expects, spelling out the
+// special values.
+func formatSample(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+}
@@ -0,0 +1,306 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeScalyr answers timeseriesQuery and numericQuery with the responses set
+// on it, recording the path and body of every request. When gate is set,
+// responses wait until it is closed.
+type fakeScalyr struct {
+	mu         sync.Mutex
+	timeseries string
+	numeric    string
+	gate       chan struct{}
+	requests   []string
+	bodies     []map[string]any
+}
+
+func (s *fakeScalyr) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	raw, _ := io.ReadAll(r.Body)
+	var body map[string]any
+	json.Unmarshal(raw, &body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	s.bodies = append(s.bodies, body)
+	gate := s.gate
+	s.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/api/numericQuery" {
+		io.WriteString(w, s.numeric)
+	} else {
+		io.WriteString(w, s.timeseries)
+	}
+}
+
+var testConfig = &Config{
+	Cache: time.Minute,
+	Metrics: []Metric{
+		{Name: "scalyr_errors", Help: "Errors by service.", Type: "gauge", Query: "timeseries", Filter: "severity >= 5", Function: "count", Start: "5m", Labels: map[string]string{"service": "api", "env": "prod"}},
+		{Name: "scalyr_errors", Help: "Errors by service.", Type: "gauge", Query: "timeseries", Filter: "severity >= 5", Function: "count", Start: "5m", Labels: map[string]string{"service": `we"b`}},
+		{Name: "scalyr_bytes_total", Type: "counter", Query: "numeric", Function: "sum(bytes)", Start: "1h"},
+	},
+}
+
+func newTestExporter(t *testing.T, scalyr *fakeScalyr, clock *time.Time) *Exporter {
+	t.Helper()
+
+	server := httptest.NewServer(scalyr)
+	t.Cleanup(server.Close)
+	c := client.New("test-token", server.URL, false)
+	c.SetRetryPolicy(client.RetryPolicy{})
+	e := NewExporter(c, testConfig, "low", 5*time.Second)
+	e.now = func() time.Time { return *clock }
+	return e
+}
+
+func (s *fakeScalyr) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func scrape(t *testing.T, e *Exporter) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	return rec.Body.String()
+}
+
+// waitForRefresh waits for the refresh running in the background, if any.
+func waitForRefresh(e *Exporter) {
+	e.mu.Lock()
+	done := e.running
+	e.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func TestExporter_Exposition(t *testing.T) {
+	scalyr := &fakeScalyr{
+		timeseries: `{"status":"success","results":[{"values":[12]},{"values":[0.5]}]}`,
+		numeric:    `{"status":"success","values":[1048576]}`,
+	}
+	clock := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	e := newTestExporter(t, scalyr, &clock)
+
+	assert.Equal(t, `# HELP scalyr_errors Errors by service.
+# TYPE scalyr_errors gauge
+scalyr_errors{env="prod",service="api"} 12
+scalyr_errors{service="we\"b"} 0.5
+# TYPE scalyr_bytes_total counter
+scalyr_bytes_total 1048576
+# HELP logbasset_up Whether every query of the last refresh succeeded.
+# TYPE logbasset_up gauge
+logbasset_up 1
+# HELP logbasset_last_refresh_timestamp_seconds When the queries were last run, as a Unix time.
+# TYPE logbasset_last_refresh_timestamp_seconds gauge
+logbasset_last_refresh_timestamp_seconds 1704207845
+# HELP logbasset_refresh_duration_seconds How long the last refresh took.
+# TYPE logbasset_refresh_duration_seconds gauge
+logbasset_refresh_duration_seconds 0
+`, scrape(t, e))
+
+	assert.ElementsMatch(t, []string{"/api/numericQuery", "/api/timeseriesQuery"}, scalyr.requests,
+		"every timeseries metric is computed in one request")
+	for i, path := range scalyr.requests {
+		if path != "/api/timeseriesQuery" {
+			continue
+		}
+		queries := scalyr.bodies[i]["queries"].([]any)
+		require.Len(t, queries, 2)
+		query := queries[0].(map[string]any)
+		assert.Equal(t, "severity >= 5", query["filter"])
+		assert.Equal(t, "5m", query["startTime"])
+		assert.EqualValues(t, 1, query["buckets"])
+		assert.Equal(t, "low", query["priority"])
+	}
+}
+
+func TestExporter_Cache(t *testing.T) {
+	scalyr := &fakeScalyr{
+		timeseries: `{"status":"success","results":[{"values":[1]},{"values":[2]}]}`,
+		numeric:    `{"status":"success","values":[3]}`,
+	}
+	clock := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	e := newTestExporter(t, scalyr, &clock)
+
+	scrape(t, e)
+	clock = clock.Add(30 * time.Second)
+	scalyr.timeseries = `{"status":"success","results":[{"values":[10]},{"values":[20]}]}`
+	out := scrape(t, e)
+	assert.Len(t, scalyr.requests, 2, "a scrape within the cache period runs no queries")
+	assert.Contains(t, out, `scalyr_errors{env="prod",service="api"} 1`+"\n")
+
+	clock = clock.Add(30 * time.Second)
+	scrape(t, e)
+	waitForRefresh(e)
+	assert.Len(t, scalyr.requests, 4, "a scrape after the cache period runs them again")
+	out = scrape(t, e)
+	assert.Contains(t, out, `scalyr_errors{env="prod",service="api"} 10`+"\n")
+}
+
+func TestExporter_FailedQueriesKeepValues(t *testing.T) {
+	scalyr := &fakeScalyr{
+		timeseries: `{"status":"success","results":[{"values":[1]},{"values":[]}]}`,
+		numeric:    `{"status":"success","values":[3]}`,
+	}
+	clock := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	e := newTestExporter(t, scalyr, &clock)
+
+	out := scrape(t, e)
+	assert.Contains(t, out, "logbasset_up 1\n")
+	assert.NotContains(t, out, `service="we\"b"`, "a query without values exports no sample")
+
+	clock = clock.Add(time.Minute)
+	scalyr.numeric = `{"status":"error/client/badParam","message":"bad function"}`
+	scrape(t, e)
+	waitForRefresh(e)
+	out = scrape(t, e)
+	assert.Contains(t, out, "logbasset_up 0\n")
+	assert.Contains(t, out, "scalyr_bytes_total 3\n", "a failed query keeps its last value")
+	assert.Equal(t, 1, strings.Count(out, "# TYPE scalyr_errors gauge"))
+}
+
+func TestExporter_ScrapesDoNotWaitForRunningRefresh(t *testing.T) {
+	scalyr := &fakeScalyr{
+		timeseries: `{"status":"success","results":[{"values":[1]},{"values":[2]}]}`,
+		numeric:    `{"status":"success","values":[3]}`,
+	}
+	clock := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	e := newTestExporter(t, scalyr, &clock)
+	scrape(t, e)
+
+	clock = clock.Add(time.Minute)
+	gate := make(chan struct{})
+	scalyr.mu.Lock()
+	scalyr.gate = gate
+	scalyr.timeseries = `{"status":"success","results":[{"values":[10]},{"values":[20]}]}`
+	scalyr.mu.Unlock()
+
+	scraped := make(chan string)
+	go func() { scraped <- scrape(t, e) }()
+	var out string
+	select {
+	case out = <-scraped:
+	case <-time.After(5 * time.Second):
+		close(gate)
+		t.Fatal("the stale scrape waited for the refresh it started")
+	}
+	assert.Contains(t, out, `scalyr_errors{env="prod",service="api"} 1`+"\n",
+		"the stale scrape is answered from the last values")
+	require.Eventually(t, func() bool { return scalyr.requestCount() > 2 }, 5*time.Second, time.Millisecond,
+		"the stale scrape starts a refresh in the background")
+
+	out = scrape(t, e)
+	assert.Contains(t, out, `scalyr_errors{env="prod",service="api"} 1`+"\n",
+		"a scrape during a refresh is answered from the last values")
+	assert.LessOrEqual(t, scalyr.requestCount(), 4, "only one refresh runs at a time")
+
+	close(gate)
+	waitForRefresh(e)
+	assert.Contains(t, scrape(t, e), `scalyr_errors{env="prod",service="api"} 10`+"\n")
+	assert.Len(t, scalyr.requests, 4)
+}
+
+func TestExporter_FirstScrapeWaitsForRefresh(t *testing.T) {
+	scalyr := &fakeScalyr{
+		timeseries: `{"status":"success","results":[{"values":[1]},{"values":[2]}]}`,
+		numeric:    `{"status":"success","values":[3]}`,
+		gate:       make(chan struct{}),
+	}
+	clock := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	e := newTestExporter(t, scalyr, &clock)
+
+	scraped := make(chan string)
+	go func() { scraped <- scrape(t, e) }()
+	require.Eventually(t, func() bool { return scalyr.requestCount() > 0 }, 5*time.Second, time.Millisecond)
+	select {
+	case <-scraped:
+		t.Fatal("a scrape before the first refresh has ended was answered without values")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	scalyr.mu.Lock()
+	close(scalyr.gate)
+	scalyr.mu.Unlock()
+	assert.Contains(t, <-scraped, "scalyr_bytes_total 3\n")
+}
+
+func TestExporter_Run(t *testing.T) {
+	scalyr := &fakeScalyr{
+		timeseries: `{"status":"success","results":[{"values":[1]},{"values":[2]}]}`,
+		numeric:    `{"status":"success","values":[3]}`,
+	}
+	clock := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	e := newTestExporter(t, scalyr, &clock)
+	config := *testConfig
+	config.Cache = 10 * time.Millisecond
+	e.config = &config
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		e.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool { return scalyr.requestCount() >= 4 }, 5*time.Second, time.Millisecond,
+		"Run refreshes at once and again every cache period, unscraped")
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+	assert.Contains(t, scrape(t, e), "scalyr_bytes_total 3\n")
+}
+
+func TestExporter_NumericQueriesRunConcurrently(t *testing.T) {
+	gate := make(chan struct{})
+	scalyr := &fakeScalyr{numeric: `{"status":"success","values":[1]}`, gate: gate}
+	server := httptest.NewServer(scalyr)
+	t.Cleanup(server.Close)
+	c := client.New("test-token", server.URL, false)
+	c.SetRetryPolicy(client.RetryPolicy{})
+
+	config := &Config{Cache: time.Minute}
+	for range numericParallel + 2 {
+		config.Metrics = append(config.Metrics, Metric{Name: "scalyr_bytes_total", Type: "counter", Query: "numeric", Function: "sum(bytes)", Start: "1h"})
+	}
+	e := NewExporter(c, config, "low", 5*time.Second)
+
+	done := make(chan error)
+	go func() { done <- e.refresh() }()
+	require.Eventually(t, func() bool { return scalyr.requestCount() == numericParallel }, 5*time.Second, time.Millisecond,
+		"numeric queries do not wait for each other")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, numericParallel, scalyr.requestCount(), "at most numericParallel queries run at once")
+
+	close(gate)
+	require.NoError(t, <-done)
+	assert.Equal(t, numericParallel+2, scalyr.requestCount())
+}
@@ -0,0 +1,68 @@
+package output
+
+import "sort"
+
+// attribute is one key/value pair of an event's attributes.
+type attribute struct {
+	key   string
+	value any
+}
+
+// attributeLayout decides the order line-oriented formats write an event's
+// attributes in, and whether nested objects are flattened.
+type attributeLayout struct {
+	rank    map[string]int
+	flatten bool
+}
+
+func newAttributeLayout(opts Options) attributeLayout {
+	rank := make(map[string]int, len(opts.AttrOrder))
+	for i, key := range opts.AttrOrder {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
+	}
+	return attributeLayout{rank: rank, flatten: opts.Flatten}
+}
+
+// attributes returns attrs in a stable order: the keys of Options.AttrOrder
+// first, as they are listed, then the rest sorted by name. When flattening,
+// nested objects are expanded into dotted keys, so {"http": {"status": 500}}
+// becomes http.status=500, and AttrOrder can name those keys.
+func (l attributeLayout) attributes(attrs map[string]any) []attribute {
+	pairs := make([]attribute, 0, len(attrs))
+	for k, v := range attrs {
+		if l.flatten {
+			pairs = flattenAttribute(pairs, k, v)
+		} else {
+			pairs = append(pairs, attribute{key: k, value: v})
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		ri, iPinned := l.rank[pairs[i].key]
+		rj, jPinned := l.rank[pairs[j].key]
+		switch {
+		case iPinned && jPinned:
+			return ri < rj
+		case iPinned != jPinned:
+			return iPinned
+		default:
+			return pairs[i].key < pairs[j].key
+		}
+	})
+	return pairs
+}
+
+// flattenAttribute appends value under key, or the leaves of a non-empty
+// nested object under dotted keys. Arrays are kept whole.
+func flattenAttribute(pairs []attribute, key string, value any) []attribute {
+	obj, ok := value.(map[string]any)
+	if !ok || len(obj) == 0 {
+		return append(pairs, attribute{key: key, value: value})
+	}
+	for k, v := range obj {
+		pairs = flattenAttribute(pairs, key+"."+k, v)
+	}
+	return pairs
+}
@@ -0,0 +1,290 @@
+package output
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+// Theme holds the ANSI SGR codes, such as "31" or "1;35", that the text
+// formats colour events with. An empty code leaves that part uncoloured.
+type Theme struct {
+	Debug, Info, Warning, Error, Fatal string
+	// Timestamp colours event timestamps.
+	Timestamp string
+	// Key colours field names, such as "Message:" in multiline.
+	Key string
+	// Highlight marks the filter terms found in messages.
+	Highlight string
+}
+
+// DefaultTheme leaves info events uncoloured so the levels that need
+// attention stand out.
+func DefaultTheme() Theme {
+	return Theme{
+		Debug:     "90",
+		Warning:   "33",
+		Error:     "31",
+		Fatal:     "1;35",
+		Timestamp: "2",
+		Key:       "1",
+		Highlight: "7",
+	}
+}
+
+// ThemeKeys are the names theme overrides are given under, in logbasset.yaml.
+var ThemeKeys = []string{"debug", "info", "warning", "error", "fatal", "timestamp", "key", "highlight"}
+
+// Severity returns the code for a Scalyr severity, by its SeverityChar.
+func (t Theme) Severity(sev int) string {
+	switch SeverityChar(sev) {
+	case "D":
+		return t.Debug
+	case "I":
+		return t.Info
+	case "W":
+		return t.Warning
+	case "E":
+		return t.Error
+	default:
+		return t.Fatal
+	}
+}
+
+// WithOverrides returns the theme with the colours named in overrides, keyed
+// by ThemeKeys, replacing its own.
+func (t Theme) WithOverrides(overrides map[string]string) (Theme, error) {
+	fields := map[string]*string{
+		"debug":     &t.Debug,
+		"info":      &t.Info,
+		"warning":   &t.Warning,
+		"error":     &t.Error,
+		"fatal":     &t.Fatal,
+		"timestamp": &t.Timestamp,
+		"key":       &t.Key,
+		"highlight": &t.Highlight,
+	}
+	for key, spec := range overrides {
+		field, ok := fields[strings.ToLower(key)]
+		if !ok {
+			return t, errors.NewConfigError(
+				fmt.Sprintf("unknown theme key: %s", key),
+				fmt.Errorf("valid keys are %s", strings.Join(ThemeKeys, ", ")),
+			)
+		}
+		code, err := ParseColor(spec)
+		if err != nil {
+			return t, errors.NewConfigError(fmt.Sprintf("invalid theme colour for %s", key), err)
+		}
+		*field = code
+	}
+	return t, nil
+}
+
+// colorCodes are the names ParseColor accepts.
+var colorCodes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"reverse":   "7",
+	"black":     "30",
+	"red":       "31",
+	"green":     "32",
+	"yellow":    "33",
+	"blue":      "34",
+	"magenta":   "35",
+	"cyan":      "36",
+	"white":     "37",
+	"gray":      "90",
+	"grey":      "90",
+}
+
+var sgrCode = regexp.MustCompile(`^[0-9]+(;[0-9]+)*$`)
+
+// ParseColor turns a colour such as "red", "bold yellow" or a raw SGR code
+// like "38;5;208" into an SGR code. "none" or an empty string mean no colour.
+func ParseColor(spec string) (string, error) {
+	var codes []string
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(spec, ",", " "))) {
+		if word == "none" {
+			continue
+		}
+		if code, ok := colorCodes[word]; ok {
+			codes = append(codes, code)
+			continue
+		}
+		if !sgrCode.MatchString(word) {
+			names := make([]string, 0, len(colorCodes))
+			for name := range colorCodes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return "", fmt.Errorf("unknown colour %q, use one of %s or an SGR code", word, strings.Join(names, ", "))
+		}
+		codes = append(codes, word)
+	}
+	return strings.Join(codes, ";"), nil
+}
+
+// paint wraps s in the SGR code, when there is one.
+func paint(code, s string) string {
+	if code == "" || s == "" {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+// highlighter paints messages, marking the filter terms they contain.
+type highlighter struct {
+	terms *regexp.Regexp
+	code  string
+}
+
+// newHighlighter matches terms case-insensitively, like Scalyr's text
+// search. It never highlights when terms or code are empty.
+func newHighlighter(terms []string, code string) highlighter {
+	if len(terms) == 0 || code == "" {
+		return highlighter{}
+	}
+
+	// Longer terms first, so a term is not cut short by its own prefix
+	sorted := append([]string(nil), terms...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	quoted := make([]string, len(sorted))
+	for i, term := range sorted {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	return highlighter{terms: regexp.MustCompile("(?i)" + strings.Join(quoted, "|")), code: code}
+}
+
+// paint colours s with base, highlighting any terms.
+func (h highlighter) paint(base, s string) string {
+	if h.terms == nil {
+		return paint(base, s)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range h.terms.FindAllStringIndex(s, -1) {
+		b.WriteString(paint(base, s[last:m[0]]))
+		b.WriteString(paint(h.code, s[m[0]:m[1]]))
+		last = m[1]
+	}
+	b.WriteString(paint(base, s[last:]))
+	return b.String()
+}
+
+// filterKeywords are the boolean operators and literals of the Scalyr
+// filter language, which are never search terms.
+var filterKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "true": true, "false": true, "null": true, "*": true,
+}
+
+// textOperators compare a field with text that may appear in the message.
+var textOperators = map[string]bool{"contains": true, "startswith": true, "endswith": true}
+
+// otherOperators compare a field with a value that is not message text.
+var otherOperators = map[string]bool{"matches": true, "in": true}
+
+// filterToken is a word, quoted string or run of operator characters.
+type filterToken struct {
+	text   string
+	quoted bool
+	symbol bool
+}
+
+// isComparison reports whether t compares a field with a value.
+func (t filterToken) isComparison() bool {
+	if t.quoted {
+		return false
+	}
+	if t.symbol {
+		return t.text != "(" && t.text != ")" && t.text != ","
+	}
+	word := strings.ToLower(t.text)
+	return textOperators[word] || otherOperators[word]
+}
+
+const filterSymbols = "=!<>(),"
+
+func tokenizeFilter(filter string) []filterToken {
+	var tokens []filterToken
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			tokens = append(tokens, filterToken{text: b.String(), quoted: true})
+			i = j + 1
+		case strings.ContainsRune(filterSymbols, r):
+			j := i
+			for j < len(runes) && strings.ContainsRune(filterSymbols, runes[j]) {
+				j++
+			}
+			tokens = append(tokens, filterToken{text: string(runes[i:j]), symbol: true})
+			i = j
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune(filterSymbols+"\"'", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, filterToken{text: string(runes[i:j])})
+			i = j
+		}
+	}
+	return tokens
+}
+
+// FilterTerms returns the text a filter searches messages for, to highlight
+// it: bare words and quoted strings that are not field comparisons, plus the
+// text given to contains, startsWith and endsWith. For example
+// `"connection reset" $serverHost="web-1"` yields "connection reset".
+func FilterTerms(filter string) []string {
+	tokens := tokenizeFilter(filter)
+	at := func(i int) filterToken {
+		if i < 0 || i >= len(tokens) {
+			return filterToken{}
+		}
+		return tokens[i]
+	}
+
+	var terms []string
+	seen := make(map[string]bool)
+	for i, t := range tokens {
+		if t.text == "" || t.isComparison() || t.symbol {
+			continue
+		}
+		prev, next := at(i-1), at(i+1)
+		switch {
+		case next.isComparison():
+			// A field being compared
+			continue
+		case prev.isComparison():
+			if !t.quoted || !textOperators[strings.ToLower(prev.text)] {
+				continue
+			}
+		case !t.quoted && (filterKeywords[strings.ToLower(t.text)] || strings.HasPrefix(t.text, "$")):
+			continue
+		}
+		if key := strings.ToLower(t.text); !seen[key] {
+			seen[key] = true
+			terms = append(terms, t.text)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,106 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{spec: "red", want: "31"},
+		{spec: "bold red", want: "1;31"},
+		{spec: "Bold, Yellow", want: "1;33"},
+		{spec: "38;5;208", want: "38;5;208"},
+		{spec: "none", want: ""},
+		{spec: "", want: ""},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, got, tt.spec)
+	}
+
+	_, err := ParseColor("teal")
+	assert.Error(t, err)
+}
+
+func TestThemeWithOverrides(t *testing.T) {
+	theme, err := DefaultTheme().WithOverrides(map[string]string{"error": "bold red", "info": "green", "highlight": "none"})
+	require.NoError(t, err)
+	assert.Equal(t, "1;31", theme.Error)
+	assert.Equal(t, "32", theme.Info)
+	assert.Empty(t, theme.Highlight)
+	assert.Equal(t, DefaultTheme().Warning, theme.Warning, "keys not overridden keep their defaults")
+
+	_, err = DefaultTheme().WithOverrides(map[string]string{"critical": "red"})
+	assert.Error(t, err)
+	_, err = DefaultTheme().WithOverrides(map[string]string{"error": "teal"})
+	assert.Error(t, err)
+}
+
+func TestThemeSeverity(t *testing.T) {
+	theme := DefaultTheme()
+	assert.Equal(t, theme.Debug, theme.Severity(1))
+	assert.Equal(t, theme.Info, theme.Severity(3))
+	assert.Equal(t, theme.Warning, theme.Severity(4))
+	assert.Equal(t, theme.Error, theme.Severity(5))
+	assert.Equal(t, theme.Fatal, theme.Severity(6))
+}
+
+func TestFilterTerms(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{filter: "", want: nil},
+		{filter: "timeout", want: []string{"timeout"}},
+		{filter: `"connection reset" $serverHost="web-1"`, want: []string{"connection reset"}},
+		{filter: "severity >= 3", want: nil},
+		{filter: "error and not debug", want: []string{"error", "debug"}},
+		{filter: "(timeout or 'refused') and status == 500", want: []string{"timeout", "refused"}},
+		{filter: `message contains "disk full"`, want: []string{"disk full"}},
+		{filter: `message matches "^GET .*"`, want: nil},
+		{filter: "Error error ERROR", want: []string{"Error"}},
+		{filter: `"say \"hi\""`, want: []string{`say "hi"`}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FilterTerms(tt.filter), tt.filter)
+	}
+}
+
+func TestHighlighter(t *testing.T) {
+	h := newHighlighter([]string{"time", "timeout"}, "7")
+	assert.Equal(t, "read \033[7mTimeout\033[0m after \033[7mtime\033[0m", h.paint("", "read Timeout after time"))
+	assert.Equal(t, "\033[31mread \033[0m\033[7mtimeout\033[0m", h.paint("31", "read timeout"))
+
+	assert.Equal(t, "\033[31mplain\033[0m", newHighlighter(nil, "7").paint("31", "plain"))
+	assert.Equal(t, "timeout", newHighlighter([]string{"timeout"}, "").paint("", "timeout"))
+}
+
+func TestTextColors(t *testing.T) {
+	opts := Options{Color: true, Theme: DefaultTheme(), Highlight: []string{"boom"}}
+
+	out := render(t, "compact", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents[:2])
+	})
+	assert.Equal(t,
+		"\033[2m22:13:20\033[0m I service ready\n"+
+			"\033[2m22:13:21\033[0m \033[31mE\033[0m \033[7mboom\033[0m\n",
+		out)
+
+	out = render(t, "messageonly", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, []client.LogEvent{{Severity: 4, Message: "disk almost full"}})
+	})
+	assert.Equal(t, "\033[33mdisk almost full\033[0m\n", out)
+
+	out = render(t, "multiline", Options{Theme: DefaultTheme()}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents[1:2])
+	})
+	assert.NotContains(t, out, "\033[", "the theme is only applied when colour is enabled")
+}
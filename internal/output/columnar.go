@@ -0,0 +1,437 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+func init() {
+	Register(Format{
+		Name:        "parquet",
+		Description: "An Apache Parquet file with typed columns, one row group per batch of records",
+		Binary:      true,
+		New: func(w io.Writer, opts Options) Formatter {
+			return &columnarFormatter{w: w, name: "Parquet", open: openParquet, batchRows: columnarBatchRows}
+		},
+	})
+	Register(Format{
+		Name:        "arrow",
+		Description: "An Apache Arrow IPC file with typed columns, one record batch per batch of records",
+		Binary:      true,
+		New: func(w io.Writer, opts Options) Formatter {
+			return &columnarFormatter{w: w, name: "Arrow", open: openArrow, batchRows: columnarBatchRows}
+		},
+	})
+}
+
+// columnarBatchRows is how many records the columnar formats hold before
+// writing them out, which bounds their memory however long the result is.
+const columnarBatchRows = 32768
+
+// overflowColumn holds, as a JSON object, the attributes of an event that
+// have no column of their own: those first seen after the schema was
+// inferred, and values that do not fit their column's type.
+const overflowColumn = "attributes"
+
+// columnarWriter writes record batches in a columnar file format.
+type columnarWriter interface {
+	Write(rec arrow.Record) error
+	Close() error
+}
+
+func openParquet(w io.Writer, schema *arrow.Schema) (columnarWriter, error) {
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(compress.Codecs.Zstd),
+		parquet.WithMaxRowGroupLength(columnarBatchRows),
+	)
+	return pqarrow.NewFileWriter(schema, w, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+}
+
+func openArrow(w io.Writer, schema *arrow.Schema) (columnarWriter, error) {
+	return ipc.NewFileWriter(w, ipc.WithSchema(schema))
+}
+
+// columnKind is the Arrow type of a column.
+type columnKind int
+
+const (
+	kindNone columnKind = iota
+	kindString
+	kindFloat
+	kindBool
+	kindInt
+	kindTimestamp
+)
+
+func (k columnKind) dataType() arrow.DataType {
+	switch k {
+	case kindFloat:
+		return arrow.PrimitiveTypes.Float64
+	case kindBool:
+		return arrow.FixedWidthTypes.Boolean
+	case kindInt:
+		return arrow.PrimitiveTypes.Int32
+	case kindTimestamp:
+		return &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// valueKind is the column kind a JSON value fits, or kindNone for null.
+func valueKind(v any) columnKind {
+	switch v.(type) {
+	case nil:
+		return kindNone
+	case bool:
+		return kindBool
+	case float64, float32, int, int32, int64:
+		return kindFloat
+	default:
+		return kindString
+	}
+}
+
+// mergeKind widens a column seen with kind a to also hold kind b: mixed
+// types become strings.
+func mergeKind(a, b columnKind) columnKind {
+	switch {
+	case a == kindNone:
+		return b
+	case b == kindNone || a == b:
+		return a
+	default:
+		return kindString
+	}
+}
+
+// columnarColumn is one column of a columnar file and how a record fills it.
+type columnarColumn struct {
+	name  string
+	kind  columnKind
+	value func(r Record) any
+	// attribute names the event attribute the column holds, so the overflow
+	// column can leave it out.
+	attribute string
+}
+
+// columnarFormatter writes Parquet or Arrow IPC files. It buffers a batch of
+// records, infers the schema from the first batch (fixed types for the event
+// fields, and the types observed for attributes and table values), then
+// writes every batch as a row group or record batch as soon as it is full.
+type columnarFormatter struct {
+	w         io.Writer
+	name      string
+	open      func(w io.Writer, schema *arrow.Schema) (columnarWriter, error)
+	batchRows int
+
+	header   Header
+	layout   columnLayout
+	pending  []Record
+	columns  []columnarColumn
+	overflow bool
+	schema   *arrow.Schema
+	writer   columnarWriter
+}
+
+func (f *columnarFormatter) Begin(h Header) error {
+	f.header = h
+	f.layout = newColumnLayout(h, TimeFormat{})
+	return nil
+}
+
+func (f *columnarFormatter) Write(r Record) error {
+	if r.Event != nil {
+		// The caller may reuse the event once Write returns.
+		event := *r.Event
+		r.Event = &event
+	}
+	f.pending = append(f.pending, r)
+	if len(f.pending) >= f.batchRows {
+		return f.flush()
+	}
+	return nil
+}
+
+func (f *columnarFormatter) End() error {
+	if err := f.flush(); err != nil {
+		return err
+	}
+	if f.writer == nil {
+		// An empty result still gets a file with its schema.
+		if err := f.start(); err != nil {
+			return err
+		}
+	}
+	if err := f.writer.Close(); err != nil {
+		return errors.NewParseError("failed to write "+f.name+" file", err)
+	}
+	return nil
+}
+
+// start infers the schema from the pending records and opens the writer.
+func (f *columnarFormatter) start() error {
+	f.columns, f.overflow = f.inferColumns()
+	fields := make([]arrow.Field, len(f.columns))
+	for i, col := range f.columns {
+		fields[i] = arrow.Field{Name: col.name, Type: col.kind.dataType(), Nullable: true}
+	}
+	if f.overflow {
+		fields = append(fields, arrow.Field{Name: overflowColumn, Type: arrow.BinaryTypes.String, Nullable: true})
+	}
+	f.schema = arrow.NewSchema(fields, nil)
+
+	// Hide any Close method: the Parquet writer closes its sink, which
+	// belongs to the caller.
+	writer, err := f.open(struct{ io.Writer }{f.w}, f.schema)
+	if err != nil {
+		return errors.NewParseError("failed to start "+f.name+" file", err)
+	}
+	f.writer = writer
+	return nil
+}
+
+// flush writes the pending records as one batch.
+func (f *columnarFormatter) flush() error {
+	if len(f.pending) == 0 {
+		return nil
+	}
+	if f.writer == nil {
+		if err := f.start(); err != nil {
+			return err
+		}
+	}
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, f.schema)
+	defer b.Release()
+	for _, r := range f.pending {
+		var extra map[string]any
+		for i, col := range f.columns {
+			v := col.value(r)
+			if !appendValue(b.Field(i), col.kind, v) && col.attribute != "" {
+				if extra == nil {
+					extra = make(map[string]any)
+				}
+				extra[col.attribute] = v
+			}
+		}
+		if f.overflow {
+			f.appendOverflow(b.Field(len(f.columns)).(*array.StringBuilder), r, extra)
+		}
+	}
+
+	rec := b.NewRecord()
+	defer rec.Release()
+	f.pending = f.pending[:0]
+	if err := f.writer.Write(rec); err != nil {
+		return errors.NewParseError("failed to write "+f.name+" file", err)
+	}
+	return nil
+}
+
+// appendOverflow writes the attributes of r that have no column, plus extra
+// (the ones that did not fit theirs), as a JSON object.
+func (f *columnarFormatter) appendOverflow(b *array.StringBuilder, r Record, extra map[string]any) {
+	if r.Event != nil {
+		for k, v := range r.Event.Attributes {
+			if !f.hasAttributeColumn(k) {
+				if extra == nil {
+					extra = make(map[string]any)
+				}
+				extra[k] = v
+			}
+		}
+	}
+	if len(extra) == 0 {
+		b.AppendNull()
+		return
+	}
+	out, err := json.Marshal(extra)
+	if err != nil {
+		b.AppendNull()
+		return
+	}
+	b.Append(string(out))
+}
+
+func (f *columnarFormatter) hasAttributeColumn(name string) bool {
+	for _, col := range f.columns {
+		if col.attribute == name {
+			return true
+		}
+	}
+	return false
+}
+
+// inferColumns returns the columns for the pending records, and whether the
+// overflow column is needed: events shown with every field get a column for
+// each attribute in the first batch, and the overflow column for the rest.
+func (f *columnarFormatter) inferColumns() ([]columnarColumn, bool) {
+	var columns []columnarColumn
+	if f.layout.sources {
+		columns = append(columns, columnarColumn{name: "source", kind: kindString, value: func(r Record) any { return r.Source }})
+	}
+
+	switch {
+	case f.header.Events && len(f.header.Columns) == 0:
+		for _, name := range []string{"timestamp", "severity", "message", "thread"} {
+			columns = append(columns, eventFieldColumn(name, kindNone))
+		}
+		kinds := make(map[string]columnKind)
+		for _, r := range f.pending {
+			for k, v := range r.Event.Attributes {
+				kinds[k] = mergeKind(kinds[k], valueKind(v))
+			}
+		}
+		keys := make([]string, 0, len(kinds))
+		for k := range kinds {
+			if !reservedColumnName(k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			columns = append(columns, eventFieldColumn(k, kinds[k]))
+		}
+		return columns, true
+
+	case f.header.Events:
+		for _, name := range f.layout.fields {
+			kind := kindNone
+			for _, r := range f.pending {
+				v, _ := EventField(*r.Event, name)
+				kind = mergeKind(kind, valueKind(v))
+			}
+			columns = append(columns, eventFieldColumn(name, kind))
+		}
+		return columns, false
+	}
+
+	n := len(f.header.Columns)
+	for _, r := range f.pending {
+		n = max(n, len(r.Values))
+	}
+	for i := 0; i < n; i++ {
+		kind := kindNone
+		for _, r := range f.pending {
+			if i < len(r.Values) {
+				kind = mergeKind(kind, valueKind(r.Values[i]))
+			}
+		}
+		columns = append(columns, columnarColumn{
+			name: columnName(f.header, i),
+			kind: kindOrString(kind),
+			value: func(r Record) any {
+				if i < len(r.Values) {
+					return r.Values[i]
+				}
+				return nil
+			},
+		})
+	}
+	return columns, false
+}
+
+// eventFieldColumn is the column of an event field: the fixed fields have
+// fixed types, attributes the kind observed.
+func eventFieldColumn(name string, kind columnKind) columnarColumn {
+	col := columnarColumn{name: name, value: func(r Record) any {
+		v, _ := EventField(*r.Event, name)
+		return v
+	}}
+	switch name {
+	case "timestamp":
+		col.kind = kindTimestamp
+	case "severity":
+		col.kind = kindInt
+	case "message", "thread":
+		col.kind = kindString
+	default:
+		col.kind = kindOrString(kind)
+		col.attribute = name
+	}
+	return col
+}
+
+func kindOrString(k columnKind) columnKind {
+	if k == kindNone {
+		return kindString
+	}
+	return k
+}
+
+// reservedColumnName reports whether an attribute shares its name with a
+// fixed column, so it is kept in the overflow column instead.
+func reservedColumnName(name string) bool {
+	switch name {
+	case "source", "timestamp", "severity", "message", "thread", overflowColumn:
+		return true
+	}
+	return false
+}
+
+// appendValue appends v to a column of kind, appending null and returning
+// false when v does not fit it. Nulls, and empty strings outside string
+// columns, are written as null.
+func appendValue(b array.Builder, kind columnKind, v any) bool {
+	if v == nil || (v == "" && kind != kindString) {
+		b.AppendNull()
+		return true
+	}
+
+	switch kind {
+	case kindTimestamp:
+		if s, ok := v.(string); ok {
+			if t, ok := ParseTimestamp(s); ok {
+				b.(*array.TimestampBuilder).Append(arrow.Timestamp(t.UnixNano()))
+				return true
+			}
+		}
+	case kindInt:
+		if n, ok := v.(int); ok {
+			b.(*array.Int32Builder).Append(int32(n))
+			return true
+		}
+	case kindFloat:
+		if n, ok := toFloat(v); ok {
+			b.(*array.Float64Builder).Append(n)
+			return true
+		}
+	case kindBool:
+		if t, ok := v.(bool); ok {
+			b.(*array.BooleanBuilder).Append(t)
+			return true
+		}
+	default:
+		b.(*array.StringBuilder).Append(FormatValue(v))
+		return true
+	}
+	b.AppendNull()
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
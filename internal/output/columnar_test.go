@@ -0,0 +1,162 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readParquet reads a Parquet file back into a table, and its row group count.
+func readParquet(t *testing.T, data []byte) (arrow.Table, int) {
+	t.Helper()
+
+	rdr, err := file.NewParquetReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	fr, err := pqarrow.NewFileReader(rdr, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	table, err := fr.ReadTable(context.Background())
+	require.NoError(t, err)
+	t.Cleanup(table.Release)
+	return table, rdr.NumRowGroups()
+}
+
+// columnValues returns the values of a table column as strings, "<nil>" for nulls.
+func columnValues(t *testing.T, table arrow.Table, name string) []string {
+	t.Helper()
+
+	idx := table.Schema().FieldIndices(name)
+	require.Len(t, idx, 1, "column %q", name)
+	var values []string
+	for _, chunk := range table.Column(idx[0]).Data().Chunks() {
+		for i := 0; i < chunk.Len(); i++ {
+			if chunk.IsNull(i) {
+				values = append(values, "<nil>")
+			} else {
+				values = append(values, chunk.ValueStr(i))
+			}
+		}
+	}
+	return values
+}
+
+func TestParquet_EventSchema(t *testing.T) {
+	events := []client.LogEvent{
+		{Timestamp: "1700000000000000000", Severity: 3, Message: "ok", Attributes: map[string]any{"status": float64(200), "host": "web-1", "cached": true}},
+		{Timestamp: "1700000001000000000", Severity: 5, Message: "boom", Thread: "7", Attributes: map[string]any{"status": "n/a", "nested": map[string]any{"a": float64(1)}}},
+	}
+	out := render(t, "parquet", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, events)
+	})
+	assert.Equal(t, "PAR1", out[:4])
+
+	table, _ := readParquet(t, []byte(out))
+	schema := table.Schema()
+	var names []string
+	for _, field := range schema.Fields() {
+		names = append(names, field.Name)
+	}
+	assert.Equal(t, []string{"timestamp", "severity", "message", "thread", "cached", "host", "nested", "status", "attributes"}, names)
+
+	typeOf := func(name string) arrow.DataType {
+		field, ok := schema.FieldsByName(name)
+		require.True(t, ok, name)
+		return field[0].Type
+	}
+	assert.Equal(t, arrow.TIMESTAMP, typeOf("timestamp").ID())
+	assert.Equal(t, arrow.INT32, typeOf("severity").ID())
+	assert.Equal(t, arrow.BOOL, typeOf("cached").ID())
+	assert.Equal(t, arrow.STRING, typeOf("status").ID(), "mixed types become strings")
+	assert.Equal(t, arrow.STRING, typeOf("nested").ID())
+
+	assert.Equal(t, []string{"ok", "boom"}, columnValues(t, table, "message"))
+	assert.Equal(t, []string{"3", "5"}, columnValues(t, table, "severity"))
+	assert.Equal(t, []string{"200", "n/a"}, columnValues(t, table, "status"))
+	assert.Equal(t, []string{"<nil>", `{"a":1}`}, columnValues(t, table, "nested"))
+	assert.Equal(t, []string{"<nil>", "<nil>"}, columnValues(t, table, "attributes"))
+}
+
+func TestParquet_RowGroupsAndLateAttributes(t *testing.T) {
+	events := []client.LogEvent{
+		{Timestamp: "1700000000000000000", Message: "a", Attributes: map[string]any{"status": float64(200)}},
+		{Timestamp: "1700000001000000000", Message: "b", Attributes: map[string]any{"status": float64(404)}},
+		{Timestamp: "1700000002000000000", Message: "c", Attributes: map[string]any{"status": "oops", "late": "x"}},
+	}
+
+	var buf bytes.Buffer
+	f := &columnarFormatter{w: &buf, name: "Parquet", open: openParquet, batchRows: 2}
+	require.NoError(t, WriteEvents(f, Header{}, events))
+
+	table, rowGroups := readParquet(t, buf.Bytes())
+	assert.Equal(t, 2, rowGroups, "a row group is written every batchRows records")
+	assert.EqualValues(t, 3, table.NumRows())
+
+	field, _ := table.Schema().FieldsByName("status")
+	assert.Equal(t, arrow.FLOAT64, field[0].Type.ID(), "the schema comes from the first batch")
+	assert.Equal(t, []string{"200", "404", "<nil>"}, columnValues(t, table, "status"))
+	assert.Equal(t, []string{"<nil>", "<nil>", `{"late":"x","status":"oops"}`}, columnValues(t, table, "attributes"),
+		"attributes first seen later, and values that do not fit their column, are kept as JSON")
+}
+
+func TestParquet_Rows(t *testing.T) {
+	out := render(t, "parquet", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"uriPath", "requests"}}, [][]any{
+			{"/login", float64(100)},
+			{"/home", nil},
+		})
+	})
+
+	table, _ := readParquet(t, []byte(out))
+	field, _ := table.Schema().FieldsByName("requests")
+	assert.Equal(t, arrow.FLOAT64, field[0].Type.ID())
+	assert.Equal(t, []string{"/login", "/home"}, columnValues(t, table, "uriPath"))
+	assert.Equal(t, []string{"100", "<nil>"}, columnValues(t, table, "requests"))
+}
+
+func TestParquet_EmptyResult(t *testing.T) {
+	out := render(t, "parquet", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Columns: []string{"message", "host"}}, nil)
+	})
+
+	table, _ := readParquet(t, []byte(out))
+	assert.EqualValues(t, 0, table.NumRows())
+	assert.Equal(t, 2, int(table.NumCols()))
+}
+
+func TestArrow_File(t *testing.T) {
+	out := render(t, "arrow", Options{}, func(f Formatter) error {
+		if err := f.Begin(Header{Events: true, Columns: []string{"timestamp", "host"}, Sources: []string{"api"}}); err != nil {
+			return err
+		}
+		if err := f.Write(Record{Source: "api", Event: &testEvents[1]}); err != nil {
+			return err
+		}
+		return f.End()
+	})
+
+	rdr, err := ipc.NewFileReader(bytes.NewReader([]byte(out)))
+	require.NoError(t, err)
+	defer rdr.Close()
+
+	schema := rdr.Schema()
+	require.Equal(t, 3, schema.NumFields())
+	assert.Equal(t, "source", schema.Field(0).Name)
+	assert.Equal(t, arrow.TIMESTAMP, schema.Field(1).Type.ID())
+	assert.Equal(t, arrow.STRING, schema.Field(2).Type.ID())
+
+	require.Equal(t, 1, rdr.NumRecords())
+	rec, err := rdr.Record(0)
+	require.NoError(t, err)
+	assert.Equal(t, "api", rec.Column(0).(*array.String).Value(0))
+	assert.Equal(t, arrow.Timestamp(1700000001000000000), rec.Column(1).(*array.Timestamp).Value(0))
+	assert.Equal(t, "web-1", rec.Column(2).(*array.String).Value(0))
+}
@@ -0,0 +1,72 @@
+package output
+
+// columnLayout maps records onto the cells of the column-based formats:
+// the event fields of eventColumns, or the values of a table row, after the
+// source when records carry one. Event timestamps are rendered with time.
+type columnLayout struct {
+	header  Header
+	fields  []string
+	sources bool
+	time    TimeFormat
+}
+
+func newColumnLayout(h Header, time TimeFormat) columnLayout {
+	l := columnLayout{header: h, sources: len(h.Sources) > 0, time: time}
+	if h.Events {
+		l.fields = eventColumns(h)
+	}
+	return l
+}
+
+// names returns the header row, or nil for a table of positional values.
+func (l columnLayout) names() []string {
+	names := l.fields
+	if !l.header.Events {
+		names = l.header.Columns
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	if l.sources {
+		names = append([]string{"source"}, names...)
+	}
+	return names
+}
+
+// name is the header of the i-th cell, numbering positional values.
+func (l columnLayout) name(i int) string {
+	names := l.names()
+	if i < len(names) {
+		return names[i]
+	}
+	return columnName(Header{}, i)
+}
+
+// values returns the cells of a record, unformatted.
+func (l columnLayout) values(r Record) []any {
+	var values []any
+	if l.sources {
+		values = append(values, r.Source)
+	}
+	if r.Event == nil {
+		return append(values, r.Values...)
+	}
+	for _, field := range l.fields {
+		val, _ := EventField(*r.Event, field)
+		if field == "timestamp" {
+			val = l.time.Format(r.Event.Timestamp)
+		}
+		values = append(values, val)
+	}
+	return values
+}
+
+// strings returns the cells of a record formatted with FormatValue.
+func (l columnLayout) strings(r Record) []string {
+	values := l.values(r)
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = FormatValue(v)
+	}
+	return cells
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(Format{
+		Name:        "csv",
+		Description: "Comma-separated values with a header row; events show --columns (default timestamp,severity,message)",
+		New: func(w io.Writer, opts Options) Formatter {
+			cw := csv.NewWriter(w)
+			return &delimitedFormatter{write: cw.Write, flush: func() error {
+				cw.Flush()
+				return cw.Error()
+			}, time: opts.Time}
+		},
+	})
+	Register(Format{
+		Name:        "tsv",
+		Description: "Tab-separated values with a header row, escaping tabs, newlines and backslashes as \\t, \\n and \\\\",
+		New: func(w io.Writer, opts Options) Formatter {
+			return &delimitedFormatter{
+				write: func(record []string) error {
+					for i, cell := range record {
+						record[i] = tsvEscaper.Replace(cell)
+					}
+					_, err := io.WriteString(w, strings.Join(record, "\t")+"\n")
+					return err
+				},
+				flush: func() error { return nil },
+				time:  opts.Time,
+			}
+		},
+	})
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// delimitedFormatter writes one row per record, flushing each so streamed
+// results appear as they arrive. Tables with positional values have no
+// header row.
+type delimitedFormatter struct {
+	write  func(record []string) error
+	flush  func() error
+	time   TimeFormat
+	layout columnLayout
+}
+
+func (f *delimitedFormatter) Begin(h Header) error {
+	f.layout = newColumnLayout(h, f.time)
+	if names := f.layout.names(); names != nil {
+		return f.writeRow(names)
+	}
+	return nil
+}
+
+func (f *delimitedFormatter) Write(r Record) error {
+	return f.writeRow(f.layout.strings(r))
+}
+
+func (f *delimitedFormatter) writeRow(record []string) error {
+	if err := f.write(record); err != nil {
+		return err
+	}
+	return f.flush()
+}
+
+func (f *delimitedFormatter) End() error {
+	return f.flush()
+}
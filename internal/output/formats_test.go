@@ -0,0 +1,139 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTSV_Escaping(t *testing.T) {
+	out := render(t, "tsv", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"path", "count"}}, [][]any{
+			{"a\tb\nc\\d", float64(2)},
+		})
+	})
+	assert.Equal(t, "path\tcount\na\\tb\\nc\\\\d\t2\n", out)
+}
+
+func TestLogfmt_Events(t *testing.T) {
+	out := render(t, "logfmt", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents[:2])
+	})
+	assert.Equal(t,
+		"timestamp=1700000000000000000 severity=3 message=\"service ready\"\n"+
+			"timestamp=1700000001000000000 severity=5 message=boom host=web-1\n",
+		out)
+
+	out = render(t, "logfmt", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Columns: []string{"message", "host"}}, testEvents[:1])
+	})
+	assert.Equal(t, "message=\"service ready\" host=\"\"\n", out)
+}
+
+func TestLogfmt_Rows(t *testing.T) {
+	out := render(t, "logfmt", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"server host", "value"}}, [][]any{
+			{"web=1", `say "hi"`},
+		})
+	})
+	assert.Equal(t, "server_host=\"web=1\" value=\"say \\\"hi\\\"\"\n", out)
+
+	out = render(t, "logfmt", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, [][]any{{1.5, float64(2)}})
+	})
+	assert.Equal(t, "0=1.5 1=2\n", out)
+}
+
+func TestYAML_StreamedEvents(t *testing.T) {
+	out := render(t, "yaml", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Events: true}, testEvents[:2])
+	})
+	assert.Equal(t,
+		"status: success\nmatches:\n"+
+			"  - timestamp: \"1700000000000000000\"\n    severity: 3\n    message: service ready\n"+
+			"  - timestamp: \"1700000001000000000\"\n    severity: 5\n    message: boom\n    attributes:\n      host: web-1\n",
+		out)
+
+	out = render(t, "yaml", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, nil)
+	})
+	assert.Equal(t, "status: success\nmatches: []\n", out)
+}
+
+func TestYAML_Fields(t *testing.T) {
+	out := render(t, "yaml", Options{Fields: []string{"message", "host"}}, func(f Formatter) error {
+		return WriteEvents(f, Header{Document: "ignored"}, testEvents[:2])
+	})
+	assert.Equal(t, "- message: service ready\n- host: web-1\n  message: boom\n", out)
+
+	out = render(t, "yaml", Options{Fields: []string{"message"}}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, nil)
+	})
+	assert.Equal(t, "[]\n", out)
+}
+
+func TestYAML_DocumentAndFollow(t *testing.T) {
+	out := render(t, "yaml", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Document: map[string]any{"status": "success", "values": []float64{1, 2}}}, [][]any{{1.0}, {2.0}})
+	})
+	assert.Equal(t, "status: success\nvalues:\n  - 1\n  - 2\n", out)
+
+	out = render(t, "yaml", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Follow: true}, testEvents[:2])
+	})
+	assert.Equal(t,
+		"---\ntimestamp: \"1700000000000000000\"\nseverity: 3\nmessage: service ready\n"+
+			"---\ntimestamp: \"1700000001000000000\"\nseverity: 5\nmessage: boom\nattributes:\n  host: web-1\n",
+		out)
+}
+
+func TestMarkdown_Table(t *testing.T) {
+	out := render(t, "markdown", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"host", "count"}}, [][]any{
+			{"web|1", float64(12)},
+			{"line\nbreak", float64(3)},
+		})
+	})
+	assert.Equal(t,
+		"| host | count |\n| --- | ---: |\n| web\\|1 | 12 |\n| line<br>break | 3 |\n",
+		out)
+
+	out = render(t, "markdown", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, [][]any{{1.5, "x"}})
+	})
+	assert.Equal(t, "| 0 | 1 |\n| ---: | --- |\n| 1.5 | x |\n", out, "positional columns are numbered")
+
+	out = render(t, "markdown", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, nil)
+	})
+	assert.Empty(t, out)
+}
+
+func TestMarkdown_Follow(t *testing.T) {
+	out := render(t, "markdown", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Follow: true, Columns: []string{"severity", "message"}}, testEvents[:2])
+	})
+	assert.Equal(t, "| severity | message |\n| --- | --- |\n| 3 | service ready |\n| 5 | boom |\n", out)
+}
+
+func TestHTML_Report(t *testing.T) {
+	out := render(t, "html", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Columns: []string{"message"}}, []client.LogEvent{
+			{Severity: 5, Message: "<script>alert(1)</script>"},
+		})
+	})
+	assert.Contains(t, out, "<!DOCTYPE html>")
+	assert.Contains(t, out, "<style>")
+	assert.Contains(t, out, "<thead><tr><th>message</th></tr></thead>")
+	assert.Contains(t, out, "<tr class=\"sev-error\"><td>&lt;script&gt;alert(1)&lt;/script&gt;</td></tr>")
+	assert.NotContains(t, out, "<script>")
+	assert.Contains(t, out, "<p class=\"count\">1 record</p>")
+	assert.Regexp(t, `</html>\n$`, out)
+
+	out = render(t, "html", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, [][]any{{float64(4), "web-1"}})
+	})
+	assert.NotContains(t, out, "<thead>", "positional rows have no header")
+	assert.Contains(t, out, "<tr><td class=\"num\">4</td><td>web-1</td></tr>")
+}
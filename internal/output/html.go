@@ -0,0 +1,88 @@
+package output
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(Format{
+		Name:        "html",
+		Description: "A self-contained HTML page with a table of the results, events shaded by severity",
+		New: func(w io.Writer, opts Options) Formatter {
+			return &htmlFormatter{w: w, time: opts.Time}
+		},
+	})
+}
+
+// htmlStyle keeps the report readable without any external resources.
+const htmlStyle = `body{font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;margin:2em;color:#1f2328}
+table{border-collapse:collapse;font-size:14px}
+th,td{border:1px solid #d0d7de;padding:4px 8px;text-align:left;vertical-align:top;white-space:pre-wrap}
+th{background:#f6f8fa}
+td.num{text-align:right;font-variant-numeric:tabular-nums}
+tr.sev-debug{color:#6e7781}
+tr.sev-warning{background:#fff8c5}
+tr.sev-error{background:#ffebe9}
+tr.sev-fatal{background:#ffebe9;font-weight:bold}
+p.count{color:#6e7781}`
+
+// htmlFormatter writes each record as a table row as it arrives, so a
+// followed result is readable until it is interrupted.
+type htmlFormatter struct {
+	w       io.Writer
+	time    TimeFormat
+	layout  columnLayout
+	written int
+}
+
+func (f *htmlFormatter) Begin(h Header) error {
+	f.layout = newColumnLayout(h, f.time)
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<title>logbasset results</title>\n<style>\n" + htmlStyle + "\n</style>\n</head>\n<body>\n<table>\n")
+	if names := f.layout.names(); names != nil {
+		b.WriteString("<thead><tr>")
+		for _, name := range names {
+			b.WriteString("<th>" + html.EscapeString(name) + "</th>")
+		}
+		b.WriteString("</tr></thead>\n")
+	}
+	b.WriteString("<tbody>\n")
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+func (f *htmlFormatter) Write(r Record) error {
+	var b strings.Builder
+	if r.Event != nil {
+		fmt.Fprintf(&b, "<tr class=\"sev-%s\">", SeverityName(r.Event.Severity))
+	} else {
+		b.WriteString("<tr>")
+	}
+	for _, v := range f.layout.values(r) {
+		if isNumber(v) {
+			b.WriteString("<td class=\"num\">")
+		} else {
+			b.WriteString("<td>")
+		}
+		b.WriteString(html.EscapeString(FormatValue(v)) + "</td>")
+	}
+	b.WriteString("</tr>\n")
+	f.written++
+
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+func (f *htmlFormatter) End() error {
+	noun := "records"
+	if f.written == 1 {
+		noun = "record"
+	}
+	_, err := fmt.Fprintf(f.w, "</tbody>\n</table>\n<p class=\"count\">%d %s</p>\n</body>\n</html>\n", f.written, noun)
+	return err
+}
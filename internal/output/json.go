@@ -0,0 +1,243 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+func init() {
+	Register(Format{
+		Name:        "json",
+		Description: "The API response as JSON; events are streamed inside {\"status\",\"matches\"}",
+		Fields:      true,
+		Document:    true,
+		New: func(w io.Writer, opts Options) Formatter {
+			return &jsonFormatter{w: w, fields: opts.Fields}
+		},
+	})
+	Register(Format{
+		Name:        "json-pretty",
+		Description: "json, indented",
+		Fields:      true,
+		Document:    true,
+		New: func(w io.Writer, opts Options) Formatter {
+			return &jsonFormatter{w: w, fields: opts.Fields, pretty: true}
+		},
+	})
+	Register(Format{
+		Name:        "ndjson",
+		Description: "One JSON object per event or row",
+		Fields:      true,
+		New: func(w io.Writer, opts Options) Formatter {
+			return &ndjsonFormatter{w: w, fields: opts.Fields}
+		},
+	})
+}
+
+// sourcedEvent is an event in JSON output, with the --filter it matched.
+type sourcedEvent struct {
+	Source string `json:"source,omitempty"`
+	client.LogEvent
+}
+
+// eventObject is what JSON formats write for an event: the event itself, or
+// the requested fields when fields is set.
+func eventObject(r Record, fields []string) any {
+	if fields == nil {
+		return sourcedEvent{Source: r.Source, LogEvent: *r.Event}
+	}
+	m := make(map[string]any, len(fields)+1)
+	for _, f := range fields {
+		if val, ok := EventField(*r.Event, f); ok {
+			m[f] = val
+		}
+	}
+	if r.Source != "" {
+		m["source"] = r.Source
+	}
+	return m
+}
+
+// rowObject is what JSON formats write for a table row: an object keyed by
+// column, or the bare values when the columns are positional.
+func rowObject(h Header, values []any) any {
+	if len(h.Columns) == 0 {
+		return values
+	}
+	m := make(map[string]any, len(values))
+	for i, v := range values {
+		m[columnName(h, i)] = v
+	}
+	return m
+}
+
+func marshalJSON(data any, prefix string, pretty bool) ([]byte, error) {
+	var out []byte
+	var err error
+	if pretty {
+		out, err = json.MarshalIndent(data, prefix, "  ")
+	} else {
+		out, err = json.Marshal(data)
+	}
+	if err != nil {
+		return nil, errors.NewParseError("failed to marshal JSON", err)
+	}
+	return out, nil
+}
+
+// ndjsonFormatter writes one compact JSON object per line.
+type ndjsonFormatter struct {
+	w      io.Writer
+	fields []string
+	header Header
+}
+
+func (f *ndjsonFormatter) Begin(h Header) error {
+	f.header = h
+	return nil
+}
+
+func (f *ndjsonFormatter) Write(r Record) error {
+	var data any
+	if r.Event != nil {
+		data = eventObject(r, f.fields)
+	} else {
+		data = rowObject(f.header, r.Values)
+	}
+	out, err := marshalJSON(data, "", false)
+	if err != nil {
+		return err
+	}
+	_, err = f.w.Write(append(out, '\n'))
+	return err
+}
+
+func (f *ndjsonFormatter) End() error { return nil }
+
+// jsonFormatter writes a single JSON document. A known Document is printed
+// as-is; streamed events are written into a {"status","matches"} wrapper (or
+// a bare array with --fields) as they arrive, so a long result has the same
+// shape a single large page would.
+type jsonFormatter struct {
+	w       io.Writer
+	fields  []string
+	pretty  bool
+	header  Header
+	written int
+	rows    []any
+	follow  *ndjsonFormatter
+}
+
+func (f *jsonFormatter) Begin(h Header) error {
+	f.header = h
+	if h.Follow {
+		f.follow = &ndjsonFormatter{w: f.w, fields: f.fields, header: h}
+	}
+	return nil
+}
+
+// useDocument reports whether the header's Document is printed instead of
+// the records.
+func (f *jsonFormatter) useDocument() bool {
+	return f.header.Document != nil && !(f.header.Events && f.fields != nil)
+}
+
+func (f *jsonFormatter) Write(r Record) error {
+	switch {
+	case f.follow != nil:
+		return f.follow.Write(r)
+	case f.useDocument():
+		return nil
+	case r.Event == nil:
+		f.rows = append(f.rows, rowObject(f.header, r.Values))
+		return nil
+	}
+
+	opening := ","
+	if f.written == 0 {
+		opening = f.opening()
+	}
+	if _, err := io.WriteString(f.w, opening); err != nil {
+		return err
+	}
+	f.written++
+
+	indent := f.indent()
+	if f.pretty {
+		if _, err := io.WriteString(f.w, "\n"+indent); err != nil {
+			return err
+		}
+	}
+	out, err := marshalJSON(eventObject(r, f.fields), indent, f.pretty)
+	if err != nil {
+		return err
+	}
+	_, err = f.w.Write(out)
+	return err
+}
+
+func (f *jsonFormatter) End() error {
+	switch {
+	case f.follow != nil:
+		return nil
+	case f.useDocument():
+		return f.writeDocument(f.header.Document)
+	case !f.header.Events:
+		rows := f.rows
+		if rows == nil {
+			rows = []any{}
+		}
+		return f.writeDocument(rows)
+	}
+
+	var closing string
+	if f.written == 0 {
+		closing = f.opening()
+	}
+	switch {
+	case f.pretty && f.written > 0 && f.fields != nil:
+		closing += "\n]\n"
+	case f.pretty && f.written > 0:
+		closing += "\n  ]\n}\n"
+	case f.pretty && f.fields == nil:
+		closing += "]\n}\n"
+	case f.fields != nil:
+		closing += "]\n"
+	default:
+		closing += "]}\n"
+	}
+	_, err := io.WriteString(f.w, closing)
+	return err
+}
+
+func (f *jsonFormatter) writeDocument(data any) error {
+	out, err := marshalJSON(data, "", f.pretty)
+	if err != nil {
+		return err
+	}
+	_, err = f.w.Write(append(out, '\n'))
+	return err
+}
+
+// opening mirrors how encoding/json lays out the start of the wrapper object
+// (or bare array when --fields is set) around the events.
+func (f *jsonFormatter) opening() string {
+	switch {
+	case f.fields != nil:
+		return "["
+	case f.pretty:
+		return "{\n  \"status\": \"success\",\n  \"matches\": ["
+	default:
+		return `{"status":"success","matches":[`
+	}
+}
+
+func (f *jsonFormatter) indent() string {
+	if f.fields != nil {
+		return "  "
+	}
+	return "    "
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(Format{
+		Name:        "logfmt",
+		Description: "One line of key=value pairs per event or row; events show --columns, or every field and attribute",
+		New: func(w io.Writer, opts Options) Formatter {
+			return &logfmtFormatter{w: w, time: opts.Time, attrs: newAttributeLayout(opts)}
+		},
+	})
+}
+
+// logfmtFormatter writes records as logfmt lines, e.g.
+// `timestamp=1700000000000000000 severity=3 message="user logged in"`.
+type logfmtFormatter struct {
+	w      io.Writer
+	time   TimeFormat
+	attrs  attributeLayout
+	header Header
+	layout columnLayout
+}
+
+func (f *logfmtFormatter) Begin(h Header) error {
+	f.header = h
+	f.layout = newColumnLayout(h, f.time)
+	return nil
+}
+
+func (f *logfmtFormatter) Write(r Record) error {
+	var pairs []string
+	add := func(key string, value any) {
+		pairs = append(pairs, logfmtKey(key)+"="+logfmtValue(FormatValue(value)))
+	}
+
+	switch {
+	case r.Event != nil && len(f.header.Columns) == 0:
+		// Without --columns an event shows every field, then its attributes
+		if r.Source != "" {
+			add("source", r.Source)
+		}
+		event := *r.Event
+		add("timestamp", f.time.Format(event.Timestamp))
+		add("severity", event.Severity)
+		add("message", event.Message)
+		if event.Thread != "" {
+			add("thread", event.Thread)
+		}
+		for _, a := range f.attrs.attributes(event.Attributes) {
+			add(a.key, a.value)
+		}
+	default:
+		for i, v := range f.layout.values(r) {
+			add(f.layout.name(i), v)
+		}
+	}
+
+	_, err := io.WriteString(f.w, strings.Join(pairs, " ")+"\n")
+	return err
+}
+
+func (f *logfmtFormatter) End() error { return nil }
+
+// logfmtKey replaces the characters a logfmt key cannot hold.
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+var logfmtEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// logfmtValue quotes values that are empty or hold spaces, quotes, equals
+// signs or control characters.
+func logfmtValue(value string) string {
+	if value != "" && !strings.ContainsFunc(value, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\'
+	}) {
+		return value
+	}
+	return `"` + logfmtEscaper.Replace(value) + `"`
+}
@@ -0,0 +1,117 @@
+package output
+
+import (
+	"io"
+	"strings"
+)
+
+func init() {
+	Register(Format{
+		Name:        "markdown",
+		Description: "A GitHub-flavoured Markdown table, numeric columns right-aligned",
+		New: func(w io.Writer, opts Options) Formatter {
+			return &markdownFormatter{w: w, time: opts.Time}
+		},
+	})
+}
+
+// markdownFormatter buffers a result to align its numeric columns. A
+// followed result is written as it arrives, with every column left-aligned.
+type markdownFormatter struct {
+	w       io.Writer
+	time    TimeFormat
+	header  Header
+	layout  columnLayout
+	rows    [][]any
+	started bool
+}
+
+func (f *markdownFormatter) Begin(h Header) error {
+	f.header = h
+	f.layout = newColumnLayout(h, f.time)
+	return nil
+}
+
+func (f *markdownFormatter) Write(r Record) error {
+	values := f.layout.values(r)
+	if !f.header.Follow {
+		f.rows = append(f.rows, values)
+		return nil
+	}
+	if !f.started {
+		if err := f.writeHeader(len(values), nil); err != nil {
+			return err
+		}
+	}
+	return f.writeRow(values)
+}
+
+func (f *markdownFormatter) End() error {
+	if f.header.Follow {
+		return nil
+	}
+
+	n := len(f.layout.names())
+	for _, row := range f.rows {
+		n = max(n, len(row))
+	}
+	if n == 0 {
+		return nil
+	}
+	numeric := make([]bool, n)
+	for i := range numeric {
+		numeric[i] = len(f.rows) > 0
+		for _, row := range f.rows {
+			if i < len(row) && row[i] != nil && !isNumber(row[i]) {
+				numeric[i] = false
+			}
+		}
+	}
+
+	if err := f.writeHeader(n, numeric); err != nil {
+		return err
+	}
+	for _, row := range f.rows {
+		if err := f.writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes the header and delimiter rows. Markdown tables always
+// have a header, so positional columns are numbered.
+func (f *markdownFormatter) writeHeader(n int, numeric []bool) error {
+	f.started = true
+	names := make([]string, n)
+	delimiters := make([]string, n)
+	for i := range names {
+		names[i] = markdownCell(f.layout.name(i))
+		delimiters[i] = "---"
+		if i < len(numeric) && numeric[i] {
+			delimiters[i] = "---:"
+		}
+	}
+	_, err := io.WriteString(f.w, markdownRow(names)+markdownRow(delimiters))
+	return err
+}
+
+func (f *markdownFormatter) writeRow(values []any) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = markdownCell(FormatValue(v))
+	}
+	_, err := io.WriteString(f.w, markdownRow(cells))
+	return err
+}
+
+func markdownRow(cells []string) string {
+	return "| " + strings.Join(cells, " | ") + " |\n"
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+// markdownCell escapes the characters that would break out of a table cell.
+func markdownCell(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,223 @@
+// Package output renders command results in the formats selected with
+// --output. Formats register themselves by name, and every format can render
+// every kind of result: log events from query and tail, and the tables
+// returned by power-query, facet-query, numeric-query and timeseries-query.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/validation"
+)
+
+// DefaultEventColumns are the event fields column-based formats show when no
+// columns are requested.
+var DefaultEventColumns = []string{"timestamp", "severity", "message"}
+
+// Header describes the result about to be written.
+type Header struct {
+	// Events reports whether the records are log events rather than table
+	// rows.
+	Events bool
+	// Columns names the columns of a table, or the event fields that
+	// column-based formats such as csv show. A table without Columns has
+	// positional values, such as the buckets of a numeric query.
+	Columns []string
+	// Document is the complete API response when it is known up front. The
+	// JSON formats print it unchanged so their output keeps the API's shape.
+	Document any
+	// Follow marks a result that never ends, such as tail. JSON formats then
+	// write one object per line instead of a single document.
+	Follow bool
+	// Sources lists the labels records may carry, so formats can align them.
+	Sources []string
+}
+
+// Record is one log event or one table row.
+type Record struct {
+	// Source labels the tail --filter an event matched.
+	Source string
+	Event  *client.LogEvent
+	Values []any
+}
+
+// Formatter writes one result: Begin once, then every record, then End.
+// Records are written out as they arrive, so results can be streamed.
+type Formatter interface {
+	Begin(h Header) error
+	Write(r Record) error
+	End() error
+}
+
+// Options tune every format.
+type Options struct {
+	// Fields projects events onto these fields, in formats with Fields set.
+	Fields []string
+	// Color enables ANSI colours in formats that use them, with Theme.
+	Color bool
+	Theme Theme
+	// Highlight lists the filter terms to mark in messages when colouring.
+	Highlight []string
+	// Width is the terminal width table formats fit into, or 0 when
+	// unlimited.
+	Width int
+	// Flatten writes nested attribute objects as dotted keys in the
+	// line-oriented formats, and AttrOrder lists the attributes they write
+	// first; the rest follow sorted by name.
+	Flatten   bool
+	AttrOrder []string
+	// Time renders event timestamps in the text and column-based formats;
+	// JSON, YAML, Parquet and Arrow keep the values Scalyr returns.
+	Time TimeFormat
+}
+
+// Format is a registered output format.
+type Format struct {
+	Name        string
+	Description string
+	// Fields reports whether the format honours Options.Fields.
+	Fields bool
+	// Binary reports whether the format writes a binary file, which should
+	// not be sent to a terminal.
+	Binary bool
+	// Document reports whether the format prints Header.Document when it is
+	// known, so callers should buffer the complete response for it.
+	Document bool
+	New      func(w io.Writer, opts Options) Formatter
+}
+
+var formats = make(map[string]Format)
+
+// Register adds a format to the registry. It panics on a duplicate name, as
+// that is a programming error.
+func Register(f Format) {
+	if _, ok := formats[f.Name]; ok {
+		panic("output: format registered twice: " + f.Name)
+	}
+	formats[f.Name] = f
+	validation.RegisterOutput(f.Name)
+}
+
+// Lookup returns the format registered under name.
+func Lookup(name string) (Format, bool) {
+	f, ok := formats[name]
+	return f, ok
+}
+
+// Names returns the names of every registered format, sorted.
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Usage returns the flag help listing every format, e.g. "csv|json|...".
+func Usage() string {
+	return strings.Join(Names(), "|")
+}
+
+// New returns a formatter for the named format writing to w.
+func New(name string, w io.Writer, opts Options) (Formatter, error) {
+	f, ok := Lookup(name)
+	if !ok {
+		return nil, errors.NewValidationError(
+			fmt.Sprintf("invalid output format: %s", name),
+			fmt.Errorf("valid formats: %s", strings.Join(Names(), ", ")),
+		)
+	}
+	return f.New(w, opts), nil
+}
+
+// WriteEvents writes a complete list of events.
+func WriteEvents(f Formatter, h Header, events []client.LogEvent) error {
+	h.Events = true
+	if err := f.Begin(h); err != nil {
+		return err
+	}
+	for i := range events {
+		if err := f.Write(Record{Event: &events[i]}); err != nil {
+			return err
+		}
+	}
+	return f.End()
+}
+
+// WriteRows writes a complete table.
+func WriteRows(f Formatter, h Header, rows [][]any) error {
+	if err := f.Begin(h); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := f.Write(Record{Values: row}); err != nil {
+			return err
+		}
+	}
+	return f.End()
+}
+
+// FormatValue renders a value for the text-based formats. Floats are never
+// written in exponent notation, and objects and arrays are written as JSON.
+func FormatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case map[string]any, []any:
+		if out, err := json.Marshal(v); err == nil {
+			return string(out)
+		}
+		return fmt.Sprintf("%v", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// columnName names the i-th value of a row, numbering positional values.
+func columnName(h Header, i int) string {
+	if i < len(h.Columns) {
+		return h.Columns[i]
+	}
+	return strconv.Itoa(i)
+}
+
+// eventColumns returns the columns an event is shown with in column-based
+// formats.
+func eventColumns(h Header) []string {
+	if len(h.Columns) > 0 {
+		return h.Columns
+	}
+	return DefaultEventColumns
+}
+
+// EventField returns the value of a fixed event field or attribute, and
+// whether the event has it.
+func EventField(event client.LogEvent, name string) (any, bool) {
+	switch name {
+	case "timestamp":
+		return event.Timestamp, true
+	case "severity":
+		return event.Severity, true
+	case "message":
+		return event.Message, true
+	case "thread":
+		return event.Thread, true
+	default:
+		val, ok := event.Attributes[name]
+		return val, ok
+	}
+}
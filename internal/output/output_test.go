@@ -0,0 +1,302 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEvents = []client.LogEvent{
+	{Timestamp: "1700000000000000000", Severity: 3, Message: "service ready"},
+	{Timestamp: "1700000001000000000", Severity: 5, Message: "boom", Attributes: map[string]any{"host": "web-1"}},
+	{Timestamp: "not-a-time", Severity: 0, Message: "fallback"},
+}
+
+func render(t *testing.T, name string, opts Options, fn func(Formatter) error) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	f, err := New(name, &buf, opts)
+	require.NoError(t, err)
+	require.NoError(t, fn(f))
+	return buf.String()
+}
+
+func TestRegistry(t *testing.T) {
+	names := Names()
+	for _, name := range []string{"compact", "csv", "json", "json-pretty", "messageonly", "multiline", "ndjson", "singleline"} {
+		assert.Contains(t, names, name)
+	}
+	assert.IsIncreasing(t, names)
+	assert.Contains(t, Usage(), "json|json-pretty")
+
+	_, err := New("xml", &bytes.Buffer{}, Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid output format: xml")
+
+	assert.Panics(t, func() { Register(Format{Name: "json"}) })
+}
+
+// Every format must render both events and rows without failing.
+func TestEveryFormatRendersEveryResult(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			out := render(t, name, Options{}, func(f Formatter) error {
+				return WriteEvents(f, Header{}, testEvents)
+			})
+			assert.NotEmpty(t, out)
+
+			out = render(t, name, Options{}, func(f Formatter) error {
+				return WriteRows(f, Header{Columns: []string{"count", "value"}}, [][]any{{float64(3), "web-1"}})
+			})
+			assert.Contains(t, out, "web-1")
+		})
+	}
+}
+
+func TestFormatCompactTimestamp(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "empty input", in: "", want: ""},
+		{name: "nanoseconds since epoch", in: "1700000000000000000", want: "22:13:20"},
+		{name: "rfc3339 utc", in: "2024-05-19T07:08:09Z", want: "07:08:09"},
+		{name: "rfc3339 with offset is normalised to utc", in: "2024-05-19T09:08:09+02:00", want: "07:08:09"},
+		{name: "unparseable falls back to input", in: "not-a-timestamp", want: "not-a-timestamp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatCompactTimestamp(tt.in))
+		})
+	}
+}
+
+func TestSeverityChar(t *testing.T) {
+	tests := []struct {
+		sev  int
+		want string
+	}{
+		{sev: 0, want: "D"},
+		{sev: 1, want: "D"},
+		{sev: 2, want: "D"},
+		{sev: 3, want: "I"},
+		{sev: 4, want: "W"},
+		{sev: 5, want: "E"},
+		{sev: 6, want: "F"},
+		{sev: 9, want: "F"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SeverityChar(tt.sev), "severity %d", tt.sev)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	out := render(t, "compact", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents)
+	})
+	expected := "22:13:20 I service ready\n" +
+		"22:13:21 E boom\n" +
+		"not-a-time D fallback\n"
+	assert.Equal(t, expected, out)
+
+	out = render(t, "compact", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, nil)
+	})
+	assert.Empty(t, out)
+}
+
+func TestMultiLine_Rows(t *testing.T) {
+	out := render(t, "multiline", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"count", "value"}}, [][]any{{float64(3), "a"}, {float64(1), "b"}})
+	})
+	assert.Equal(t, "count: 3\nvalue: a\n\ncount: 1\nvalue: b\n", out)
+}
+
+func TestSingleLine_PositionalRow(t *testing.T) {
+	out := render(t, "singleline", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, [][]any{{1.5, float64(2)}})
+	})
+	assert.Equal(t, "0=1.5 1=2\n", out)
+}
+
+// nestedEvent has attributes in an order a map does not keep, and a nested
+// object.
+var nestedEvent = client.LogEvent{
+	Timestamp: "1700000000000000000",
+	Severity:  5,
+	Message:   "boom",
+	Attributes: map[string]any{
+		"zone":    "eu-1",
+		"app":     "api",
+		"http":    map[string]any{"status": float64(500), "method": "GET"},
+		"tags":    []any{"a", "b"},
+		"service": "checkout",
+	},
+}
+
+func TestEventAttributes_SortedWithNestedValuesAsJSON(t *testing.T) {
+	for range 5 {
+		out := render(t, "singleline", Options{}, func(f Formatter) error {
+			return WriteEvents(f, Header{}, []client.LogEvent{nestedEvent})
+		})
+		assert.Equal(t, `1700000000000000000 [5] boom [app=api, http={"method":"GET","status":500}, service=checkout, tags=["a","b"], zone=eu-1]`+"\n", out)
+	}
+
+	out := render(t, "multiline", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, []client.LogEvent{nestedEvent})
+	})
+	assert.Contains(t, out, "Attributes:\n  app: api\n  http: {\"method\":\"GET\",\"status\":500}\n  service: checkout\n")
+}
+
+func TestEventAttributes_FlattenAndOrder(t *testing.T) {
+	opts := Options{Flatten: true, AttrOrder: []string{"service", "http.status", "missing"}}
+
+	out := render(t, "singleline", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, []client.LogEvent{nestedEvent})
+	})
+	assert.Equal(t, `1700000000000000000 [5] boom [service=checkout, http.status=500, app=api, http.method=GET, tags=["a","b"], zone=eu-1]`+"\n", out)
+
+	out = render(t, "multiline", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, []client.LogEvent{nestedEvent})
+	})
+	assert.Contains(t, out, "Attributes:\n  service: checkout\n  http.status: 500\n  app: api\n  http.method: GET\n")
+
+	out = render(t, "logfmt", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, []client.LogEvent{nestedEvent})
+	})
+	assert.Equal(t, `timestamp=1700000000000000000 severity=5 message=boom service=checkout http.status=500 app=api http.method=GET tags="[\"a\",\"b\"]" zone=eu-1`+"\n", out)
+}
+
+func TestEventAttributes_EmptyObjectIsKept(t *testing.T) {
+	event := client.LogEvent{Message: "m", Attributes: map[string]any{"meta": map[string]any{}}}
+	out := render(t, "singleline", Options{Flatten: true}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, []client.LogEvent{event})
+	})
+	assert.Equal(t, " [0] m [meta={}]\n", out)
+}
+
+func TestCSV_NumericRow(t *testing.T) {
+	out := render(t, "csv", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, [][]any{{1.5, float64(2), 3.14}})
+	})
+	assert.Equal(t, "1.5,2,3.14\n", out)
+
+	out = render(t, "csv", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, [][]any{{}})
+	})
+	assert.Equal(t, "\n", out)
+}
+
+func TestCSV_Events(t *testing.T) {
+	out := render(t, "csv", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Columns: []string{"message", "host"}}, testEvents[:2])
+	})
+	assert.Equal(t, "message,host\nservice ready,\nboom,web-1\n", out)
+}
+
+func TestCSV_Sources(t *testing.T) {
+	out := render(t, "csv", Options{}, func(f Formatter) error {
+		if err := f.Begin(Header{Events: true, Follow: true, Sources: []string{"api"}}); err != nil {
+			return err
+		}
+		if err := f.Write(Record{Source: "api", Event: &testEvents[0]}); err != nil {
+			return err
+		}
+		return f.End()
+	})
+	assert.Equal(t, "source,timestamp,severity,message\napi,1700000000000000000,3,service ready\n", out)
+}
+
+func TestLabelPrefixes(t *testing.T) {
+	sources := []string{"api", "worker"}
+
+	assert.Equal(t, map[string]string{"api": "[api]    ", "worker": "[worker] "}, LabelPrefixes(sources, false))
+
+	colored := LabelPrefixes(sources, true)
+	assert.Equal(t, "\033[36m[api]   \033[0m ", colored["api"])
+	assert.Equal(t, "\033[33m[worker]\033[0m ", colored["worker"])
+
+	assert.Empty(t, LabelPrefixes([]string{""}, true))
+}
+
+func TestJSON_FollowWritesOneObjectPerLine(t *testing.T) {
+	event := client.LogEvent{Timestamp: "1", Severity: 3, Message: "hello"}
+
+	out := render(t, "json", Options{}, func(f Formatter) error {
+		if err := f.Begin(Header{Events: true, Follow: true, Sources: []string{"api"}}); err != nil {
+			return err
+		}
+		if err := f.Write(Record{Source: "api", Event: &event}); err != nil {
+			return err
+		}
+		if err := f.Write(Record{Event: &event}); err != nil {
+			return err
+		}
+		return f.End()
+	})
+	assert.Equal(t,
+		`{"source":"api","timestamp":"1","severity":3,"message":"hello"}`+"\n"+
+			`{"timestamp":"1","severity":3,"message":"hello"}`+"\n",
+		out)
+}
+
+func TestJSON_Document(t *testing.T) {
+	doc := map[string]any{"status": "success", "values": []float64{1, 2}}
+
+	out := render(t, "json", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Document: doc}, [][]any{{float64(1), float64(2)}})
+	})
+	assert.Equal(t, `{"status":"success","values":[1,2]}`+"\n", out)
+}
+
+func TestJSON_StreamedEventsMatchMarshalledResponse(t *testing.T) {
+	for _, pretty := range []bool{false, true} {
+		for _, events := range [][]client.LogEvent{nil, testEvents} {
+			name := "json"
+			if pretty {
+				name = "json-pretty"
+			}
+			out := render(t, name, Options{}, func(f Formatter) error {
+				return WriteEvents(f, Header{}, events)
+			})
+
+			matches := events
+			if matches == nil {
+				matches = []client.LogEvent{}
+			}
+			response := struct {
+				Status  string            `json:"status"`
+				Matches []client.LogEvent `json:"matches"`
+			}{"success", matches}
+			want, err := marshalJSON(response, "", pretty)
+			require.NoError(t, err)
+			assert.Equal(t, string(want)+"\n", out, "%s with %d events", name, len(events))
+		}
+	}
+}
+
+func TestJSON_Fields(t *testing.T) {
+	out := render(t, "json", Options{Fields: []string{"message", "host"}}, func(f Formatter) error {
+		return WriteEvents(f, Header{Document: "ignored"}, testEvents[:2])
+	})
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &got))
+	assert.Equal(t, []map[string]any{
+		{"message": "service ready"},
+		{"message": "boom", "host": "web-1"},
+	}, got)
+}
+
+func TestNDJSON_Rows(t *testing.T) {
+	out := render(t, "ndjson", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"count", "value"}}, [][]any{{float64(3), "a"}})
+	})
+	assert.Equal(t, `{"count":3,"value":"a"}`+"\n", out)
+}
@@ -0,0 +1,301 @@
+package output
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/validation"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions lists the codecs rotated segments can be compressed with.
+var Compressions = []string{"gzip", "zstd"}
+
+var compressionExts = map[string]string{"gzip": ".gz", "zstd": ".zst"}
+
+func init() {
+	for _, c := range Compressions {
+		validation.RegisterCompression(c)
+	}
+}
+
+// segmentTimeLayout stamps rotated segments with the time they were started,
+// so their names sort in order.
+const segmentTimeLayout = "20060102T150405Z"
+
+// RotateOptions decide when a rotating file starts a new segment, and what
+// becomes of the segments it rotates out.
+type RotateOptions struct {
+	// Size rotates once a segment holds this many bytes, or never when 0.
+	Size int64
+	// Every rotates at each multiple of this interval, counted in UTC, or
+	// never when 0.
+	Every time.Duration
+	// Compress is a codec from Compressions to compress rotated segments
+	// with, or "" to leave them as they are.
+	Compress string
+	// Retain is how many rotated segments are kept, deleting the oldest, or
+	// every segment when 0.
+	Retain int
+}
+
+// NewRotating returns a formatter that writes to path, moving it aside as a
+// timestamped segment whenever opts says to rotate. Every segment is a
+// complete file: the formatter made by newFormatter for it is ended, and a
+// fresh one begun with the same Header, so formats with headers or footers
+// stay valid. A non-empty file already at path is rotated out first rather
+// than overwritten.
+func NewRotating(path string, opts RotateOptions, newFormatter func(w io.Writer) Formatter) Formatter {
+	return &rotatingFormatter{path: path, opts: opts, newFormatter: newFormatter, now: time.Now}
+}
+
+type rotatingFormatter struct {
+	path         string
+	opts         RotateOptions
+	newFormatter func(w io.Writer) Formatter
+	now          func() time.Time
+
+	header   Header
+	file     *os.File
+	counter  *countingWriter
+	current  Formatter
+	started  time.Time
+	deadline time.Time
+}
+
+// countingWriter counts the bytes written to a segment.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (f *rotatingFormatter) Begin(h Header) error {
+	f.header = h
+	if info, err := os.Stat(f.path); err == nil && info.Size() > 0 {
+		if err := f.archive(info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return f.open()
+}
+
+func (f *rotatingFormatter) Write(r Record) error {
+	if f.due() {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	return f.current.Write(r)
+}
+
+func (f *rotatingFormatter) End() error {
+	return f.close()
+}
+
+// due reports whether the current segment is full or its interval is over.
+func (f *rotatingFormatter) due() bool {
+	if f.opts.Size > 0 && f.counter.n >= f.opts.Size {
+		return true
+	}
+	return f.opts.Every > 0 && !f.now().Before(f.deadline)
+}
+
+// open starts a new segment at path.
+func (f *rotatingFormatter) open() error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		return errors.NewConfigError("failed to create "+f.path, err)
+	}
+	f.file = file
+	f.counter = &countingWriter{w: file}
+	f.started = f.now()
+	if f.opts.Every > 0 {
+		f.deadline = f.started.UTC().Truncate(f.opts.Every).Add(f.opts.Every)
+	}
+	f.current = f.newFormatter(f.counter)
+	return f.current.Begin(f.header)
+}
+
+// close ends the current segment and closes its file.
+func (f *rotatingFormatter) close() error {
+	err := f.current.End()
+	if closeErr := f.file.Close(); err == nil && closeErr != nil {
+		err = errors.NewConfigError("failed to write "+f.path, closeErr)
+	}
+	return err
+}
+
+func (f *rotatingFormatter) rotate() error {
+	if err := f.close(); err != nil {
+		return err
+	}
+	if err := f.archive(f.started); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// archive moves the file at path aside as the segment started at started,
+// compresses it and prunes the segments beyond the retention count.
+func (f *rotatingFormatter) archive(started time.Time) error {
+	name := f.segmentName(started)
+	if err := os.Rename(f.path, name); err != nil {
+		return errors.NewConfigError("failed to rotate "+f.path, err)
+	}
+	if f.opts.Compress != "" {
+		if err := compressFile(name, f.opts.Compress); err != nil {
+			return err
+		}
+	}
+	return f.prune()
+}
+
+// segmentParts splits path into the parts segment names are built from:
+// "logs/errors.ndjson" becomes "logs/errors" and ".ndjson".
+func (f *rotatingFormatter) segmentParts() (stem, ext string) {
+	ext = filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext), ext
+}
+
+// segmentName returns an unused name for a segment started at started, such
+// as "errors-20240102T150405Z.ndjson", numbering segments started within the
+// same second.
+func (f *rotatingFormatter) segmentName(started time.Time) string {
+	stem, ext := f.segmentParts()
+	base := stem + "-" + started.UTC().Format(segmentTimeLayout)
+	for n := 0; ; n++ {
+		name := base + ext
+		if n > 0 {
+			name = base + "-" + strconv.Itoa(n) + ext
+		}
+		if !segmentExists(name) {
+			return name
+		}
+	}
+}
+
+func segmentExists(name string) bool {
+	for _, suffix := range []string{"", ".gz", ".zst"} {
+		if _, err := os.Stat(name + suffix); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// segment is a rotated segment found on disk.
+type segment struct {
+	path    string
+	started string
+	n       int
+}
+
+// segments lists the rotated segments of path, oldest first.
+func (f *rotatingFormatter) segments() ([]segment, error) {
+	stem, ext := f.segmentParts()
+	pattern := regexp.MustCompile("^" + regexp.QuoteMeta(filepath.Base(stem)) +
+		`-(\d{8}T\d{6}Z)(?:-(\d+))?` + regexp.QuoteMeta(ext) + `(?:\.gz|\.zst)?$`)
+
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return nil, errors.NewConfigError("failed to list rotated segments of "+f.path, err)
+	}
+	var found []segment
+	for _, entry := range entries {
+		m := pattern.FindStringSubmatch(entry.Name())
+		if m == nil || entry.IsDir() {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		found = append(found, segment{path: filepath.Join(filepath.Dir(f.path), entry.Name()), started: m[1], n: n})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].started != found[j].started {
+			return found[i].started < found[j].started
+		}
+		return found[i].n < found[j].n
+	})
+	return found, nil
+}
+
+// prune deletes the oldest segments beyond the retention count.
+func (f *rotatingFormatter) prune() error {
+	if f.opts.Retain <= 0 {
+		return nil
+	}
+	found, err := f.segments()
+	if err != nil {
+		return err
+	}
+	for len(found) > f.opts.Retain {
+		if err := os.Remove(found[0].path); err != nil {
+			return errors.NewConfigError("failed to delete rotated segment "+found[0].path, err)
+		}
+		found = found[1:]
+	}
+	return nil
+}
+
+// compressFile replaces name with a compressed copy carrying the codec's
+// extension. The copy is written under a temporary name first, so an
+// interrupted compression never leaves a truncated segment behind.
+func compressFile(name, codec string) error {
+	ext, ok := compressionExts[codec]
+	if !ok {
+		return errors.NewValidationError(fmt.Sprintf("invalid compression: %s", codec),
+			fmt.Errorf("valid compressions: %s", strings.Join(Compressions, ", ")))
+	}
+
+	in, err := os.Open(name)
+	if err != nil {
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	var w io.WriteCloser
+	if codec == "gzip" {
+		w = gzip.NewWriter(tmp)
+	} else if w, err = zstd.NewWriter(tmp); err != nil {
+		tmp.Close()
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		tmp.Close()
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	if err := w.Close(); err != nil {
+		tmp.Close()
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	if err := os.Rename(tmp.Name(), name+ext); err != nil {
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	if err := os.Remove(name); err != nil {
+		return errors.NewConfigError("failed to compress "+name, err)
+	}
+	return nil
+}
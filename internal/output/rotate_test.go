@@ -0,0 +1,170 @@
+package output
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a settable clock for rotation tests.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestRotating(t *testing.T, path, format string, opts RotateOptions, clock *fakeClock) Formatter {
+	t.Helper()
+
+	f := NewRotating(path, opts, func(w io.Writer) Formatter {
+		f, err := New(format, w, Options{})
+		require.NoError(t, err)
+		return f
+	})
+	f.(*rotatingFormatter).now = clock.now
+	return f
+}
+
+// dirFiles lists the names of the files in dir, sorted.
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRotating_Size(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "errors.csv")
+	clock := &fakeClock{t: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)}
+
+	f := newTestRotating(t, path, "csv", RotateOptions{Size: 20}, clock)
+	require.NoError(t, f.Begin(Header{Events: true, Columns: []string{"message"}}))
+	for _, msg := range []string{"first event", "second event", "third event"} {
+		require.NoError(t, f.Write(Record{Event: &client.LogEvent{Message: msg}}))
+		clock.t = clock.t.Add(time.Second)
+	}
+	require.NoError(t, f.End())
+
+	assert.Equal(t, []string{"errors-20240102T150405Z.csv", "errors-20240102T150406Z.csv", "errors.csv"}, dirFiles(t, dir))
+	assert.Equal(t, "message\nfirst event\n", readFile(t, filepath.Join(dir, "errors-20240102T150405Z.csv")),
+		"every segment is a complete file with its own header")
+	assert.Equal(t, "message\nsecond event\n", readFile(t, filepath.Join(dir, "errors-20240102T150406Z.csv")))
+	assert.Equal(t, "message\nthird event\n", readFile(t, path))
+}
+
+func TestRotating_EveryAlignsToInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tail.ndjson")
+	clock := &fakeClock{t: time.Date(2024, 1, 2, 15, 40, 0, 0, time.UTC)}
+
+	f := newTestRotating(t, path, "ndjson", RotateOptions{Every: time.Hour}, clock)
+	require.NoError(t, f.Begin(Header{Events: true, Follow: true}))
+	require.NoError(t, f.Write(Record{Event: &testEvents[0]}))
+	clock.t = time.Date(2024, 1, 2, 15, 59, 59, 0, time.UTC)
+	require.NoError(t, f.Write(Record{Event: &testEvents[1]}))
+	clock.t = time.Date(2024, 1, 2, 16, 0, 0, 0, time.UTC)
+	require.NoError(t, f.Write(Record{Event: &testEvents[2]}))
+	require.NoError(t, f.End())
+
+	assert.Equal(t, []string{"tail-20240102T154000Z.ndjson", "tail.ndjson"}, dirFiles(t, dir))
+	assert.Contains(t, readFile(t, filepath.Join(dir, "tail-20240102T154000Z.ndjson")), "boom")
+	assert.Contains(t, readFile(t, path), "fallback")
+}
+
+func TestRotating_CompressAndRetain(t *testing.T) {
+	for _, tt := range []struct {
+		codec string
+		ext   string
+		open  func(r io.Reader) (io.Reader, error)
+	}{
+		{codec: "gzip", ext: ".gz", open: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{codec: "zstd", ext: ".zst", open: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) }},
+	} {
+		t.Run(tt.codec, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "app.log")
+			clock := &fakeClock{t: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+
+			f := newTestRotating(t, path, "messageonly", RotateOptions{Size: 1, Compress: tt.codec, Retain: 2}, clock)
+			require.NoError(t, f.Begin(Header{Events: true, Follow: true}))
+			for _, msg := range []string{"one", "two", "three", "four"} {
+				require.NoError(t, f.Write(Record{Event: &client.LogEvent{Message: msg}}))
+				clock.t = clock.t.Add(time.Minute)
+			}
+			require.NoError(t, f.End())
+
+			assert.Equal(t, []string{
+				"app-20240102T000100Z.log" + tt.ext,
+				"app-20240102T000200Z.log" + tt.ext,
+				"app.log",
+			}, dirFiles(t, dir), "only the newest --retain segments are kept, compressed")
+
+			file, err := os.Open(filepath.Join(dir, "app-20240102T000200Z.log"+tt.ext))
+			require.NoError(t, err)
+			defer file.Close()
+			r, err := tt.open(file)
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, "three\n", string(data))
+			assert.Equal(t, "four\n", readFile(t, path))
+		})
+	}
+}
+
+func TestRotating_ExistingFileIsKept(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tail.log")
+	require.NoError(t, os.WriteFile(path, []byte("from the last run\n"), 0644))
+	modTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	clock := &fakeClock{t: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+	f := newTestRotating(t, path, "messageonly", RotateOptions{Every: time.Hour}, clock)
+	require.NoError(t, f.Begin(Header{Events: true, Follow: true}))
+	require.NoError(t, f.Write(Record{Event: &client.LogEvent{Message: "new run"}}))
+	require.NoError(t, f.End())
+
+	assert.Equal(t, []string{"tail-20240101T120000Z.log", "tail.log"}, dirFiles(t, dir))
+	assert.Equal(t, "from the last run\n", readFile(t, filepath.Join(dir, "tail-20240101T120000Z.log")))
+	assert.Equal(t, "new run\n", readFile(t, path))
+}
+
+func TestRotating_SegmentsInTheSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "burst")
+	clock := &fakeClock{t: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+
+	f := newTestRotating(t, path, "messageonly", RotateOptions{Size: 1, Retain: 2}, clock)
+	require.NoError(t, f.Begin(Header{Events: true, Follow: true}))
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, f.Write(Record{Event: &client.LogEvent{Message: msg}}))
+	}
+	require.NoError(t, f.End())
+
+	assert.Equal(t, []string{"burst", "burst-20240102T000000Z-1", "burst-20240102T000000Z-2"}, dirFiles(t, dir),
+		"segments started in the same second are numbered, and pruned in order")
+	assert.Equal(t, "c\n", readFile(t, filepath.Join(dir, "burst-20240102T000000Z-2")))
+}
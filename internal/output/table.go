@@ -0,0 +1,293 @@
+package output
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+func init() {
+	Register(Format{
+		Name:        "table",
+		Description: "Aligned columns with Unicode box-drawing borders, fitted to the terminal width",
+		New: func(w io.Writer, opts Options) Formatter {
+			return &tableFormatter{w: w, style: unicodeBorders, width: opts.Width, time: opts.Time}
+		},
+	})
+	Register(Format{
+		Name:        "table-ascii",
+		Description: "table, drawn with plain ASCII",
+		New: func(w io.Writer, opts Options) Formatter {
+			return &tableFormatter{w: w, style: asciiBorders, width: opts.Width, time: opts.Time}
+		},
+	})
+}
+
+// tableBorders are the characters a table is drawn with. Each rule lists the
+// left edge, the fill, the column separator and the right edge.
+type tableBorders struct {
+	top, middle, bottom [4]string
+	vertical            string
+}
+
+var (
+	unicodeBorders = tableBorders{
+		top:      [4]string{"┌", "─", "┬", "┐"},
+		middle:   [4]string{"├", "─", "┼", "┤"},
+		bottom:   [4]string{"└", "─", "┴", "┘"},
+		vertical: "│",
+	}
+	asciiBorders = tableBorders{
+		top:      [4]string{"+", "-", "+", "+"},
+		middle:   [4]string{"+", "-", "+", "+"},
+		bottom:   [4]string{"+", "-", "+", "+"},
+		vertical: "|",
+	}
+)
+
+// minCellWidth is how narrow a column may be squeezed to fit the terminal.
+const minCellWidth = 6
+
+// tableFormatter buffers a result to measure its columns before drawing it.
+// A followed result never ends, so its columns are measured on the first
+// record and later rows are wrapped to fit them.
+type tableFormatter struct {
+	w       io.Writer
+	style   tableBorders
+	width   int
+	time    TimeFormat
+	header  Header
+	layout  columnLayout
+	columns []string
+	rows    [][]string
+	numeric []bool
+	widths  []int
+	started bool
+}
+
+func (f *tableFormatter) Begin(h Header) error {
+	f.header = h
+	f.layout = newColumnLayout(h, f.time)
+	f.columns = f.layout.names()
+	return nil
+}
+
+func (f *tableFormatter) Write(r Record) error {
+	values := f.layout.values(r)
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = FormatValue(v)
+		if i >= len(f.numeric) {
+			f.numeric = append(f.numeric, true)
+		}
+		if v != nil && !isNumber(v) {
+			f.numeric[i] = false
+		}
+	}
+
+	if !f.header.Follow {
+		f.rows = append(f.rows, row)
+		return nil
+	}
+	if !f.started {
+		f.measure([][]string{row})
+		if err := f.writeHeader(); err != nil {
+			return err
+		}
+	}
+	return f.writeRow(row)
+}
+
+func (f *tableFormatter) End() error {
+	if !f.header.Follow {
+		f.measure(f.rows)
+		if err := f.writeHeader(); err != nil {
+			return err
+		}
+		for _, row := range f.rows {
+			if err := f.writeRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	if !f.started {
+		return nil
+	}
+	return f.writeRule(f.style.bottom)
+}
+
+// measure sizes every column to its widest cell, then narrows the widest
+// columns until the table fits the terminal.
+func (f *tableFormatter) measure(rows [][]string) {
+	n := len(f.columns)
+	for _, row := range rows {
+		n = max(n, len(row))
+	}
+	for len(f.numeric) < n {
+		f.numeric = append(f.numeric, false)
+	}
+
+	f.widths = make([]int, n)
+	for i := range f.widths {
+		f.widths[i] = max(1, utf8.RuneCountInString(f.columnName(i)))
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			for _, line := range strings.Split(cell, "\n") {
+				f.widths[i] = max(f.widths[i], utf8.RuneCountInString(line))
+			}
+		}
+	}
+
+	if f.width > 0 {
+		// Every column has a space either side and a border after it.
+		fitWidths(f.widths, f.width-(3*n+1))
+	}
+}
+
+// fitWidths narrows the widest columns, never below minCellWidth, until
+// their sum fits in avail.
+func fitWidths(widths []int, avail int) {
+	for {
+		total, widest, second := 0, 0, 0
+		for i, w := range widths {
+			total += w
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		for i, w := range widths {
+			if i != widest {
+				second = max(second, w)
+			}
+		}
+
+		excess := total - avail
+		if excess <= 0 || widths[widest] <= minCellWidth {
+			return
+		}
+		target := max(widths[widest]-excess, second, minCellWidth)
+		if target == widths[widest] {
+			target--
+		}
+		widths[widest] = target
+	}
+}
+
+// columnName is the header of column i. Positional tables have no header.
+func (f *tableFormatter) columnName(i int) string {
+	if i < len(f.columns) {
+		return f.columns[i]
+	}
+	return ""
+}
+
+func (f *tableFormatter) writeHeader() error {
+	if len(f.widths) == 0 {
+		return nil
+	}
+	f.started = true
+	if err := f.writeRule(f.style.top); err != nil {
+		return err
+	}
+	if len(f.columns) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(f.widths))
+	for i := range names {
+		names[i] = f.columnName(i)
+	}
+	if err := f.writeCells(names, false); err != nil {
+		return err
+	}
+	return f.writeRule(f.style.middle)
+}
+
+func (f *tableFormatter) writeRow(row []string) error {
+	if !f.started {
+		return nil
+	}
+	for len(row) < len(f.widths) {
+		row = append(row, "")
+	}
+	return f.writeCells(row[:len(f.widths)], true)
+}
+
+// writeCells writes one row, wrapping cells wider than their column onto
+// further lines. Numeric columns are right-aligned.
+func (f *tableFormatter) writeCells(cells []string, align bool) error {
+	wrapped := make([][]string, len(cells))
+	lines := 1
+	for i, cell := range cells {
+		wrapped[i] = wrapCell(cell, f.widths[i])
+		lines = max(lines, len(wrapped[i]))
+	}
+
+	var b strings.Builder
+	for line := 0; line < lines; line++ {
+		b.WriteString(f.style.vertical)
+		for i, cell := range wrapped {
+			text := ""
+			if line < len(cell) {
+				text = cell[line]
+			}
+			padding := strings.Repeat(" ", f.widths[i]-utf8.RuneCountInString(text))
+			b.WriteString(" ")
+			if align && f.numeric[i] {
+				b.WriteString(padding + text)
+			} else {
+				b.WriteString(text + padding)
+			}
+			b.WriteString(" " + f.style.vertical)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+func (f *tableFormatter) writeRule(rule [4]string) error {
+	var b strings.Builder
+	b.WriteString(rule[0])
+	for i, w := range f.widths {
+		if i > 0 {
+			b.WriteString(rule[2])
+		}
+		b.WriteString(strings.Repeat(rule[1], w+2))
+	}
+	b.WriteString(rule[3] + "\n")
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+// wrapCell splits a cell into lines of at most width runes, breaking at its
+// own newlines first and then at the last space that fits, if any.
+func wrapCell(cell string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(cell, "\t", " "), "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			cut, next := width, width
+			for i := width; i > 0; i-- {
+				if runes[i] == ' ' {
+					cut, next = i, i+1
+					break
+				}
+			}
+			lines = append(lines, string(runes[:cut]))
+			runes = runes[next:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int, int32, int64, float32, float64:
+		return true
+	default:
+		return false
+	}
+}
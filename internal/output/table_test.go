@@ -0,0 +1,134 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTable_Rows(t *testing.T) {
+	out := render(t, "table", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"uriPath", "requests"}}, [][]any{
+			{"/login", float64(100)},
+			{"/home", float64(2500)},
+		})
+	})
+
+	expected := "" +
+		"┌─────────┬──────────┐\n" +
+		"│ uriPath │ requests │\n" +
+		"├─────────┼──────────┤\n" +
+		"│ /login  │      100 │\n" +
+		"│ /home   │     2500 │\n" +
+		"└─────────┴──────────┘\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestTable_ASCII(t *testing.T) {
+	out := render(t, "table-ascii", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"count", "value"}}, [][]any{{float64(42), "/index.html"}})
+	})
+
+	expected := "" +
+		"+-------+-------------+\n" +
+		"| count | value       |\n" +
+		"+-------+-------------+\n" +
+		"|    42 | /index.html |\n" +
+		"+-------+-------------+\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestTable_PositionalRowHasNoHeader(t *testing.T) {
+	out := render(t, "table-ascii", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, [][]any{{1.5, float64(20)}})
+	})
+
+	expected := "" +
+		"+-----+----+\n" +
+		"| 1.5 | 20 |\n" +
+		"+-----+----+\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestTable_EmptyResult(t *testing.T) {
+	out := render(t, "table", Options{}, func(f Formatter) error {
+		return WriteRows(f, Header{}, nil)
+	})
+	assert.Empty(t, out)
+}
+
+func TestTable_EventsWithColumns(t *testing.T) {
+	out := render(t, "table-ascii", Options{}, func(f Formatter) error {
+		return WriteEvents(f, Header{Columns: []string{"severity", "message", "host"}}, testEvents[:2])
+	})
+
+	expected := "" +
+		"+----------+---------------+-------+\n" +
+		"| severity | message       | host  |\n" +
+		"+----------+---------------+-------+\n" +
+		"|        3 | service ready |       |\n" +
+		"|        5 | boom          | web-1 |\n" +
+		"+----------+---------------+-------+\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestTable_WrapsToWidth(t *testing.T) {
+	out := render(t, "table-ascii", Options{Width: 30}, func(f Formatter) error {
+		return WriteRows(f, Header{Columns: []string{"n", "message"}}, [][]any{
+			{float64(1), "a message far too long for the terminal"},
+		})
+	})
+
+	expected := "" +
+		"+---+------------------------+\n" +
+		"| n | message                |\n" +
+		"+---+------------------------+\n" +
+		"| 1 | a message far too long |\n" +
+		"|   | for the terminal       |\n" +
+		"+---+------------------------+\n"
+	assert.Equal(t, expected, out)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		assert.LessOrEqual(t, len(line), 30)
+	}
+}
+
+func TestTable_FollowUsesFirstRecordWidths(t *testing.T) {
+	out := render(t, "table-ascii", Options{}, func(f Formatter) error {
+		if err := f.Begin(Header{Events: true, Follow: true, Columns: []string{"message"}, Sources: []string{"api"}}); err != nil {
+			return err
+		}
+		for i := range testEvents[:2] {
+			if err := f.Write(Record{Source: "api", Event: &testEvents[i]}); err != nil {
+				return err
+			}
+		}
+		return f.End()
+	})
+
+	expected := "" +
+		"+--------+---------------+\n" +
+		"| source | message       |\n" +
+		"+--------+---------------+\n" +
+		"| api    | service ready |\n" +
+		"| api    | boom          |\n" +
+		"+--------+---------------+\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestWrapCell(t *testing.T) {
+	assert.Equal(t, []string{"short"}, wrapCell("short", 10))
+	assert.Equal(t, []string{"two", "lines"}, wrapCell("two\nlines", 10))
+	assert.Equal(t, []string{"breaks at", "spaces"}, wrapCell("breaks at spaces", 10))
+	assert.Equal(t, []string{"unbreakabl", "e"}, wrapCell("unbreakable", 10))
+}
+
+func TestFitWidths(t *testing.T) {
+	widths := []int{10, 50, 40}
+	fitWidths(widths, 60)
+	assert.Equal(t, []int{10, 25, 25}, widths)
+
+	widths = []int{8, 8}
+	fitWidths(widths, 4)
+	assert.Equal(t, []int{minCellWidth, minCellWidth}, widths, "columns never shrink below the minimum")
+}
@@ -0,0 +1,137 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+)
+
+// TemplateEvent is what --template is executed with: the event, plus the
+// label of the tail --filter it matched.
+type TemplateEvent struct {
+	Source string
+	client.LogEvent
+}
+
+// TemplateFuncs returns the functions available to --template. Colours are
+// only written when opts.Color is set, so templates can use them
+// unconditionally.
+func TemplateFuncs(opts Options) template.FuncMap {
+	color := func(spec string, v any) (string, error) {
+		code, err := ParseColor(spec)
+		if err != nil {
+			return "", err
+		}
+		if !opts.Color {
+			return FormatValue(v), nil
+		}
+		return paint(code, FormatValue(v)), nil
+	}
+
+	highlight := highlighter{}
+	if opts.Color {
+		highlight = newHighlighter(opts.Highlight, opts.Theme.Highlight)
+	}
+
+	return template.FuncMap{
+		// time formats a timestamp with a Go layout, e.g. "15:04:05", and
+		// timestamp with --time-format, both in the --tz time zone.
+		"time": func(layout string, ts string) string {
+			return opts.Time.Layout(ts, layout)
+		},
+		"timestamp": opts.Time.Format,
+		"compacttime": func(ts string) string {
+			return opts.Time.Layout(ts, compactLayout)
+		},
+		"severity": SeverityName,
+		"sevchar":  SeverityChar,
+		"color":    color,
+		// sevcolor colours v with the theme's colour for the severity.
+		"sevcolor": func(sev int, v any) string {
+			if !opts.Color {
+				return FormatValue(v)
+			}
+			return paint(opts.Theme.Severity(sev), FormatValue(v))
+		},
+		// highlight marks the filter terms in v.
+		"highlight": func(v any) string {
+			return highlight.paint("", FormatValue(v))
+		},
+		// pad and padleft align v in a column of n runes.
+		"pad": func(n int, v any) string {
+			s := FormatValue(v)
+			return s + strings.Repeat(" ", max(0, n-utf8.RuneCountInString(s)))
+		},
+		"padleft": func(n int, v any) string {
+			s := FormatValue(v)
+			return strings.Repeat(" ", max(0, n-utf8.RuneCountInString(s))) + s
+		},
+		// trunc shortens v to at most n runes.
+		"trunc": func(n int, v any) string {
+			s := FormatValue(v)
+			if utf8.RuneCountInString(s) <= n {
+				return s
+			}
+			return string([]rune(s)[:max(0, n)])
+		},
+		"json": func(v any) (string, error) {
+			out, err := json.Marshal(v)
+			return string(out), err
+		},
+		// default returns def when v is missing or empty, such as an
+		// attribute the event does not have.
+		"default": func(def any, v any) any {
+			if v == nil || v == "" {
+				return def
+			}
+			return v
+		},
+	}
+}
+
+// ParseTemplate parses a --template, which renders one event per line.
+func ParseTemplate(text string, opts Options) (*template.Template, error) {
+	t, err := template.New("template").Funcs(TemplateFuncs(opts)).Parse(text)
+	if err != nil {
+		return nil, errors.NewValidationError("invalid template", err)
+	}
+	return t, nil
+}
+
+// NewTemplate returns a formatter that executes t for each event, adding a
+// newline when the template does not end with one.
+func NewTemplate(t *template.Template, w io.Writer) Formatter {
+	return &templateFormatter{w: w, t: t}
+}
+
+type templateFormatter struct {
+	w io.Writer
+	t *template.Template
+}
+
+func (f *templateFormatter) Begin(h Header) error {
+	if !h.Events {
+		return errors.NewValidationError("templates can only render log events", nil)
+	}
+	return nil
+}
+
+func (f *templateFormatter) Write(r Record) error {
+	var buf bytes.Buffer
+	if err := f.t.Execute(&buf, TemplateEvent{Source: r.Source, LogEvent: *r.Event}); err != nil {
+		return errors.NewValidationError("failed to execute template", err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	_, err := f.w.Write(buf.Bytes())
+	return err
+}
+
+func (f *templateFormatter) End() error { return nil }
@@ -0,0 +1,119 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func renderTemplate(t *testing.T, text string, color bool, records ...Record) string {
+	t.Helper()
+
+	tmpl, err := ParseTemplate(text, Options{Color: color, Theme: DefaultTheme(), Highlight: []string{"refused"}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	f := NewTemplate(tmpl, &buf)
+	require.NoError(t, f.Begin(Header{Events: true}))
+	for _, r := range records {
+		require.NoError(t, f.Write(r))
+	}
+	require.NoError(t, f.End())
+	return buf.String()
+}
+
+func TestTemplate_Events(t *testing.T) {
+	out := renderTemplate(t,
+		`{{.Timestamp | time "15:04:05"}} {{.Attributes.host | default "-"}} {{.Message}}`, false,
+		Record{Event: &testEvents[0]}, Record{Event: &testEvents[1]})
+
+	assert.Equal(t, "22:13:20 - service ready\n22:13:21 web-1 boom\n", out)
+}
+
+func TestTemplate_KeepsTrailingNewline(t *testing.T) {
+	out := renderTemplate(t, "{{.Message}}\n", false, Record{Event: &testEvents[0]})
+	assert.Equal(t, "service ready\n", out)
+}
+
+func TestTemplate_Source(t *testing.T) {
+	out := renderTemplate(t, "{{.Source}}: {{.Message}}", false, Record{Source: "api", Event: &testEvents[0]})
+	assert.Equal(t, "api: service ready\n", out)
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	event := client.LogEvent{
+		Timestamp:  "1700000001000000000",
+		Severity:   5,
+		Message:    "connection refused",
+		Attributes: map[string]any{"latency": 12.5, "empty": ""},
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		color bool
+		want  string
+	}{
+		{name: "time", text: `{{.Timestamp | time "2006-01-02 15:04:05"}}`, want: "2023-11-14 22:13:21"},
+		{name: "time keeps unparseable input", text: `{{time "15:04" "soon"}}`, want: "soon"},
+		{name: "compacttime", text: `{{compacttime .Timestamp}}`, want: "22:13:21"},
+		{name: "severity", text: `{{severity .Severity}} {{sevchar .Severity}}`, want: "error E"},
+		{name: "pad", text: `[{{.Severity | severity | pad 7}}]`, want: "[error  ]"},
+		{name: "padleft", text: `[{{padleft 6 .Attributes.latency}}]`, want: "[  12.5]"},
+		{name: "trunc", text: `{{.Message | trunc 10}}`, want: "connection"},
+		{name: "trunc shorter than limit", text: `{{.Message | trunc 100}}`, want: "connection refused"},
+		{name: "json", text: `{{json .Attributes}}`, want: `{"empty":"","latency":12.5}`},
+		{name: "default for missing attribute", text: `{{.Attributes.host | default "unknown"}}`, want: "unknown"},
+		{name: "default for empty attribute", text: `{{.Attributes.empty | default "-"}}`, want: "-"},
+		{name: "default keeps value", text: `{{.Attributes.latency | default 0}}`, want: "12.5"},
+		{name: "color disabled", text: `{{.Message | color "red"}}`, want: "connection refused"},
+		{name: "color", text: `{{.Message | color "red"}}`, color: true, want: "\033[31mconnection refused\033[0m"},
+		{name: "color with attributes", text: `{{color "bold red" "!"}}`, color: true, want: "\033[1;31m!\033[0m"},
+		{name: "sevcolor", text: `{{sevcolor .Severity "E"}}`, color: true, want: "\033[31mE\033[0m"},
+		{name: "sevcolor disabled", text: `{{sevcolor .Severity "E"}}`, want: "E"},
+		{name: "highlight", text: `{{.Message | highlight}}`, color: true, want: "connection \033[7mrefused\033[0m"},
+		{name: "highlight disabled", text: `{{.Message | highlight}}`, want: "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := renderTemplate(t, tt.text, tt.color, Record{Event: &event})
+			assert.Equal(t, tt.want+"\n", out)
+		})
+	}
+}
+
+func TestTemplateFuncs_TimeFormat(t *testing.T) {
+	tf, err := NewTimeFormat("rfc3339", "Asia/Tokyo")
+	require.NoError(t, err)
+	tmpl, err := ParseTemplate(`{{timestamp .Timestamp}} {{compacttime .Timestamp}} {{time "15:04" .Timestamp}}`, Options{Time: tf})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	f := NewTemplate(tmpl, &buf)
+	require.NoError(t, WriteEvents(f, Header{}, testEvents[:1]))
+	assert.Equal(t, "2023-11-15T07:13:20+09:00 07:13:20 07:13\n", buf.String())
+}
+
+func TestParseTemplate_Invalid(t *testing.T) {
+	_, err := ParseTemplate("{{.Message", Options{})
+	require.Error(t, err)
+	assert.Equal(t, errors.ValidationError, err.(*errors.LogBassetError).Type)
+
+	_, err = ParseTemplate("{{nosuchfunc .Message}}", Options{})
+	assert.Error(t, err)
+}
+
+func TestTemplate_ExecutionErrors(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{.Message | color "teal"}}`, Options{})
+	require.NoError(t, err)
+
+	f := NewTemplate(tmpl, &bytes.Buffer{})
+	require.NoError(t, f.Begin(Header{Events: true}))
+	assert.Error(t, f.Write(Record{Event: &testEvents[0]}))
+
+	assert.Error(t, f.Begin(Header{}), "templates only render events")
+}
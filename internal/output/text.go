@@ -0,0 +1,225 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+)
+
+// Text layouts, shared by events and table rows.
+const (
+	layoutMultiLine = iota
+	layoutSingleLine
+	layoutCompact
+	layoutMessageOnly
+)
+
+func init() {
+	registerText("multiline", "One field per line, records separated by a blank line", layoutMultiLine)
+	registerText("singleline", "One record per line with every field", layoutSingleLine)
+	registerText("compact", "One record per line: HH:MM:SS, a severity letter and the message", layoutCompact)
+	registerText("messageonly", "Only the message of each event, or the values of each row", layoutMessageOnly)
+}
+
+func registerText(name, description string, layout int) {
+	Register(Format{
+		Name:        name,
+		Description: description,
+		New: func(w io.Writer, opts Options) Formatter {
+			f := &textFormatter{w: w, layout: layout, color: opts.Color, time: opts.Time, attrs: newAttributeLayout(opts)}
+			if opts.Color {
+				f.theme = opts.Theme
+				f.highlight = newHighlighter(opts.Highlight, opts.Theme.Highlight)
+			}
+			return f
+		},
+	})
+}
+
+// labelColors are cycled through to tell tail --filter sources apart.
+var labelColors = []string{"36", "33", "35", "32", "34", "31"}
+
+// LabelPrefixes returns the line prefix of each source: its label padded to
+// the longest one, coloured when color is set. Empty labels get none.
+func LabelPrefixes(sources []string, color bool) map[string]string {
+	width := 0
+	for _, source := range sources {
+		width = max(width, len(source))
+	}
+
+	prefixes := make(map[string]string, len(sources))
+	for i, source := range sources {
+		if source == "" {
+			continue
+		}
+		label := fmt.Sprintf("[%s]%s", source, strings.Repeat(" ", width-len(source)))
+		if color {
+			label = "\033[" + labelColors[i%len(labelColors)] + "m" + label + "\033[0m"
+		}
+		prefixes[source] = label + " "
+	}
+	return prefixes
+}
+
+// ParseTimestamp parses a Scalyr nanosecond timestamp string, or an RFC 3339
+// one, into a UTC time.
+func ParseTimestamp(ts string) (time.Time, bool) {
+	if nanos, err := strconv.ParseInt(ts, 10, 64); err == nil {
+		return time.Unix(0, nanos).UTC(), true
+	}
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// FormatCompactTimestamp converts a Scalyr nanosecond timestamp string into
+// HH:MM:SS. Falls back to the original value if parsing fails.
+func FormatCompactTimestamp(ts string) string {
+	return TimeFormat{}.Compact(ts)
+}
+
+// SeverityChar maps a Scalyr severity level to a single character for
+// compact output.
+func SeverityChar(sev int) string {
+	switch {
+	case sev <= 2:
+		return "D"
+	case sev == 3:
+		return "I"
+	case sev == 4:
+		return "W"
+	case sev == 5:
+		return "E"
+	default:
+		return "F"
+	}
+}
+
+// SeverityName maps a Scalyr severity level to the name of its SeverityChar.
+func SeverityName(sev int) string {
+	switch SeverityChar(sev) {
+	case "D":
+		return "debug"
+	case "I":
+		return "info"
+	case "W":
+		return "warning"
+	case "E":
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// textFormatter writes the human-readable layouts. Without colour its theme
+// is empty, so painting leaves the text unchanged.
+type textFormatter struct {
+	w         io.Writer
+	layout    int
+	color     bool
+	theme     Theme
+	highlight highlighter
+	time      TimeFormat
+	attrs     attributeLayout
+	header    Header
+	prefixes  map[string]string
+	written   int
+}
+
+func (f *textFormatter) Begin(h Header) error {
+	f.header = h
+	f.prefixes = LabelPrefixes(h.Sources, f.color)
+	return nil
+}
+
+func (f *textFormatter) Write(r Record) error {
+	var b strings.Builder
+	if f.layout == layoutMultiLine {
+		if f.written > 0 {
+			b.WriteString("\n")
+		}
+		if r.Source != "" {
+			fmt.Fprintf(&b, "Source: %s\n", r.Source)
+		}
+	} else {
+		b.WriteString(f.prefixes[r.Source])
+	}
+
+	if r.Event != nil {
+		f.writeEvent(&b, *r.Event)
+	} else {
+		f.writeRow(&b, r.Values)
+	}
+	f.written++
+
+	_, err := io.WriteString(f.w, b.String())
+	return err
+}
+
+func (f *textFormatter) writeEvent(b *strings.Builder, event client.LogEvent) {
+	sev := f.theme.Severity(event.Severity)
+	key := func(name string) string { return paint(f.theme.Key, name) }
+	message := f.highlight.paint(sev, event.Message)
+	timestamp := paint(f.theme.Timestamp, f.time.Format(event.Timestamp))
+
+	switch f.layout {
+	case layoutMultiLine:
+		fmt.Fprintf(b, "%s %s\n", key("Timestamp:"), timestamp)
+		fmt.Fprintf(b, "%s %s\n", key("Severity:"), paint(sev, strconv.Itoa(event.Severity)))
+		fmt.Fprintf(b, "%s %s\n", key("Message:"), message)
+		if event.Thread != "" {
+			fmt.Fprintf(b, "%s %s\n", key("Thread:"), event.Thread)
+		}
+		if len(event.Attributes) > 0 {
+			b.WriteString(key("Attributes:") + "\n")
+			for _, a := range f.attrs.attributes(event.Attributes) {
+				fmt.Fprintf(b, "  %s %s\n", key(a.key+":"), FormatValue(a.value))
+			}
+		}
+	case layoutSingleLine:
+		fmt.Fprintf(b, "%s %s %s", timestamp, paint(sev, fmt.Sprintf("[%d]", event.Severity)), message)
+		if event.Thread != "" {
+			fmt.Fprintf(b, " (thread: %s)", event.Thread)
+		}
+		if len(event.Attributes) > 0 {
+			var attrs []string
+			for _, a := range f.attrs.attributes(event.Attributes) {
+				attrs = append(attrs, key(a.key)+"="+FormatValue(a.value))
+			}
+			fmt.Fprintf(b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString("\n")
+	case layoutCompact:
+		fmt.Fprintf(b, "%s %s %s\n", paint(f.theme.Timestamp, f.time.Compact(event.Timestamp)), paint(sev, SeverityChar(event.Severity)), message)
+	default:
+		b.WriteString(message + "\n")
+	}
+}
+
+func (f *textFormatter) writeRow(b *strings.Builder, values []any) {
+	switch f.layout {
+	case layoutMultiLine:
+		for i, v := range values {
+			fmt.Fprintf(b, "%s %s\n", paint(f.theme.Key, columnName(f.header, i)+":"), FormatValue(v))
+		}
+	case layoutSingleLine:
+		fields := make([]string, len(values))
+		for i, v := range values {
+			fields[i] = paint(f.theme.Key, columnName(f.header, i)) + "=" + FormatValue(v)
+		}
+		b.WriteString(strings.Join(fields, " ") + "\n")
+	default:
+		fields := make([]string, len(values))
+		for i, v := range values {
+			fields[i] = FormatValue(v)
+		}
+		b.WriteString(strings.Join(fields, " ") + "\n")
+	}
+}
+
+func (f *textFormatter) End() error { return nil }
@@ -0,0 +1,161 @@
+package output
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/timerange"
+)
+
+// TimeFormats are the named values of --time-format. Any other value is a Go
+// layout such as "2006-01-02 15:04:05".
+var TimeFormats = []string{"raw", "rfc3339", "rfc3339nano", "unix", "unixms", "relative"}
+
+// compactLayout is how compact output shows timestamps by default.
+const compactLayout = "15:04:05"
+
+// TimeFormat renders event timestamps as --time-format and --tz ask. The
+// zero value leaves them as the nanosecond strings Scalyr returns, and shows
+// the clock times of compact output in UTC.
+type TimeFormat struct {
+	format   string
+	location *time.Location
+	now      func() time.Time
+}
+
+// NewTimeFormat returns the TimeFormat for a --time-format and a --tz value,
+// either of which may be empty.
+func NewTimeFormat(format, tz string) (TimeFormat, error) {
+	loc, err := timerange.LoadLocation(tz)
+	if err != nil {
+		return TimeFormat{}, errors.NewValidationError(err.Error(),
+			fmt.Errorf("use local, UTC or an IANA name such as Europe/London"))
+	}
+	if err := ValidateTimeFormat(format); err != nil {
+		return TimeFormat{}, err
+	}
+	return TimeFormat{format: format, location: loc}, nil
+}
+
+// ValidateTimeFormat checks a --time-format value: a name from TimeFormats,
+// or a Go layout with at least one date or time element.
+func ValidateTimeFormat(format string) error {
+	if format == "" || slices.Contains(TimeFormats, format) {
+		return nil
+	}
+	// A layout without elements formats every time the same, as itself
+	probe := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if probe.Format(format) == format {
+		return errors.NewValidationError(
+			fmt.Sprintf("invalid time format: %s", format),
+			fmt.Errorf("use %s, or a Go layout such as \"2006-01-02 15:04:05\"", strings.Join(TimeFormats, ", ")),
+		)
+	}
+	return nil
+}
+
+// Raw reports whether timestamps are left as Scalyr returns them.
+func (tf TimeFormat) Raw() bool {
+	return tf.format == "" || tf.format == "raw"
+}
+
+// in converts t to the --tz time zone.
+func (tf TimeFormat) in(t time.Time) time.Time {
+	if tf.location == nil {
+		return t.UTC()
+	}
+	return t.In(tf.location)
+}
+
+// Format renders a timestamp in the --time-format, or returns it unchanged
+// when it cannot be parsed.
+func (tf TimeFormat) Format(ts string) string {
+	if tf.Raw() {
+		return ts
+	}
+	t, ok := ParseTimestamp(ts)
+	if !ok {
+		return ts
+	}
+	return tf.render(t)
+}
+
+// Time renders a time logbasset computed, such as a bucket boundary, in the
+// --time-format. With no Scalyr value to keep, raw is RFC 3339.
+func (tf TimeFormat) Time(t time.Time) string {
+	if tf.Raw() {
+		return tf.in(t).Format(time.RFC3339)
+	}
+	return tf.render(t)
+}
+
+// render formats a parsed time in the --time-format, which is not raw.
+func (tf TimeFormat) render(t time.Time) string {
+	switch tf.format {
+	case "rfc3339":
+		return tf.in(t).Format(time.RFC3339)
+	case "rfc3339nano":
+		return tf.in(t).Format(time.RFC3339Nano)
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unixms":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "relative":
+		now := time.Now
+		if tf.now != nil {
+			now = tf.now
+		}
+		return relativeTime(t, now())
+	default:
+		return tf.in(t).Format(tf.format)
+	}
+}
+
+// Layout renders a timestamp with a Go layout in the --tz time zone, or
+// returns it unchanged when it cannot be parsed.
+func (tf TimeFormat) Layout(ts, layout string) string {
+	if t, ok := ParseTimestamp(ts); ok {
+		return tf.in(t).Format(layout)
+	}
+	return ts
+}
+
+// Compact renders a timestamp for compact output: HH:MM:SS, unless a
+// --time-format was chosen.
+func (tf TimeFormat) Compact(ts string) string {
+	if tf.Raw() {
+		return tf.Layout(ts, compactLayout)
+	}
+	return tf.Format(ts)
+}
+
+// relativeTime describes t from now in its largest whole unit, e.g. "3m ago"
+// or "in 2h".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var amount string
+	switch {
+	case d < time.Minute:
+		amount = fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		amount = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		amount = fmt.Sprintf("%dh", int(d/time.Hour))
+	default:
+		amount = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	}
+
+	if future {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
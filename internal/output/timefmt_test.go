@@ -0,0 +1,111 @@
+package output
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreagrandi/logbasset/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeFormat_Format(t *testing.T) {
+	const ts = "1700000000123000000" // 2023-11-14T22:13:20.123Z
+
+	tests := []struct {
+		format string
+		tz     string
+		want   string
+	}{
+		{format: "", want: ts},
+		{format: "raw", tz: "Europe/Rome", want: ts},
+		{format: "rfc3339", want: "2023-11-14T22:13:20Z"},
+		{format: "rfc3339", tz: "Europe/Rome", want: "2023-11-14T23:13:20+01:00"},
+		{format: "rfc3339nano", want: "2023-11-14T22:13:20.123Z"},
+		{format: "unix", tz: "Europe/Rome", want: "1700000000"},
+		{format: "unixms", want: "1700000000123"},
+		{format: "2006-01-02 15:04 MST", tz: "America/New_York", want: "2023-11-14 17:13 EST"},
+	}
+	for _, tt := range tests {
+		tf, err := NewTimeFormat(tt.format, tt.tz)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, tf.Format(ts), "%s in %q", tt.format, tt.tz)
+	}
+
+	tf, err := NewTimeFormat("rfc3339", "")
+	require.NoError(t, err)
+	assert.Equal(t, "not-a-time", tf.Format("not-a-time"), "unparseable timestamps are kept")
+}
+
+func TestTimeFormat_Relative(t *testing.T) {
+	tf, err := NewTimeFormat("relative", "")
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	tf.now = func() time.Time { return now }
+
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{at: now.Add(-45 * time.Second), want: "45s ago"},
+		{at: now.Add(-3*time.Minute - 10*time.Second), want: "3m ago"},
+		{at: now.Add(-5 * time.Hour), want: "5h ago"},
+		{at: now.Add(-50 * time.Hour), want: "2d ago"},
+		{at: now.Add(2 * time.Minute), want: "in 2m"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tf.Format(tt.at.Format(time.RFC3339)))
+	}
+}
+
+func TestNewTimeFormat_Invalid(t *testing.T) {
+	_, err := NewTimeFormat("iso", "")
+	assert.Error(t, err, "a layout without date or time elements")
+
+	_, err = NewTimeFormat("rfc3339", "Nowhere/Special")
+	assert.Error(t, err)
+
+	tf, err := NewTimeFormat("", "local")
+	require.NoError(t, err)
+	assert.True(t, tf.Raw())
+}
+
+func TestTimeFormat_Formatters(t *testing.T) {
+	tf, err := NewTimeFormat("rfc3339", "Europe/Rome")
+	require.NoError(t, err)
+	opts := Options{Time: tf}
+
+	out := render(t, "singleline", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents[:1])
+	})
+	assert.Equal(t, "2023-11-14T23:13:20+01:00 [3] service ready\n", out)
+
+	out = render(t, "compact", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents)
+	})
+	assert.Equal(t, "2023-11-14T23:13:20+01:00 I service ready\n"+
+		"2023-11-14T23:13:21+01:00 E boom\n"+
+		"not-a-time D fallback\n", out, "compact shows a chosen --time-format in full")
+
+	rome, err := NewTimeFormat("", "Europe/Rome")
+	require.NoError(t, err)
+	out = render(t, "compact", Options{Time: rome}, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents[:1])
+	})
+	assert.Equal(t, "23:13:20 I service ready\n", out, "--tz alone moves compact clock times")
+
+	out = render(t, "tsv", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{Columns: []string{"timestamp", "host"}}, testEvents[1:2])
+	})
+	assert.Equal(t, "timestamp\thost\n2023-11-14T23:13:21+01:00\tweb-1\n", out)
+
+	out = render(t, "logfmt", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, []client.LogEvent{{Timestamp: "1700000000000000000", Message: "m"}})
+	})
+	assert.Equal(t, "timestamp=2023-11-14T23:13:20+01:00 severity=0 message=m\n", out)
+
+	out = render(t, "ndjson", opts, func(f Formatter) error {
+		return WriteEvents(f, Header{}, testEvents[:1])
+	})
+	assert.Contains(t, out, `"timestamp":"1700000000000000000"`, "JSON keeps the values Scalyr returns")
+}
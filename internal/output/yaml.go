@@ -0,0 +1,164 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/andreagrandi/logbasset/internal/errors"
+	"go.yaml.in/yaml/v3"
+)
+
+func init() {
+	Register(Format{
+		Name:        "yaml",
+		Description: "The same document as json, as YAML; tail writes one document per event",
+		Fields:      true,
+		Document:    true,
+		New: func(w io.Writer, opts Options) Formatter {
+			return &yamlFormatter{w: w, fields: opts.Fields}
+		},
+	})
+}
+
+// yamlNode converts data to YAML through its JSON encoding, so keys follow
+// the json tags and keep their order.
+func yamlNode(data any) (*yaml.Node, error) {
+	out, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.NewParseError("failed to marshal JSON", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(out, &doc); err != nil {
+		return nil, errors.NewParseError("failed to convert JSON to YAML", err)
+	}
+	clearStyle(&doc)
+	return &doc, nil
+}
+
+// clearStyle drops the flow style and quoting JSON syntax implies, so the
+// encoder picks block style and quotes only where YAML needs it.
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+func marshalYAML(data any) ([]byte, error) {
+	node, err := yamlNode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, errors.NewParseError("failed to marshal YAML", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.NewParseError("failed to marshal YAML", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlFormatter mirrors jsonFormatter: a known Document is written as-is,
+// streamed events are listed under status and matches as they arrive (or
+// as a bare list with --fields), and followed results are a stream of
+// documents.
+type yamlFormatter struct {
+	w       io.Writer
+	fields  []string
+	header  Header
+	written int
+	rows    []any
+}
+
+func (f *yamlFormatter) Begin(h Header) error {
+	f.header = h
+	return nil
+}
+
+func (f *yamlFormatter) useDocument() bool {
+	return f.header.Document != nil && !(f.header.Events && f.fields != nil)
+}
+
+func (f *yamlFormatter) Write(r Record) error {
+	var data any
+	if r.Event != nil {
+		data = eventObject(r, f.fields)
+	} else {
+		data = rowObject(f.header, r.Values)
+	}
+
+	switch {
+	case f.header.Follow:
+		return f.writeDocument("---\n", data)
+	case f.useDocument():
+		return nil
+	case r.Event == nil:
+		f.rows = append(f.rows, data)
+		return nil
+	}
+
+	opening := ""
+	if f.written == 0 && f.fields == nil {
+		opening = "status: success\nmatches:\n"
+	}
+	f.written++
+
+	out, err := marshalYAML([]any{data})
+	if err != nil {
+		return err
+	}
+	if f.fields == nil {
+		out = indentLines(out, "  ")
+	}
+	_, err = io.WriteString(f.w, opening+string(out))
+	return err
+}
+
+func (f *yamlFormatter) End() error {
+	switch {
+	case f.header.Follow:
+		return nil
+	case f.useDocument():
+		return f.writeDocument("", f.header.Document)
+	case !f.header.Events:
+		rows := f.rows
+		if rows == nil {
+			rows = []any{}
+		}
+		return f.writeDocument("", rows)
+	case f.written > 0:
+		return nil
+	case f.fields != nil:
+		_, err := io.WriteString(f.w, "[]\n")
+		return err
+	default:
+		_, err := io.WriteString(f.w, "status: success\nmatches: []\n")
+		return err
+	}
+}
+
+func (f *yamlFormatter) writeDocument(prefix string, data any) error {
+	out, err := marshalYAML(data)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f.w, prefix+string(out))
+	return err
+}
+
+// indentLines prefixes every line of out with indent.
+func indentLines(out []byte, indent string) []byte {
+	lines := strings.SplitAfter(string(out), "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(indent + line)
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,201 @@
+// Package timerange resolves the start/end time expressions accepted by the
+// CLI into absolute instants and splits ranges into windows.
+package timerange
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embedded zone data, so --tz names resolve on hosts without a zoneinfo
+	// database, such as Windows or scratch containers
+	_ "time/tzdata"
+)
+
+// DefaultSpan is how far past the start time the Scalyr API searches when no
+// end time is given.
+const DefaultSpan = 24 * time.Hour
+
+// Window is a half-open [Start, End) time range.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+var dateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+var clockLayouts = []string{
+	"15:04:05",
+	"15:04",
+	"3:04:05 PM",
+	"3:04 PM",
+	"3:04:05PM",
+	"3:04PM",
+}
+
+// ParseDuration parses a Go duration string, additionally accepting a whole
+// number of days such as "7d".
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid duration: %s", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %s", value)
+	}
+	return d, nil
+}
+
+// LoadLocation resolves a --tz value: "local" for the system time zone, ""
+// or "UTC" for UTC, or an IANA name such as "Europe/London".
+func LoadLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utc":
+		return time.UTC, nil
+	case "local":
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone: %s", name)
+	}
+	return loc, nil
+}
+
+// Resolve converts a time expression into an absolute instant relative to
+// now. It accepts the same forms as validation.ValidateTimeFormat: relative
+// offsets ("24h", "7d", "30m", "60s"), "NOW", dates and date-times, and
+// clock times which refer to today. Absolute values are interpreted in now's
+// location.
+func Resolve(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty time value")
+	}
+
+	if strings.EqualFold(value, "now") {
+		return now, nil
+	}
+
+	if d, ok := parseRelative(value); ok {
+		return now.Add(-d), nil
+	}
+
+	loc := now.Location()
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range clockLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToUpper(value), loc); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, loc), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized time format: %s", value)
+}
+
+// ResolveRange resolves a start/end pair the way the Scalyr API does: an empty
+// end means DefaultSpan after start.
+func ResolveRange(start, end string, now time.Time) (Window, error) {
+	s, err := Resolve(start, now)
+	if err != nil {
+		return Window{}, err
+	}
+
+	e := s.Add(DefaultSpan)
+	if end != "" {
+		if e, err = Resolve(end, now); err != nil {
+			return Window{}, err
+		}
+	}
+
+	if !e.After(s) {
+		return Window{}, fmt.Errorf("end time %s is not after start time %s", e.Format(time.RFC3339), s.Format(time.RFC3339))
+	}
+
+	return Window{Start: s, End: e}, nil
+}
+
+// Split divides w into consecutive windows of length step. The final window
+// is shortened so the windows exactly cover w.
+func Split(w Window, step time.Duration) []Window {
+	if step <= 0 {
+		return []Window{w}
+	}
+
+	var windows []Window
+	for start := w.Start; start.Before(w.End); start = start.Add(step) {
+		end := start.Add(step)
+		if end.After(w.End) {
+			end = w.End
+		}
+		windows = append(windows, Window{Start: start, End: end})
+	}
+	return windows
+}
+
+// Count returns how many windows Split would produce, without allocating them.
+func Count(w Window, step time.Duration) int {
+	if step <= 0 {
+		return 1
+	}
+	span := w.End.Sub(w.Start)
+	n := int(span / step)
+	if span%step != 0 {
+		n++
+	}
+	return n
+}
+
+// Buckets divides w into n equal windows, the buckets of a numeric or
+// timeseries query. The final bucket ends exactly at w.End.
+func Buckets(w Window, n int) []Window {
+	if n <= 0 {
+		return nil
+	}
+	step := w.End.Sub(w.Start) / time.Duration(n)
+	buckets := make([]Window, n)
+	for i := range buckets {
+		buckets[i] = Window{Start: w.Start.Add(step * time.Duration(i)), End: w.Start.Add(step * time.Duration(i+1))}
+	}
+	buckets[n-1].End = w.End
+	return buckets
+}
+
+func parseRelative(value string) (time.Duration, bool) {
+	if len(value) < 2 {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	switch value[len(value)-1] {
+	case 's':
+		return time.Duration(n) * time.Second, true
+	case 'm':
+		return time.Duration(n) * time.Minute, true
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,132 @@
+package timerange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "now", input: "NOW", want: now},
+		{name: "now lower case", input: "now", want: now},
+		{name: "relative hours", input: "24h", want: now.Add(-24 * time.Hour)},
+		{name: "relative days", input: "7d", want: now.Add(-7 * 24 * time.Hour)},
+		{name: "relative minutes", input: "30m", want: now.Add(-30 * time.Minute)},
+		{name: "relative seconds", input: "60s", want: now.Add(-time.Minute)},
+		{name: "date", input: "2024-01-15", want: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "date and time", input: "2024-01-15 14:30", want: time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC)},
+		{name: "date and time with seconds", input: "2024-01-15 14:30:45", want: time.Date(2024, 1, 15, 14, 30, 45, 0, time.UTC)},
+		{name: "clock time", input: "14:30", want: time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)},
+		{name: "clock time 12h", input: "2:30 PM", want: time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)},
+		{name: "clock time 12h no space", input: "2:30pm", want: time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)},
+		{name: "empty", input: "", wantErr: true},
+		{name: "garbage", input: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.input, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+		})
+	}
+}
+
+func TestResolveRange(t *testing.T) {
+	w, err := ResolveRange("2h", "NOW", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour), w.Start)
+	assert.Equal(t, now, w.End)
+
+	w, err = ResolveRange("2024-01-15", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, w.End.Sub(w.Start), "missing end defaults to a day after start")
+
+	_, err = ResolveRange("1h", "2h", now)
+	assert.Error(t, err, "end before start must be rejected")
+}
+
+func TestSplit(t *testing.T) {
+	w := Window{Start: now, End: now.Add(150 * time.Minute)}
+
+	windows := Split(w, time.Hour)
+	require.Len(t, windows, 3)
+	assert.Equal(t, Count(w, time.Hour), len(windows))
+	assert.Equal(t, now, windows[0].Start)
+	assert.Equal(t, now.Add(time.Hour), windows[0].End)
+	assert.Equal(t, windows[0].End, windows[1].Start, "windows must be contiguous")
+	assert.Equal(t, now.Add(2*time.Hour), windows[2].Start)
+	assert.Equal(t, w.End, windows[2].End, "last window is truncated to the range end")
+
+	assert.Equal(t, []Window{w}, Split(w, 0))
+	assert.Len(t, Split(w, 30*time.Minute), 5)
+	assert.Equal(t, 5, Count(w, 30*time.Minute))
+}
+
+func TestBuckets(t *testing.T) {
+	w := Window{Start: now, End: now.Add(3 * time.Hour)}
+	assert.Equal(t, []Window{
+		{Start: now, End: now.Add(time.Hour)},
+		{Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)},
+		{Start: now.Add(2 * time.Hour), End: now.Add(3 * time.Hour)},
+	}, Buckets(w, 3))
+
+	uneven := Buckets(Window{Start: now, End: now.Add(10 * time.Nanosecond)}, 3)
+	require.Len(t, uneven, 3)
+	assert.Equal(t, now.Add(3*time.Nanosecond), uneven[0].End)
+	assert.Equal(t, now.Add(10*time.Nanosecond), uneven[2].End, "the last bucket ends at the end of the range")
+
+	assert.Nil(t, Buckets(w, 0))
+}
+
+func TestParseDuration(t *testing.T) {
+	d, err := ParseDuration("1h")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, d)
+
+	d, err = ParseDuration("2d")
+	require.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, d)
+
+	d, err = ParseDuration("90m")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	_, err = ParseDuration("soon")
+	assert.Error(t, err)
+	_, err = ParseDuration("-1d")
+	assert.Error(t, err)
+}
+
+func TestLoadLocation(t *testing.T) {
+	for _, name := range []string{"", "UTC", "utc"} {
+		loc, err := LoadLocation(name)
+		require.NoError(t, err)
+		assert.Equal(t, time.UTC, loc, name)
+	}
+
+	loc, err := LoadLocation("local")
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, loc)
+
+	loc, err = LoadLocation("Europe/London")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/London", loc.String())
+
+	_, err = LoadLocation("Mars/Olympus_Mons")
+	assert.Error(t, err)
+}
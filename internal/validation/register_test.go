@@ -0,0 +1,17 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/andreagrandi/logbasset/internal/output"
+	"github.com/andreagrandi/logbasset/internal/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+// Importing output registers its formats and compressions, which the
+// DefaultConfig tests in this package rely on.
+func TestDefaultConfigRegisteredNames(t *testing.T) {
+	config := validation.DefaultConfig()
+	assert.Equal(t, output.Names(), config.ValidOutputs)
+	assert.Equal(t, output.Compressions, config.ValidCompressions)
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"math"
 	"regexp"
 	"slices"
 	"strconv"
@@ -9,9 +10,11 @@ import (
 	"time"
 
 	"github.com/andreagrandi/logbasset/internal/errors"
+	"github.com/andreagrandi/logbasset/internal/timerange"
 )
 
 var (
+	sizeRegex = regexp.MustCompile(`^(?i)(\d+)\s*(b|k|kb|m|mb|g|gb)?$`)
 	timeRegex = regexp.MustCompile(`^(\d+[dhm]|(\d{4}-\d{2}-\d{2}(\s+\d{1,2}:\d{2}(:\d{2})?(\s*(AM|PM))?)?)|(\d{1,2}:\d{2}(:\d{2})?(\s*(AM|PM))?))$`)
 )
 
@@ -20,9 +23,14 @@ type ValidationConfig struct {
 	MaxBuckets      int
 	MaxFacetCount   int
 	MaxTailLines    int
+	MaxParallel     int
+	MaxShards       int
+	MinTailInterval time.Duration
 	ValidOutputs    []string
 	ValidPriorities []string
 	ValidModes      []string
+
+	ValidCompressions []string
 }
 
 func DefaultConfig() *ValidationConfig {
@@ -31,12 +39,32 @@ func DefaultConfig() *ValidationConfig {
 		MaxBuckets:      5000,
 		MaxFacetCount:   1000,
 		MaxTailLines:    10000,
-		ValidOutputs:    []string{"multiline", "singleline", "compact", "csv", "json", "json-pretty", "messageonly"},
+		MaxParallel:     16,
+		MaxShards:       1000,
+		MinTailInterval: 500 * time.Millisecond,
+		ValidOutputs:    slices.Sorted(slices.Values(outputs)),
 		ValidPriorities: []string{"high", "low"},
 		ValidModes:      []string{"head", "tail"},
+
+		ValidCompressions: slices.Clone(compressions),
 	}
 }
 
+// The output formats and compressions DefaultConfig accepts. The output
+// package registers them, so validation does not import the formats it
+// validates.
+var outputs, compressions []string
+
+// RegisterOutput adds an output format name to DefaultConfig's ValidOutputs.
+func RegisterOutput(name string) {
+	outputs = append(outputs, name)
+}
+
+// RegisterCompression adds a codec name to DefaultConfig's ValidCompressions.
+func RegisterCompression(name string) {
+	compressions = append(compressions, name)
+}
+
 func ValidateTimeFormat(timeStr string) error {
 	if timeStr == "" {
 		return nil
@@ -132,6 +160,182 @@ func ValidateCount(count int, maxCount int) error {
 	return nil
 }
 
+// ValidateLimit checks the total record limit used when paging through query
+// results with continuation tokens. Unlike count it has no upper bound.
+func ValidateLimit(limit int) error {
+	if limit < 1 {
+		return errors.NewValidationError(
+			"limit must be at least 1",
+			fmt.Errorf("provided limit: %d", limit),
+		)
+	}
+	return nil
+}
+
+// ValidateSplit checks the --split window size used to shard a query's time
+// range. Windows shorter than a minute are rejected as almost certainly a
+// mistake.
+func ValidateSplit(split string) error {
+	d, err := timerange.ParseDuration(split)
+	if err != nil {
+		return errors.NewValidationError(
+			fmt.Sprintf("invalid split duration: %s", split),
+			fmt.Errorf("use a duration such as 30m, 1h or 1d"),
+		)
+	}
+	if d < time.Minute {
+		return errors.NewValidationError(
+			"split must be at least 1m",
+			fmt.Errorf("provided split: %s", split),
+		)
+	}
+	return nil
+}
+
+// ParseSize parses a file size such as 512KB, 100MB or 2GB into bytes. Units
+// are powers of 1024, and a bare number is a count of bytes.
+func ParseSize(size string) (int64, error) {
+	m := sizeRegex.FindStringSubmatch(strings.TrimSpace(size))
+	if m == nil {
+		return 0, errors.NewValidationError(
+			fmt.Sprintf("invalid size: %s", size),
+			fmt.Errorf("use a size such as 500KB, 100MB or 1GB"),
+		)
+	}
+	var shift uint
+	switch strings.ToLower(m[2]) {
+	case "k", "kb":
+		shift = 10
+	case "m", "mb":
+		shift = 20
+	case "g", "gb":
+		shift = 30
+	}
+	// The regex only admits digits, so ParseInt fails only out of range
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || n > math.MaxInt64>>shift {
+		return 0, errors.NewValidationError(
+			"size is too large",
+			fmt.Errorf("provided size: %s", size),
+		)
+	}
+	if n < 1 {
+		return 0, errors.NewValidationError(
+			"size must be at least 1 byte",
+			fmt.Errorf("provided size: %s", size),
+		)
+	}
+	return n << shift, nil
+}
+
+// ValidateRotateEvery checks tail's --rotate-every interval. Like --split, it
+// must be at least a minute.
+func ValidateRotateEvery(every string) error {
+	d, err := timerange.ParseDuration(every)
+	if err != nil {
+		return errors.NewValidationError(
+			fmt.Sprintf("invalid rotate-every duration: %s", every),
+			fmt.Errorf("use a duration such as 30m, 1h or 1d"),
+		)
+	}
+	if d < time.Minute {
+		return errors.NewValidationError(
+			"rotate-every must be at least 1m",
+			fmt.Errorf("provided rotate-every: %s", every),
+		)
+	}
+	return nil
+}
+
+// ValidateCheckEvery checks the --every interval of check, which reruns its
+// query and must leave at least ten seconds between runs.
+func ValidateCheckEvery(every string) error {
+	d, err := timerange.ParseDuration(every)
+	if err != nil {
+		return errors.NewValidationError(
+			fmt.Sprintf("invalid every duration: %s", every),
+			fmt.Errorf("use a duration such as 30s, 1m or 1h"),
+		)
+	}
+	if d < 10*time.Second {
+		return errors.NewValidationError(
+			"every must be at least 10s",
+			fmt.Errorf("provided every: %s", every),
+		)
+	}
+	return nil
+}
+
+func ValidateRetain(retain int) error {
+	if retain < 0 {
+		return errors.NewValidationError(
+			"retain cannot be negative",
+			fmt.Errorf("provided retain: %d", retain),
+		)
+	}
+	return nil
+}
+
+func ValidateParallel(parallel int, maxParallel int) error {
+	if parallel < 1 {
+		return errors.NewValidationError(
+			"parallel must be at least 1",
+			fmt.Errorf("provided parallel: %d", parallel),
+		)
+	}
+	if parallel > maxParallel {
+		return errors.NewValidationError(
+			fmt.Sprintf("parallel cannot exceed %d", maxParallel),
+			fmt.Errorf("provided parallel: %d", parallel),
+		)
+	}
+	return nil
+}
+
+// ValidateTailPolling checks tail's --interval and --max-interval. A zero
+// maxInterval disables adaptive polling.
+func ValidateTailPolling(interval, maxInterval, minInterval time.Duration) error {
+	if interval < minInterval {
+		return errors.NewValidationError(
+			fmt.Sprintf("interval must be at least %s", minInterval),
+			fmt.Errorf("provided interval: %s", interval),
+		)
+	}
+	if maxInterval != 0 && maxInterval < interval {
+		return errors.NewValidationError(
+			"max-interval cannot be less than interval",
+			fmt.Errorf("provided interval: %s, max-interval: %s", interval, maxInterval),
+		)
+	}
+	return nil
+}
+
+func ValidatePageSize(pageSize int, maxPageSize int) error {
+	if pageSize < 1 {
+		return errors.NewValidationError(
+			"page-size must be at least 1",
+			fmt.Errorf("provided page-size: %d", pageSize),
+		)
+	}
+	if pageSize > maxPageSize {
+		return errors.NewValidationError(
+			fmt.Sprintf("page-size cannot exceed %d", maxPageSize),
+			fmt.Errorf("provided page-size: %d", pageSize),
+		)
+	}
+	return nil
+}
+
+func ValidateShardCount(shards int, maxShards int) error {
+	if shards > maxShards {
+		return errors.NewValidationError(
+			fmt.Sprintf("split produces %d time windows, more than the maximum of %d", shards, maxShards),
+			fmt.Errorf("use a larger --split or a narrower time range"),
+		)
+	}
+	return nil
+}
+
 func ValidateBuckets(buckets int, maxBuckets int) error {
 	if buckets < 1 {
 		return errors.NewValidationError(
@@ -162,6 +366,16 @@ func ValidateOutput(output string, validOutputs []string) error {
 		fmt.Errorf("valid formats: %s", strings.Join(validOutputs, ", ")),
 	)
 }
+func ValidateCompression(compression string, validCompressions []string) error {
+	if compression == "" || slices.Contains(validCompressions, compression) {
+		return nil
+	}
+
+	return errors.NewValidationError(
+		fmt.Sprintf("invalid compression: %s", compression),
+		fmt.Errorf("valid compressions: %s", strings.Join(validCompressions, ", ")),
+	)
+}
 func ValidatePriority(priority string, validPriorities []string) error {
 	if priority == "" {
 		return nil
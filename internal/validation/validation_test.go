@@ -2,6 +2,7 @@ package validation
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -70,6 +71,162 @@ func TestValidateCount(t *testing.T) {
 	}
 }
 
+func TestValidateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		wantError bool
+	}{
+		{"valid limit", 100, false},
+		{"beyond page size", 500000, false},
+		{"zero limit", 0, true},
+		{"negative limit", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLimit(tt.limit)
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateSplit(t *testing.T) {
+	tests := []struct {
+		name      string
+		split     string
+		wantError bool
+	}{
+		{"hours", "1h", false},
+		{"minutes", "30m", false},
+		{"days", "1d", false},
+		{"compound", "1h30m", false},
+		{"too small", "30s", true},
+		{"zero", "0m", true},
+		{"garbage", "hourly", true},
+		{"empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSplit(tt.split)
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "512", want: 512},
+		{size: "500KB", want: 500 << 10},
+		{size: "100MB", want: 100 << 20},
+		{size: "100mb", want: 100 << 20},
+		{size: "2 G", want: 2 << 30},
+		{size: "0", wantErr: true},
+		{size: "1.5GB", wantErr: true},
+		{size: "8589934591G", want: 8589934591 << 30},
+		{size: "8589934592G", wantErr: true},
+		{size: "9999999999G", wantErr: true},
+		{size: "99999999999999999999", wantErr: true},
+		{size: "10TB", wantErr: true},
+		{size: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.size)
+		if tt.wantErr {
+			assert.Error(t, err, tt.size)
+			continue
+		}
+		assert.NoError(t, err, tt.size)
+		assert.Equal(t, tt.want, got, tt.size)
+	}
+}
+
+func TestValidateRotateEvery(t *testing.T) {
+	assert.NoError(t, ValidateRotateEvery("1h"))
+	assert.NoError(t, ValidateRotateEvery("1d"))
+	assert.Error(t, ValidateRotateEvery("30s"))
+	assert.Error(t, ValidateRotateEvery("hourly"))
+}
+
+func TestValidateCheckEvery(t *testing.T) {
+	assert.NoError(t, ValidateCheckEvery("10s"))
+	assert.NoError(t, ValidateCheckEvery("5m"))
+	assert.Error(t, ValidateCheckEvery("1s"))
+	assert.Error(t, ValidateCheckEvery("often"))
+}
+
+func TestValidateCompression(t *testing.T) {
+	valid := DefaultConfig().ValidCompressions
+	assert.NoError(t, ValidateCompression("", valid))
+	assert.NoError(t, ValidateCompression("gzip", valid))
+	assert.NoError(t, ValidateCompression("zstd", valid))
+	assert.Error(t, ValidateCompression("bzip2", valid))
+}
+
+func TestValidateRetain(t *testing.T) {
+	assert.NoError(t, ValidateRetain(0))
+	assert.NoError(t, ValidateRetain(24))
+	assert.Error(t, ValidateRetain(-1))
+}
+
+func TestValidateParallel(t *testing.T) {
+	tests := []struct {
+		name      string
+		parallel  int
+		wantError bool
+	}{
+		{"minimum", 1, false},
+		{"maximum", 16, false},
+		{"zero", 0, true},
+		{"exceeds maximum", 17, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParallel(tt.parallel, 16)
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateTailPolling(t *testing.T) {
+	minInterval := 500 * time.Millisecond
+	assert.NoError(t, ValidateTailPolling(2*time.Second, 0, minInterval))
+	assert.NoError(t, ValidateTailPolling(2*time.Second, 2*time.Second, minInterval))
+	assert.NoError(t, ValidateTailPolling(time.Second, time.Minute, minInterval))
+	assert.Error(t, ValidateTailPolling(100*time.Millisecond, 0, minInterval))
+	assert.Error(t, ValidateTailPolling(5*time.Second, time.Second, minInterval))
+}
+
+func TestValidatePageSize(t *testing.T) {
+	assert.NoError(t, ValidatePageSize(1, 5000))
+	assert.NoError(t, ValidatePageSize(5000, 5000))
+	assert.Error(t, ValidatePageSize(0, 5000))
+	assert.Error(t, ValidatePageSize(5001, 5000))
+}
+
+func TestValidateShardCount(t *testing.T) {
+	assert.NoError(t, ValidateShardCount(1000, 1000))
+	assert.Error(t, ValidateShardCount(1001, 1000))
+}
+
 func TestValidateBuckets(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -400,6 +557,8 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 5000, config.MaxBuckets)
 	assert.Equal(t, 1000, config.MaxFacetCount)
 	assert.Equal(t, 10000, config.MaxTailLines)
+	assert.Equal(t, 16, config.MaxParallel)
+	assert.Equal(t, 1000, config.MaxShards)
 	assert.Contains(t, config.ValidOutputs, "json")
 	assert.Contains(t, config.ValidPriorities, "high")
 	assert.Contains(t, config.ValidModes, "head")